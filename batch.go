@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Batch modes. An atomic batch is applied only if every operation
+// succeeds; a best effort batch applies every operation that can be.
+const (
+	batchAtomic     = "atomic"
+	batchBestEffort = "best_effort"
+)
+
+// Batch operations.
+const (
+	batchCreate = "create"
+	batchUpdate = "update"
+	batchDelete = "delete"
+)
+
+// maxBatchSize is the most operations a batch may hold.
+const maxBatchSize = 1000
+
+// batchOperation creates or updates Car, or deletes the car with Id.
+type batchOperation struct {
+	Op             string `json:"op" enums:"create,update,delete"`
+	Car            Car    `json:"car"`
+	Id             string `json:"id,omitempty"`
+	AllowDuplicate bool   `json:"allow_duplicate,omitempty"`
+}
+
+// batchRequest is an ordered list of operations. Mode defaults to atomic.
+// @Description ordered car operations applied together
+type batchRequest struct {
+	Mode       string           `json:"mode" enums:"atomic,best_effort"`
+	Operations []batchOperation `json:"operations"`
+}
+
+// batchResult is the outcome of one operation, with the status code the
+// equivalent single request would have returned.
+type batchResult struct {
+	Status     int              `json:"status"`
+	Car        *Car             `json:"car,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	Duplicates []duplicateMatch `json:"duplicates,omitempty"`
+
+	// deleted is the car a delete moved to the trash, for its event.
+	deleted Car
+}
+
+// batchResponse reports whether the batch was committed and the result of
+// each operation, in order.
+// @Description result of a batch, per operation
+type batchResponse struct {
+	Mode      string        `json:"mode"`
+	Committed bool          `json:"committed"`
+	Results   []batchResult `json:"results"`
+}
+
+// statusOf maps an error from the car methods to the status code the REST
+// handlers use for it. Errors that carry details wrap one of the errors
+// declared beside each feature, so they are told apart with errors.Is.
+func statusOf(err error) int {
+	var duplicate *duplicateError
+	var missing *compareError
+	switch {
+	case errors.Is(err, errIdNotFound), errors.Is(err, errReservationNotFound), errors.Is(err, errScheduledPriceNotFound), errors.Is(err, errMediaNotFound), errors.As(err, &missing):
+		return http.StatusNotFound
+	case errors.Is(err, errIdExists), errors.Is(err, errTransition), errors.Is(err, errAlreadyHeld), errors.Is(err, errHeld), errors.As(err, &duplicate):
+		return http.StatusConflict
+	case errors.Is(err, errReservationHeld):
+		return http.StatusForbidden
+	case errors.Is(err, errMediaTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, errUnsupportedMedia):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, errNotEnoughComparables):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, errWal):
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// apply validates and runs one operation against db. It does not publish
+// events.
+func (db *Db) apply(op batchOperation, actor string) batchResult {
+	var car Car
+	var err error
+	status := http.StatusOK
+
+	switch op.Op {
+	case batchCreate:
+		c := op.Car
+		if err = c.fillFromVin(); err == nil {
+			err = m.validate_create(&c)
+		}
+		if err == nil {
+			c.normalizeMileage()
+			if !op.AllowDuplicate {
+				err = db.checkDuplicates(c)
+			}
+		}
+		if err == nil {
+			car, err = db.add(&c)
+		}
+		status = http.StatusCreated
+	case batchUpdate:
+		c := op.Car
+		if err = c.fillFromVin(); err == nil {
+			err = m.validate_update(&c)
+		}
+		if err == nil {
+			err = db.checkHold(c.Id, actor)
+		}
+		if err == nil {
+			c.normalizeMileage()
+			car, err = db.update(&c)
+		}
+	case batchDelete:
+		c := Car{Id: op.Id}
+		if err = m.validate_delete(&c); err == nil {
+			err = db.checkHold(c.Id, actor)
+		}
+		if err == nil {
+			car, err = db.delete(c.Id, actor)
+		}
+		status = http.StatusNoContent
+	}
+
+	if dup, ok := err.(*duplicateError); ok {
+		return batchResult{Status: statusOf(err), Error: err.Error(), Duplicates: dup.Duplicates}
+	}
+	if err != nil {
+		return batchResult{Status: statusOf(err), Error: err.Error()}
+	}
+	if op.Op == batchDelete {
+		return batchResult{Status: status, deleted: car}
+	}
+	return batchResult{Status: status, Car: &car}
+}
+
+// shadow returns an unlogged copy of the state apply reads and changes:
+// the cars with their statuses, the trash, the holds and the price
+// schedules. A batch tried on it fails exactly where it would on db,
+// without touching the store.
+func (db *Db) shadow() *Db {
+	s := &Db{
+		cars:   make([]Car, len(db.cars)),
+		trash:  carTrash{cars: make([]trashedCar, len(db.trash.cars))},
+		holds:  carHolds{byCar: map[string]reservation{}},
+		prices: priceSchedule{byCar: map[string][]scheduledPrice{}},
+	}
+	copy(s.cars, db.cars)
+	copy(s.trash.cars, db.trash.cars)
+	for id, r := range db.holds.byCar {
+		s.holds.byCar[id] = r
+	}
+	for id, p := range db.prices.byCar {
+		s.prices.byCar[id] = append([]scheduledPrice{}, p...)
+	}
+	return s
+}
+
+// batch applies the operations in order. In atomic mode they are first
+// tried on a shadow copy; if any fails nothing is applied and the others
+// report 424. Otherwise they are logged as a single record, so replay
+// also applies all or none of them, checking holds as apply does.
+func (db *Db) batch(ops []batchOperation, atomic bool, actor string) ([]batchResult, bool) {
+	results := make([]batchResult, len(ops))
+
+	if !atomic {
+		for i, op := range ops {
+			results[i] = db.apply(op, actor)
+		}
+		return results, true
+	}
+
+	shadow := db.shadow()
+	failed := false
+	for i, op := range ops {
+		results[i] = shadow.apply(op, actor)
+		failed = failed || results[i].Error != ""
+	}
+	if failed {
+		for i := range results {
+			if results[i].Error == "" {
+				results[i] = batchResult{Status: http.StatusFailedDependency, Error: "not applied, another operation failed"}
+			}
+		}
+		return results, false
+	}
+
+	records := make([]walRecord, len(ops))
+	for i, op := range ops {
+		switch op.Op {
+		case batchCreate:
+			records[i] = walRecord{Op: opAdd, Car: *results[i].Car}
+		case batchUpdate:
+			records[i] = walRecord{Op: opUpdate, Car: *results[i].Car, Actor: actor}
+		case batchDelete:
+			records[i] = walRecord{Op: opDelete, Id: op.Id, Actor: actor}
+		}
+	}
+	if err := db.wal.append(walRecord{Op: opBatch, Batch: records}); err != nil {
+		for i := range results {
+			results[i] = batchResult{Status: http.StatusInternalServerError, Error: err.Error()}
+		}
+		return results, false
+	}
+
+	// Already logged as one record, so apply without logging each.
+	log := db.wal
+	db.wal = nil
+	defer func() { db.wal = log }()
+	for i, op := range ops {
+		results[i] = db.apply(op, actor)
+	}
+	return results, true
+}
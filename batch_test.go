@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func batchPost(t *testing.T, body string) (int, batchResponse) {
+	h := &carHandler{}
+	r := httptest.NewRequest("POST", "/cars:batch", strings.NewReader(body))
+	r.Header.Set("content-type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	var out batchResponse
+	json.Unmarshal(w.Body.Bytes(), &out)
+	return w.Code, out
+}
+
+func batchCarJSON(id string) string {
+	b, _ := json.Marshal(persistTestCar(id))
+	return string(b)
+}
+
+func TestBatch_WhenAtomicBatchSucceeds(t *testing.T){
+	code, out := batchPost(t, `{"operations": [
+		{"op": "create", "car": ` + batchCarJSON("batchcar1") + `},
+		{"op": "create", "car": ` + batchCarJSON("batchcar2") + `, "allow_duplicate": true},
+		{"op": "delete", "id": "batchcar1"}]}`)
+
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, out.Mode, batchAtomic)
+	assert.Equal(t, out.Committed, true)
+	assert.Equal(t, []int{out.Results[0].Status, out.Results[1].Status, out.Results[2].Status}, []int{201, 201, 204})
+
+	_, err := db.getById("batchcar1")
+	assert.Equal(t, err.Error(), "id not found")
+	_, err = db.getById("batchcar2")
+	assert.Equal(t, err, nil)
+}
+
+func TestBatch_WhenAtomicBatchFails(t *testing.T){
+	code, out := batchPost(t, `{"mode": "atomic", "operations": [
+		{"op": "create", "car": ` + batchCarJSON("batchcar3") + `, "allow_duplicate": true},
+		{"op": "update", "car": ` + batchCarJSON("batchmissing") + `}]}`)
+
+	assert.Equal(t, code, http.StatusNotFound)
+	assert.Equal(t, out.Committed, false)
+	assert.Equal(t, out.Results[0].Status, http.StatusFailedDependency)
+	assert.Equal(t, out.Results[1].Error, "id not found")
+
+	_, err := db.getById("batchcar3")
+	assert.Equal(t, err.Error(), "id not found")
+}
+
+func TestBatch_WhenBestEffort(t *testing.T){
+	code, out := batchPost(t, `{"mode": "best_effort", "operations": [
+		{"op": "create", "car": ` + batchCarJSON("batchcar4") + `, "allow_duplicate": true},
+		{"op": "create", "car": {"id": "batchcar5"}},
+		{"op": "create", "car": ` + batchCarJSON("batchcar4") + `}]}`)
+
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, []int{out.Results[0].Status, out.Results[1].Status, out.Results[2].Status}, []int{201, 400, 409})
+	assert.Equal(t, out.Results[1].Error, "make field empty")
+}
+
+func TestBatch_WhenRequestInvalid(t *testing.T){
+	code, _ := batchPost(t, `{"mode": "sometimes", "operations": [{"op": "delete", "id": "a"}]}`)
+	assert.Equal(t, code, http.StatusBadRequest)
+
+	code, _ = batchPost(t, `{"operations": [{"op": "upsert", "id": "a"}]}`)
+	assert.Equal(t, code, http.StatusBadRequest)
+}
+
+func TestOpen_WhenReplayingBatch(t *testing.T){
+	dir := t.TempDir()
+	var d Db
+	d.open(dir)
+	d.add(persistTestCar("a"))
+	results, committed := d.batch([]batchOperation{
+		{ Op: batchCreate, Car: *persistTestCar("b"), AllowDuplicate: true },
+		{ Op: batchDelete, Id: "a" },
+	}, true, "alice")
+	assert.Equal(t, committed, true)
+	assert.Equal(t, results[1].Status, http.StatusNoContent)
+	d.wal.f.Close()
+
+	var replayed Db
+	assert.Equal(t, replayed.open(dir), nil)
+	assert.Equal(t, len(replayed.cars), 1)
+	assert.Equal(t, replayed.cars[0].Id, "b")
+	assert.Equal(t, replayed.trash.cars[0].DeletedBy, "alice")
+}
+
+func TestBatch_WhenAtomicBatchUpdatesHeldCar(t *testing.T){
+	dir := t.TempDir()
+	var d Db
+	d.open(dir)
+	d.add(persistTestCar("a"))
+	d.add(persistTestCar("b"))
+	at := now()
+	d.hold(reservation{ Id: "r", CarId: "a", Customer: "CUST-1", HeldBy: "rita", CreatedAt: at, ExpiresAt: at.Add(time.Hour) })
+	seq := d.wal.seq
+
+	updated := persistTestCar("a")
+	updated.Color = "Pink"
+	results, committed := d.batch([]batchOperation{
+		{ Op: batchDelete, Id: "b" },
+		{ Op: batchUpdate, Car: *updated },
+	}, true, "bob")
+
+	assert.Equal(t, committed, false)
+	assert.Equal(t, results[0].Status, http.StatusFailedDependency)
+	assert.Equal(t, results[1].Status, http.StatusConflict)
+	assert.Equal(t, d.wal.seq, seq)
+	car, _ := d.getById("a")
+	assert.Equal(t, car.Color, "Gray")
+	_, err := d.getById("b")
+	assert.Equal(t, err, nil)
+}
+
+func TestOpen_WhenReplayingBatchOnHeldCar(t *testing.T){
+	dir := t.TempDir()
+	var d Db
+	d.open(dir)
+	d.add(persistTestCar("a"))
+	at := now()
+	d.hold(reservation{ Id: "r", CarId: "a", Customer: "CUST-1", HeldBy: "rita", CreatedAt: at, ExpiresAt: at.Add(time.Hour) })
+	updated := persistTestCar("a")
+	updated.Color = "Pink"
+	d.wal.append(walRecord{ Op: opBatch, Batch: []walRecord{ { Op: opUpdate, Car: *updated, Actor: "bob" } } })
+	d.wal.f.Close()
+
+	var replayed Db
+	assert.Equal(t, replayed.open(dir), nil)
+	car, _ := replayed.getById("a")
+	assert.Equal(t, car.Color, "Gray")
+	assert.Equal(t, car.Status, statusReserved)
+}
+
+func TestBatch_WhenCreatingWithStatus(t *testing.T){
+	incoming, sold := persistTestCar("batchcar30"), persistTestCar("batchcar31")
+	incoming.Status, sold.Status = statusIncoming, statusSold
+	b1, _ := json.Marshal(incoming)
+	b2, _ := json.Marshal(sold)
+
+	_, out := batchPost(t, `{"mode": "best_effort", "operations": [
+		{"op": "create", "car": ` + string(b1) + `, "allow_duplicate": true},
+		{"op": "create", "car": ` + string(b2) + `, "allow_duplicate": true}]}`)
+
+	assert.Equal(t, out.Results[0].Status, http.StatusCreated)
+	assert.Equal(t, out.Results[0].Car.Status, statusIncoming)
+	assert.Equal(t, out.Results[1].Status, http.StatusBadRequest)
+}
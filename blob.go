@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// blobStore keeps the bytes of uploaded media. Keys are slash separated
+// paths such as "<media id>/original".
+type blobStore interface {
+	put(key string, data []byte) error
+	get(key string) ([]byte, error)
+	// removeAll deletes every blob whose key starts with prefix.
+	removeAll(prefix string) error
+}
+
+// blobs is where media are stored. main points it at DATA_DIR/media.
+var blobs blobStore = &diskBlobStore{dir: filepath.Join("data", "media")}
+
+// diskBlobStore keeps every blob as a file under dir.
+type diskBlobStore struct {
+	dir string
+}
+
+func (s *diskBlobStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(clean)), nil
+}
+
+// put writes the blob to a temporary file and renames it into place, so
+// readers never see a partial blob.
+func (s *diskBlobStore) put(key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (s *diskBlobStore) get(key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errMediaNotFound
+	}
+	return data, err
+}
+
+func (s *diskBlobStore) removeAll(prefix string) error {
+	path, err := s.path(prefix)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(path)
+}
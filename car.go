@@ -1,17 +1,31 @@
 package main
 
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
 // Car model info
 // @Description car information
 type Car struct {
-	Id       string  `json:id`
-	Make     string  `json:make`
-	Model    string  `json:model`
-	Package  string  `json:package`
-	Color    string  `json:color`
-	Year     int     `json:year`
-	Category string  `json:category`
-	Mileage  float64 `json:mileage`
-	Price    float64 `json:price`
+	Id            string   `json:"Id"`
+	Make          string   `json:"Make"`
+	Model         string   `json:"Model"`
+	Package       string   `json:"Package"`
+	Color         string   `json:"Color"`
+	Year          int      `json:"Year"`
+	Category      string   `json:"Category"`
+	Mileage       float64  `json:"Mileage"`
+	Price         float64  `json:"Price"`
+	MileageUnit   string   `json:"mileage_unit" enums:"mi,km"`
+	Dealership    string   `json:"dealership"`
+	Status        string   `json:"status" enums:"incoming,in_transit,available,reserved,sold"`
+	PreviousPrice float64  `json:"previous_price,omitempty"`
+	Vin           string   `json:"vin,omitempty"`
+	Country       string   `json:"country,omitempty"`
+	VinWarnings   []string `json:"vin_warnings,omitempty"`
+	Media         []media  `json:"media,omitempty"`
 }
 
 var db Db
@@ -19,11 +33,62 @@ var db Db
 var m carMiddleware
 
 func (c *Car) getAllCars() ([]Car, error) {
-	cars, err := db.getAll()
+	store, err := c.store()
+	if err != nil {
+		return []Car{}, err
+	}
+
+	cars, err := store.getAll()
 	if err != nil {
 		return []Car{}, err
 	}
-	return cars, err
+
+	out := make([]Car, 0, len(cars))
+	for _, v := range cars {
+		out = append(out, store.withMedia(v))
+	}
+	return out, err
+}
+
+func (c *Car) searchCars(q string) ([]searchResult, error) {
+	err := m.validate_search(q)
+	if err != nil {
+		return []searchResult{}, err
+	}
+	store, err := c.store()
+	if err != nil {
+		return []searchResult{}, err
+	}
+
+	return store.search(q)
+}
+
+func (c *Car) getFacets(q carQuery, priceInterval, mileageInterval float64) (facets, error) {
+	err := m.validate_facets(priceInterval, mileageInterval)
+	if err != nil {
+		return facets{}, err
+	}
+
+	cars, err := c.getAllCars()
+	if err != nil {
+		return facets{}, err
+	}
+
+	return computeFacets(q.apply(cars), q.units, priceInterval, mileageInterval), nil
+}
+
+func (c *Car) getStats(q carQuery, s statsQuery) ([]statsGroup, error) {
+	err := m.validate_stats(s)
+	if err != nil {
+		return []statsGroup{}, err
+	}
+
+	cars, err := c.getAllCars()
+	if err != nil {
+		return []statsGroup{}, err
+	}
+
+	return computeStats(q.apply(cars), s, q.units), nil
 }
 
 func (c *Car) getCarById() (Car, error) {
@@ -31,57 +96,558 @@ func (c *Car) getCarById() (Car, error) {
 	if err != nil {
 		return Car{}, err
 	}
+	store, err := c.store()
+	if err != nil {
+		return Car{}, err
+	}
 
-	car, err := db.getById(c.Id)
+	car, err := store.getById(c.Id)
 
 	if err != nil {
 		return Car{}, err
 	}
 
-	return car, nil
+	return store.withMedia(car), nil
+}
+
+func (c *Car) getCarHistory() ([]carVersion, error) {
+	err := m.validate_getById(c)
+	if err != nil {
+		return []carVersion{}, err
+	}
+	store, err := c.store()
+	if err != nil {
+		return []carVersion{}, err
+	}
+
+	return store.getHistory(c.Id)
+}
+
+func (c *Car) getCarAsOf(t time.Time) (Car, error) {
+	err := m.validate_getById(c)
+	if err != nil {
+		return Car{}, err
+	}
+	store, err := c.store()
+	if err != nil {
+		return Car{}, err
+	}
+
+	return store.getAsOf(c.Id, t)
 }
 
 func (c *Car) createCar() (Car, error) {
-	err := m.validate_create(c)
+	err := c.fillFromVin()
+	if err == nil {
+		err = m.validate_create(c)
+	}
+	if err != nil {
+		return Car{}, err
+	}
+	store, err := c.store()
 	if err != nil {
 		return Car{}, err
 	}
+	c.normalizeMileage()
 
-	car, err := db.add(c)
+	car, err := store.add(c)
 
 	if err != nil {
 		return Car{}, err
 	}
 
+	bus.publish(eventCreated, car)
 	return car, nil
 }
 
+// createListing creates the car unless it looks like one already listed,
+// which fails with a *duplicateError. allowDuplicate skips the check. Every
+// API creates cars through it.
+func (c *Car) createListing(allowDuplicate bool) (Car, error) {
+	if !allowDuplicate {
+		if err := c.checkDuplicates(); err != nil {
+			return Car{}, err
+		}
+	}
+	return c.createCar()
+}
+
+// checkDuplicates fails with a *duplicateError when the car looks like one
+// already listed. The car itself is not changed.
+func (c *Car) checkDuplicates() error {
+	store, err := c.store()
+	if err != nil {
+		return err
+	}
+
+	car := *c
+	if car.fillFromVin() != nil {
+		// createCar reports the invalid VIN.
+		return nil
+	}
+	car.normalizeMileage()
+	return store.checkDuplicates(car)
+}
+
+func (c *Car) getDuplicates() ([]duplicateGroup, error) {
+	store, err := c.store()
+	if err != nil {
+		return []duplicateGroup{}, err
+	}
+
+	return store.duplicateGroups(), nil
+}
+
+func (c *Car) getValuation(q valuationQuery) (valuation, error) {
+	err := m.validate_valuation(q)
+	if err != nil {
+		return valuation{}, err
+	}
+	store, err := c.store()
+	if err != nil {
+		return valuation{}, err
+	}
+
+	return store.valuate(q)
+}
+
+// getCarValuation values the car against the others in the store.
+func (c *Car) getCarValuation() (valuation, error) {
+	err := m.validate_getById(c)
+	if err != nil {
+		return valuation{}, err
+	}
+	store, err := c.store()
+	if err != nil {
+		return valuation{}, err
+	}
+	car, err := store.getById(c.Id)
+	if err != nil {
+		return valuation{}, err
+	}
+
+	return store.valuate(valuationQuery{id: car.Id, make: car.Make, model: car.Model, category: car.Category, year: car.Year, mileage: car.Mileage})
+}
+
+// getFinancing prices a loan for the car, at q.apr or at the rate of
+// q.tier in lenderRates.
+func (c *Car) getFinancing(q financingQuery) (financing, error) {
+	err := m.validate_getById(c)
+	if err != nil {
+		return financing{}, err
+	}
+	store, err := c.store()
+	if err != nil {
+		return financing{}, err
+	}
+	car, err := store.getById(c.Id)
+	if err != nil {
+		return financing{}, err
+	}
+
+	err = m.validate_financing(q, car.Price)
+	if err != nil {
+		return financing{}, err
+	}
+	if q.tier != "" {
+		q.apr, err = lenderRates.apr(q.tier, q.term)
+		if err != nil {
+			return financing{}, err
+		}
+	}
+
+	return finance(car, q), nil
+}
+
+func (c *Car) getComparison(ids []string, units string) (comparison, error) {
+	err := m.validate_compare(ids)
+	if err != nil {
+		return comparison{}, err
+	}
+	store, err := c.store()
+	if err != nil {
+		return comparison{}, err
+	}
+
+	return store.compareCars(ids, units)
+}
+
 func (c *Car) updateCar() (Car, error) {
-	err := m.validate_update(c)
+	err := c.fillFromVin()
+	if err == nil {
+		err = m.validate_update(c)
+	}
 	if err != nil {
 		return Car{}, err
 	}
+	store, err := c.store()
+	if err != nil {
+		return Car{}, err
+	}
+	c.normalizeMileage()
 
-	car, err := db.update(c)
+	car, err := store.update(c)
 
 	if err != nil {
 		return Car{}, err
 	}
 
+	bus.publish(eventUpdated, car)
 	return car, nil
 }
 
-func (c *Car) deleteCar() (Car, error) {
+func (c *Car) deleteCar(actor string) (Car, error) {
 	err := m.validate_delete(c)
 	if err != nil {
 		return Car{}, err
 	}
+	store, err := c.store()
+	if err != nil {
+		return Car{}, err
+	}
 
-	_, err = db.delete(c.Id)
+	deleted, err := store.delete(c.Id, actor)
 
 	if err != nil {
 		return Car{}, err
 	}
 
+	bus.publish(eventDeleted, deleted)
 	return Car{}, nil
-}
\ No newline at end of file
+}
+
+func (c *Car) getDeletedCars() ([]trashedCar, error) {
+	store, err := c.store()
+	if err != nil {
+		return []trashedCar{}, err
+	}
+
+	cars, err := store.getTrash()
+	if err != nil {
+		return []trashedCar{}, err
+	}
+	return cars, nil
+}
+
+func (c *Car) restoreCar() (Car, error) {
+	err := m.validate_delete(c)
+	if err != nil {
+		return Car{}, err
+	}
+	store, err := c.store()
+	if err != nil {
+		return Car{}, err
+	}
+
+	car, err := store.restore(c.Id)
+	if err != nil {
+		return Car{}, err
+	}
+
+	bus.publish(eventRestored, car)
+	return car, nil
+}
+
+func (c *Car) transitionCar(t statusTransition, actor string) (Car, error) {
+	err := m.validate_transition(c, t)
+	if err != nil {
+		return Car{}, err
+	}
+	store, err := c.store()
+	if err != nil {
+		return Car{}, err
+	}
+
+	car, err := store.transition(c.Id, t.Status, actor, t.Reason)
+
+	if err != nil {
+		return Car{}, err
+	}
+
+	bus.publish(eventUpdated, car)
+	return car, nil
+}
+
+// checkHold fails when the car is held for someone other than actor.
+func (c *Car) checkHold(actor string) error {
+	store, err := c.store()
+	if err != nil {
+		return err
+	}
+
+	return store.checkHold(c.Id, actor)
+}
+
+func (c *Car) getReservations() ([]reservation, error) {
+	err := m.validate_getById(c)
+	if err != nil {
+		return []reservation{}, err
+	}
+	store, err := c.store()
+	if err != nil {
+		return []reservation{}, err
+	}
+
+	return store.getHolds(c.Id)
+}
+
+func (c *Car) reserveCar(req reservationRequest, actor string) (reservation, error) {
+	if req.Hours == 0 {
+		req.Hours = defaultHoldHours
+	}
+	err := m.validate_reservation(c, req)
+	if err != nil {
+		return reservation{}, err
+	}
+	store, err := c.store()
+	if err != nil {
+		return reservation{}, err
+	}
+
+	at := now()
+	r, err := store.hold(reservation{Id: newId(), CarId: c.Id, Customer: req.Customer, HeldBy: actor, CreatedAt: at, ExpiresAt: at.Add(time.Duration(req.Hours) * time.Hour)})
+
+	if err != nil {
+		return reservation{}, err
+	}
+
+	car, _ := store.getById(c.Id)
+	bus.publish(eventUpdated, car)
+	return r, nil
+}
+
+// releaseReservation ends the hold before it expires. Only the rep who
+// placed it may release it.
+func (c *Car) releaseReservation(id, actor string) (Car, error) {
+	err := m.validate_getById(c)
+	if err != nil {
+		return Car{}, err
+	}
+	store, err := c.store()
+	if err != nil {
+		return Car{}, err
+	}
+
+	r, ok := store.holds.get(c.Id)
+	if !ok || r.Id != id || !r.active() {
+		return Car{}, errReservationNotFound
+	}
+	if r.HeldBy != actor {
+		return Car{}, fmt.Errorf("%w by %s", errReservationHeld, r.HeldBy)
+	}
+
+	car, err := store.release(c.Id, actor, "reservation released")
+
+	if err != nil {
+		return Car{}, err
+	}
+
+	bus.publish(eventUpdated, car)
+	return car, nil
+}
+
+func (c *Car) getCarPrices() (priceTimeline, error) {
+	err := m.validate_getById(c)
+	if err != nil {
+		return priceTimeline{}, err
+	}
+	store, err := c.store()
+	if err != nil {
+		return priceTimeline{}, err
+	}
+
+	return store.getPrices(c.Id)
+}
+
+func (c *Car) schedulePrice(p priceChange, actor string) (scheduledPrice, error) {
+	err := m.validate_priceChange(c, p)
+	if err != nil {
+		return scheduledPrice{}, err
+	}
+	store, err := c.store()
+	if err != nil {
+		return scheduledPrice{}, err
+	}
+
+	return store.schedulePrice(scheduledPrice{Id: newId(), CarId: c.Id, Price: p.Price, EffectiveAt: p.EffectiveAt, ScheduledBy: actor, CreatedAt: now()})
+}
+
+func (c *Car) cancelScheduledPrice(id string) (scheduledPrice, error) {
+	err := m.validate_getById(c)
+	if err != nil {
+		return scheduledPrice{}, err
+	}
+	store, err := c.store()
+	if err != nil {
+		return scheduledPrice{}, err
+	}
+
+	return store.cancelPrice(c.Id, id)
+}
+
+func (c *Car) getCarMedia() ([]media, error) {
+	err := m.validate_getById(c)
+	if err != nil {
+		return []media{}, err
+	}
+	store, err := c.store()
+	if err != nil {
+		return []media{}, err
+	}
+
+	return store.getMedia(c.Id)
+}
+
+// getMediaBlob reads the original upload, or the thumbnail named size.
+func (c *Car) getMediaBlob(id, size string) ([]byte, string, error) {
+	err := m.validate_getById(c)
+	if err != nil {
+		return nil, "", err
+	}
+	store, err := c.store()
+	if err != nil {
+		return nil, "", err
+	}
+
+	md, ok := store.media.get(c.Id, id)
+	if !ok {
+		return nil, "", errMediaNotFound
+	}
+	if size == "" {
+		data, err := blobs.get(md.Id + "/original")
+		return data, md.ContentType, err
+	}
+	if _, ok := md.Thumbnails[size]; !ok {
+		return nil, "", errMediaNotFound
+	}
+	data, err := blobs.get(md.Id + "/" + size)
+	return data, thumbnailType(md.ContentType), err
+}
+
+// decodeMedia validates an upload for the car and renders its thumbnails.
+// It does not touch the store, so it runs without holding the handler
+// lock.
+func (c *Car) decodeMedia(data []byte) (decodedMedia, error) {
+	err := m.validate_media(c, data)
+	if err != nil {
+		return decodedMedia{}, err
+	}
+
+	contentType := http.DetectContentType(data)
+	cfg, thumbs, err := renderThumbnails(data, contentType)
+	if err != nil {
+		return decodedMedia{}, err
+	}
+	return decodedMedia{data: data, contentType: contentType, config: cfg, thumbs: thumbs}, nil
+}
+
+// uploadMedia stores the photo and its thumbnails, then adds it after the
+// other photos of the car.
+func (c *Car) uploadMedia(d decodedMedia, actor string) (media, error) {
+	store, err := c.store()
+	if err != nil {
+		return media{}, err
+	}
+	if _, err := store.getById(c.Id); err != nil {
+		return media{}, err
+	}
+
+	md := media{Id: newId(), CarId: c.Id, ContentType: d.contentType, Size: len(d.data), Width: d.config.Width, Height: d.config.Height, Thumbnails: map[string]string{}, UploadedBy: actor, CreatedAt: now()}
+	md.Url = mediaUrl(c.Id, md.Id)
+	err = blobs.put(md.Id+"/original", d.data)
+	for name, thumb := range d.thumbs {
+		if err == nil {
+			err = blobs.put(md.Id+"/"+name, thumb)
+		}
+		md.Thumbnails[name] = md.Url + "/" + name
+	}
+	if err != nil {
+		blobs.removeAll(md.Id)
+		return media{}, err
+	}
+
+	out, err := store.addMedia(md)
+
+	if err != nil {
+		blobs.removeAll(md.Id)
+		return media{}, err
+	}
+	return out, nil
+}
+
+func (c *Car) updateMedia(id string, u mediaUpdate) (media, error) {
+	err := m.validate_mediaUpdate(c, u)
+	if err != nil {
+		return media{}, err
+	}
+	store, err := c.store()
+	if err != nil {
+		return media{}, err
+	}
+
+	md, ok := store.media.get(c.Id, id)
+	if !ok {
+		return media{}, errMediaNotFound
+	}
+	position := md.Position
+	if u.Position != nil {
+		position = *u.Position
+	}
+
+	return store.updateMedia(c.Id, id, position, u.Primary)
+}
+
+func (c *Car) deleteMedia(id string) (media, error) {
+	err := m.validate_getById(c)
+	if err != nil {
+		return media{}, err
+	}
+	store, err := c.store()
+	if err != nil {
+		return media{}, err
+	}
+
+	md, err := store.deleteMedia(c.Id, id)
+
+	if err != nil {
+		return media{}, err
+	}
+
+	blobs.removeAll(md.Id)
+	return md, nil
+}
+
+func (c *Car) applyBatch(b batchRequest, actor string) (batchResponse, error) {
+	if b.Mode == "" {
+		b.Mode = batchAtomic
+	}
+	err := m.validate_batch(&b)
+	if err != nil {
+		return batchResponse{}, err
+	}
+	store, err := c.store()
+	if err != nil {
+		return batchResponse{}, err
+	}
+	for i := range b.Operations {
+		b.Operations[i].Car.Dealership = c.Dealership
+	}
+
+	results, committed := store.batch(b.Operations, b.Mode == batchAtomic, actor)
+
+	if committed {
+		for i, r := range results {
+			switch {
+			case r.Error != "":
+			case b.Operations[i].Op == batchCreate:
+				bus.publish(eventCreated, *r.Car)
+			case b.Operations[i].Op == batchUpdate:
+				bus.publish(eventUpdated, *r.Car)
+			case b.Operations[i].Op == batchDelete:
+				bus.publish(eventDeleted, r.deleted)
+			}
+		}
+	}
+
+	return batchResponse{Mode: b.Mode, Committed: committed, Results: results}, nil
+}
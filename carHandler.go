@@ -7,23 +7,102 @@ import (
 
 type carHandler struct {
 	sync.Mutex
+	stream *eventStream
 }
 
 func (h *carHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	dealership, err := dealershipFromRequest(r)
+	if err != nil {
+		respondWithError(w, dealershipStatus(err), err.Error())
+		return
+	}
+	r = withDealership(r, dealership)
+
 	switch r.Method {
 	case "GET":
-		if idFromUrl(r) == "-1"{
+		if r.URL.Path == "/cars:compare" {
+			h.compare(w, r)
+			return
+		}
+		switch idFromUrl(r) {
+		case "-1":
 			h.getAll(w, r)
-		} else {
-			h.getById(w, r)
+		case "search":
+			h.search(w, r)
+		case "facets":
+			h.facets(w, r)
+		case "stats":
+			h.stats(w, r)
+		case "events":
+			h.events(w, r)
+		case "duplicates":
+			h.duplicates(w, r)
+		case "valuation":
+			h.valuation(w, r)
+		default:
+			switch actionFromUrl(r) {
+			case "history":
+				h.history(w, r)
+			case "reservations":
+				h.getReservations(w, r)
+			case "prices":
+				h.getPrices(w, r)
+			case "valuation":
+				h.carValuation(w, r)
+			case "financing":
+				h.financing(w, r)
+			case "media":
+				if id, _ := mediaFromUrl(r); id != "" {
+					h.serveMedia(w, r)
+					return
+				}
+				h.getMedia(w, r)
+			default:
+				h.getById(w, r)
+			}
 		}
 		
 	case "POST":
-		h.post(w, r)
+		if r.URL.Path == "/cars:batch" {
+			h.batch(w, r)
+			return
+		}
+		switch actionFromUrl(r) {
+		case "reservations":
+			h.reserve(w, r)
+			return
+		case "prices":
+			h.schedulePrice(w, r)
+			return
+		case "media":
+			h.uploadMedia(w, r)
+			return
+		}
+		switch _, method := customMethod(r); method {
+		case "restore":
+			h.restore(w, r)
+		case "transition":
+			h.transition(w, r)
+		default:
+			h.post(w, r)
+		}
 	case "PUT", "PATCH":
+		if actionFromUrl(r) == "media" {
+			h.updateMedia(w, r)
+			return
+		}
 		h.put(w, r)
 	case "DELETE":
-		h.delete(w, r)
+		switch actionFromUrl(r) {
+		case "reservations":
+			h.release(w, r)
+		case "prices":
+			h.cancelPrice(w, r)
+		case "media":
+			h.deleteMedia(w, r)
+		default:
+			h.delete(w, r)
+		}
 	default:
 		respondWithError(w, http.StatusMethodNotAllowed, "invalid method")
 	}
@@ -32,15 +111,20 @@ func (h *carHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 func newCarHandler() *carHandler {
 	
 	preload := []Car{
-		{"JHk290Xj",	"Ford",		"F10",		"Base",		"Silver",		2010,	"Truck",	120123,		1999900}, 
-		{"fWl37la",		"Toyota",	"Camry",	"SE",		"White",		2019,	"Sedan",	3999,		2899000},
-		{"1i3xjRllc",	"Toyota",	"Rav4",		"XSE",		"Red",			2018,	"SUV",		24001,		2275000},
-		{"dku43920s",	"Ford",		"Bronco",	"Badlands",	"Burnt Orange",	2022,	"SUV",		1,			4499000},
+		{Id: "JHk290Xj", Make: "Ford", Model: "F10", Package: "Base", Color: "Silver", Year: 2010, Category: "Truck", Mileage: 120123, Price: 1999900, MileageUnit: "mi", Dealership: defaultDealership, Status: statusAvailable},
+		{Id: "fWl37la", Make: "Toyota", Model: "Camry", Package: "SE", Color: "White", Year: 2019, Category: "Sedan", Mileage: 3999, Price: 2899000, MileageUnit: "mi", Dealership: defaultDealership, Status: statusAvailable},
+		{Id: "1i3xjRllc", Make: "Toyota", Model: "Rav4", Package: "XSE", Color: "Red", Year: 2018, Category: "SUV", Mileage: 24001, Price: 2275000, MileageUnit: "mi", Dealership: defaultDealership, Status: statusAvailable},
+		{Id: "dku43920s", Make: "Ford", Model: "Bronco", Package: "Badlands", Color: "Burnt Orange", Year: 2022, Category: "SUV", Mileage: 1, Price: 4499000, MileageUnit: "mi", Dealership: defaultDealership, Status: statusAvailable},
 	}
 
-	for _, v := range preload {
-		v.createCar()
+	if len(db.cars) == 0 && len(db.trash.cars) == 0 {
+		for _, v := range preload {
+			v.createCar()
+		}
 	}
-	
-	return &carHandler{}
+
+	h := &carHandler{stream: newEventStream(replayBufferSize, bus.last())}
+	bus.subscribe(h.stream.publish)
+
+	return h
 }
\ No newline at end of file
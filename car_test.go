@@ -195,21 +195,37 @@ func TestUpdateCar_WhenPriceFieldLE_0(t *testing.T){
 
 func TestDeleteCar_WhenSuccessful(t *testing.T){
 	car := Car{ Id: "abcdefghi" }
-	_, err := car.deleteCar()
+	_, err := car.deleteCar("")
 
 	assert.Equal(t, err, nil)
 }
 
 func TestDeleteCar_WhenIdNotFound(t *testing.T){
 	car := Car{ Id: "zzzzzzzzz" }
-	_, err := car.deleteCar()
+	_, err := car.deleteCar("")
 
 	assert.Equal(t, err.Error(), "id not found")
 }
 
 func TestDeleteCar_WhenIdFieldEmpty(t *testing.T){
 	car := Car{ Id:"" }
-	_, err := car.deleteCar()
+	_, err := car.deleteCar("")
 
 	assert.Equal(t, err.Error(), "id field empty")
-}
\ No newline at end of file
+}
+
+func TestCreateCar_WhenMileageInKm(t *testing.T){
+	car := Car{ Id: "kmkmkmkmk", Make: "Nissan", Model: "Kicks", Package: "XX", Color: "Gray", Year: 2013, Category: "SUV", Mileage: 1609.344, Price: 2499000, MileageUnit: "km" }
+	q, err := car.createCar()
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, q.Mileage, 1000.0)
+	assert.Equal(t, q.MileageUnit, "mi")
+}
+
+func TestCreateCar_WhenMileageUnitInvalid(t *testing.T){
+	car := Car{ Id: "opqrstuvw", Make: "Nissan", Model: "Altima", Package: "XX", Color: "Gray", Year: 2013, Category: "SUV", Mileage: 799, Price: 2499000, MileageUnit: "ft" }
+	_, err := car.createCar()
+
+	assert.Equal(t, err.Error(), "mileage_unit field must be km or mi")
+}
@@ -0,0 +1,414 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: cars.proto
+
+package carpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	CarService_Get_FullMethodName        = "/cars.v1.CarService/Get"
+	CarService_List_FullMethodName       = "/cars.v1.CarService/List"
+	CarService_Create_FullMethodName     = "/cars.v1.CarService/Create"
+	CarService_Update_FullMethodName     = "/cars.v1.CarService/Update"
+	CarService_Delete_FullMethodName     = "/cars.v1.CarService/Delete"
+	CarService_Transition_FullMethodName = "/cars.v1.CarService/Transition"
+	CarService_Watch_FullMethodName      = "/cars.v1.CarService/Watch"
+)
+
+// CarServiceClient is the client API for CarService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CarServiceClient interface {
+	// Get returns a single car. NOT_FOUND if the id does not exist.
+	Get(ctx context.Context, in *GetCarRequest, opts ...grpc.CallOption) (*Car, error)
+	// List streams the cars matching the filter, in the requested order.
+	List(ctx context.Context, in *ListCarsRequest, opts ...grpc.CallOption) (CarService_ListClient, error)
+	// Create adds a car. INVALID_ARGUMENT on validation errors,
+	// ALREADY_EXISTS if the id is taken or the car looks like a listed one.
+	Create(ctx context.Context, in *CreateCarRequest, opts ...grpc.CallOption) (*Car, error)
+	// Update replaces a car. INVALID_ARGUMENT on validation errors, NOT_FOUND
+	// if the id does not exist.
+	Update(ctx context.Context, in *UpdateCarRequest, opts ...grpc.CallOption) (*Car, error)
+	// Delete moves a car to the trash. NOT_FOUND if the id does not exist.
+	Delete(ctx context.Context, in *DeleteCarRequest, opts ...grpc.CallOption) (*DeleteCarResponse, error)
+	// Transition changes the status of a car. NOT_FOUND if the id does not
+	// exist, FAILED_PRECONDITION if the transition is not allowed. Cars
+	// become reserved only through a reservation.
+	Transition(ctx context.Context, in *TransitionCarRequest, opts ...grpc.CallOption) (*Car, error)
+	// Watch streams car lifecycle events as they happen, starting after
+	// last_event_id. A "reset" event comes first when events after
+	// last_event_id were already evicted from the replay buffer, or when
+	// last_event_id is from before the server restarted.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (CarService_WatchClient, error)
+}
+
+type carServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCarServiceClient(cc grpc.ClientConnInterface) CarServiceClient {
+	return &carServiceClient{cc}
+}
+
+func (c *carServiceClient) Get(ctx context.Context, in *GetCarRequest, opts ...grpc.CallOption) (*Car, error) {
+	out := new(Car)
+	err := c.cc.Invoke(ctx, CarService_Get_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *carServiceClient) List(ctx context.Context, in *ListCarsRequest, opts ...grpc.CallOption) (CarService_ListClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CarService_ServiceDesc.Streams[0], CarService_List_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &carServiceListClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CarService_ListClient interface {
+	Recv() (*Car, error)
+	grpc.ClientStream
+}
+
+type carServiceListClient struct {
+	grpc.ClientStream
+}
+
+func (x *carServiceListClient) Recv() (*Car, error) {
+	m := new(Car)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *carServiceClient) Create(ctx context.Context, in *CreateCarRequest, opts ...grpc.CallOption) (*Car, error) {
+	out := new(Car)
+	err := c.cc.Invoke(ctx, CarService_Create_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *carServiceClient) Update(ctx context.Context, in *UpdateCarRequest, opts ...grpc.CallOption) (*Car, error) {
+	out := new(Car)
+	err := c.cc.Invoke(ctx, CarService_Update_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *carServiceClient) Delete(ctx context.Context, in *DeleteCarRequest, opts ...grpc.CallOption) (*DeleteCarResponse, error) {
+	out := new(DeleteCarResponse)
+	err := c.cc.Invoke(ctx, CarService_Delete_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *carServiceClient) Transition(ctx context.Context, in *TransitionCarRequest, opts ...grpc.CallOption) (*Car, error) {
+	out := new(Car)
+	err := c.cc.Invoke(ctx, CarService_Transition_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *carServiceClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (CarService_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CarService_ServiceDesc.Streams[1], CarService_Watch_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &carServiceWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CarService_WatchClient interface {
+	Recv() (*CarEvent, error)
+	grpc.ClientStream
+}
+
+type carServiceWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *carServiceWatchClient) Recv() (*CarEvent, error) {
+	m := new(CarEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CarServiceServer is the server API for CarService service.
+// All implementations must embed UnimplementedCarServiceServer
+// for forward compatibility
+type CarServiceServer interface {
+	// Get returns a single car. NOT_FOUND if the id does not exist.
+	Get(context.Context, *GetCarRequest) (*Car, error)
+	// List streams the cars matching the filter, in the requested order.
+	List(*ListCarsRequest, CarService_ListServer) error
+	// Create adds a car. INVALID_ARGUMENT on validation errors,
+	// ALREADY_EXISTS if the id is taken or the car looks like a listed one.
+	Create(context.Context, *CreateCarRequest) (*Car, error)
+	// Update replaces a car. INVALID_ARGUMENT on validation errors, NOT_FOUND
+	// if the id does not exist.
+	Update(context.Context, *UpdateCarRequest) (*Car, error)
+	// Delete moves a car to the trash. NOT_FOUND if the id does not exist.
+	Delete(context.Context, *DeleteCarRequest) (*DeleteCarResponse, error)
+	// Transition changes the status of a car. NOT_FOUND if the id does not
+	// exist, FAILED_PRECONDITION if the transition is not allowed. Cars
+	// become reserved only through a reservation.
+	Transition(context.Context, *TransitionCarRequest) (*Car, error)
+	// Watch streams car lifecycle events as they happen, starting after
+	// last_event_id. A "reset" event comes first when events after
+	// last_event_id were already evicted from the replay buffer, or when
+	// last_event_id is from before the server restarted.
+	Watch(*WatchRequest, CarService_WatchServer) error
+	mustEmbedUnimplementedCarServiceServer()
+}
+
+// UnimplementedCarServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedCarServiceServer struct {
+}
+
+func (UnimplementedCarServiceServer) Get(context.Context, *GetCarRequest) (*Car, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedCarServiceServer) List(*ListCarsRequest, CarService_ListServer) error {
+	return status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedCarServiceServer) Create(context.Context, *CreateCarRequest) (*Car, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Create not implemented")
+}
+func (UnimplementedCarServiceServer) Update(context.Context, *UpdateCarRequest) (*Car, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Update not implemented")
+}
+func (UnimplementedCarServiceServer) Delete(context.Context, *DeleteCarRequest) (*DeleteCarResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedCarServiceServer) Transition(context.Context, *TransitionCarRequest) (*Car, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Transition not implemented")
+}
+func (UnimplementedCarServiceServer) Watch(*WatchRequest, CarService_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedCarServiceServer) mustEmbedUnimplementedCarServiceServer() {}
+
+// UnsafeCarServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CarServiceServer will
+// result in compilation errors.
+type UnsafeCarServiceServer interface {
+	mustEmbedUnimplementedCarServiceServer()
+}
+
+func RegisterCarServiceServer(s grpc.ServiceRegistrar, srv CarServiceServer) {
+	s.RegisterService(&CarService_ServiceDesc, srv)
+}
+
+func _CarService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCarRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CarServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CarService_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CarServiceServer).Get(ctx, req.(*GetCarRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CarService_List_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListCarsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CarServiceServer).List(m, &carServiceListServer{stream})
+}
+
+type CarService_ListServer interface {
+	Send(*Car) error
+	grpc.ServerStream
+}
+
+type carServiceListServer struct {
+	grpc.ServerStream
+}
+
+func (x *carServiceListServer) Send(m *Car) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _CarService_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCarRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CarServiceServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CarService_Create_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CarServiceServer).Create(ctx, req.(*CreateCarRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CarService_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateCarRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CarServiceServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CarService_Update_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CarServiceServer).Update(ctx, req.(*UpdateCarRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CarService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteCarRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CarServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CarService_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CarServiceServer).Delete(ctx, req.(*DeleteCarRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CarService_Transition_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransitionCarRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CarServiceServer).Transition(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CarService_Transition_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CarServiceServer).Transition(ctx, req.(*TransitionCarRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CarService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CarServiceServer).Watch(m, &carServiceWatchServer{stream})
+}
+
+type CarService_WatchServer interface {
+	Send(*CarEvent) error
+	grpc.ServerStream
+}
+
+type carServiceWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *carServiceWatchServer) Send(m *CarEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// CarService_ServiceDesc is the grpc.ServiceDesc for CarService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CarService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cars.v1.CarService",
+	HandlerType: (*CarServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    _CarService_Get_Handler,
+		},
+		{
+			MethodName: "Create",
+			Handler:    _CarService_Create_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _CarService_Update_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _CarService_Delete_Handler,
+		},
+		{
+			MethodName: "Transition",
+			Handler:    _CarService_Transition_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "List",
+			Handler:       _CarService_List_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _CarService_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "cars.proto",
+}
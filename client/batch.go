@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Batch modes and operations.
+const (
+	BatchAtomic     = "atomic"
+	BatchBestEffort = "best_effort"
+
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// BatchOperation creates or updates Car, or deletes the car with Id. A
+// create that looks like a listed car fails with 409 unless
+// AllowDuplicate is set.
+type BatchOperation struct {
+	Op             string `json:"op"`
+	Car            Car    `json:"car"`
+	Id             string `json:"id,omitempty"`
+	AllowDuplicate bool   `json:"allow_duplicate,omitempty"`
+}
+
+// BatchResult is the outcome of one operation, with the status code the
+// equivalent single request would have returned.
+type BatchResult struct {
+	Status     int              `json:"status"`
+	Car        *Car             `json:"car,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	Duplicates []DuplicateMatch `json:"duplicates,omitempty"`
+}
+
+type BatchResponse struct {
+	Mode      string        `json:"mode"`
+	Committed bool          `json:"committed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// Batch applies ops in order with POST /cars:batch. When an atomic batch
+// is rolled back the per-operation results are returned together with the
+// *Error of the first failure. It is not retried.
+func (c *Client) Batch(ctx context.Context, mode string, ops []BatchOperation) (BatchResponse, error) {
+	in := struct {
+		Mode       string           `json:"mode,omitempty"`
+		Operations []BatchOperation `json:"operations"`
+	}{mode, ops}
+
+	var out BatchResponse
+	err := c.do(ctx, "POST", "/cars:batch", nil, in, &out)
+	if e, ok := err.(*Error); ok && json.Unmarshal(e.Body, &out) == nil {
+		for _, r := range out.Results {
+			if r.Error != "" && r.Status == e.StatusCode {
+				e.Message = r.Error
+				break
+			}
+		}
+	}
+	return out, err
+}
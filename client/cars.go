@@ -0,0 +1,290 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func carPath(id string) string {
+	return "/cars/" + url.PathEscape(id)
+}
+
+// ListCars lists the cars matching opts, which may be nil.
+func (c *Client) ListCars(ctx context.Context, opts *ListOptions) ([]Car, error) {
+	var cars []Car
+	err := c.do(ctx, "GET", "/cars", opts.values(), nil, &cars)
+	return cars, err
+}
+
+// ListDeletedCars lists the cars in the trash matching opts.
+func (c *Client) ListDeletedCars(ctx context.Context, opts *ListOptions) ([]TrashedCar, error) {
+	q := opts.values()
+	q.Set("deleted", "true")
+
+	var cars []TrashedCar
+	err := c.do(ctx, "GET", "/cars", q, nil, &cars)
+	return cars, err
+}
+
+// GetCar gets a car by id. opts may be nil.
+func (c *Client) GetCar(ctx context.Context, id string, opts *GetOptions) (Car, error) {
+	q := url.Values{}
+	if opts != nil {
+		if opts.Units != "" {
+			q.Set("units", opts.Units)
+		}
+		if !opts.AsOf.IsZero() {
+			q.Set("as_of", opts.AsOf.Format(time.RFC3339Nano))
+		}
+	}
+
+	var car Car
+	err := c.do(ctx, "GET", carPath(id), q, nil, &car)
+	return car, err
+}
+
+// CreateCar adds a car. It fails with ErrConflict when the car looks like
+// one already listed; Duplicates lists them. It is not retried.
+func (c *Client) CreateCar(ctx context.Context, car Car) (Car, error) {
+	var out Car
+	err := c.do(ctx, "POST", "/cars", nil, car, &out)
+	return out, err
+}
+
+// CreateCarAllowingDuplicate adds a car even if it looks like one already
+// listed. It is not retried.
+func (c *Client) CreateCarAllowingDuplicate(ctx context.Context, car Car) (Car, error) {
+	var out Car
+	q := url.Values{"allow_duplicate": {"true"}}
+	err := c.do(ctx, "POST", "/cars", q, car, &out)
+	return out, err
+}
+
+// CarDuplicates groups the listed cars that look like the same vehicle.
+// units may be empty.
+func (c *Client) CarDuplicates(ctx context.Context, units string) ([]DuplicateGroup, error) {
+	q := url.Values{}
+	if units != "" {
+		q.Set("units", units)
+	}
+
+	var groups []DuplicateGroup
+	err := c.do(ctx, "GET", "/cars/duplicates", q, nil, &groups)
+	return groups, err
+}
+
+// UpdateCar replaces the car with the same id.
+func (c *Client) UpdateCar(ctx context.Context, car Car) (Car, error) {
+	var out Car
+	err := c.do(ctx, "PUT", "/cars", nil, car, &out)
+	return out, err
+}
+
+// DeleteCar moves a car to the trash.
+func (c *Client) DeleteCar(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", carPath(id), nil, nil, nil)
+}
+
+// RestoreCar moves a car back from the trash. It fails with ErrConflict if
+// another car now uses its id.
+func (c *Client) RestoreCar(ctx context.Context, id string) (Car, error) {
+	var car Car
+	err := c.do(ctx, "POST", carPath(id)+":restore", nil, nil, &car)
+	return car, err
+}
+
+// TransitionCar moves a car to another status, recording reason in its
+// history. It fails with ErrConflict if the transition is not allowed.
+func (c *Client) TransitionCar(ctx context.Context, id, status, reason string) (Car, error) {
+	var car Car
+	in := map[string]string{"status": status, "reason": reason}
+	err := c.do(ctx, "POST", carPath(id)+":transition", nil, in, &car)
+	return car, err
+}
+
+// ReserveCar holds an available car for customer for the given hours, or
+// 48 when hours is 0. It fails with ErrConflict if the car is already held
+// or not available.
+func (c *Client) ReserveCar(ctx context.Context, id, customer string, hours int) (Reservation, error) {
+	var r Reservation
+	in := map[string]interface{}{"customer": customer, "hours": hours}
+	err := c.do(ctx, "POST", carPath(id)+"/reservations", nil, in, &r)
+	return r, err
+}
+
+// CarReservations lists the active hold on a car, if any.
+func (c *Client) CarReservations(ctx context.Context, id string) ([]Reservation, error) {
+	var rs []Reservation
+	err := c.do(ctx, "GET", carPath(id)+"/reservations", nil, nil, &rs)
+	return rs, err
+}
+
+// ReleaseReservation ends a hold before it expires. Only the user who
+// placed it may release it.
+func (c *Client) ReleaseReservation(ctx context.Context, id, reservationId string) (Car, error) {
+	var car Car
+	err := c.do(ctx, "DELETE", carPath(id)+"/reservations/"+url.PathEscape(reservationId), nil, nil, &car)
+	return car, err
+}
+
+// CarPrices returns the price timeline of a car.
+func (c *Client) CarPrices(ctx context.Context, id string) (PriceTimeline, error) {
+	var t PriceTimeline
+	err := c.do(ctx, "GET", carPath(id)+"/prices", nil, nil, &t)
+	return t, err
+}
+
+// SchedulePrice changes the price of a car to price at effectiveAt, which
+// must be in the future.
+func (c *Client) SchedulePrice(ctx context.Context, id string, price float64, effectiveAt time.Time) (ScheduledPrice, error) {
+	var p ScheduledPrice
+	in := map[string]interface{}{"price": price, "effective_at": effectiveAt}
+	err := c.do(ctx, "POST", carPath(id)+"/prices", nil, in, &p)
+	return p, err
+}
+
+// CancelScheduledPrice removes a price change that was not applied yet.
+func (c *Client) CancelScheduledPrice(ctx context.Context, id, scheduleId string) error {
+	return c.do(ctx, "DELETE", carPath(id)+"/prices/"+url.PathEscape(scheduleId), nil, nil, nil)
+}
+
+// DecodeVin validates a VIN and decodes its manufacturer, country and
+// model year.
+func (c *Client) DecodeVin(ctx context.Context, vin string) (VinInfo, error) {
+	var info VinInfo
+	err := c.do(ctx, "GET", "/vin/"+url.PathEscape(vin), nil, nil, &info)
+	return info, err
+}
+
+// CarHistory lists every version of a car, oldest first.
+func (c *Client) CarHistory(ctx context.Context, id string) ([]CarVersion, error) {
+	var versions []CarVersion
+	err := c.do(ctx, "GET", carPath(id)+"/history", nil, nil, &versions)
+	return versions, err
+}
+
+// ValueCar estimates the market price of a car from comparable listed cars.
+func (c *Client) ValueCar(ctx context.Context, in ValuationQuery) (Valuation, error) {
+	q := url.Values{"make": {in.Make}, "model": {in.Model}, "year": {strconv.Itoa(in.Year)}}
+	if in.Category != "" {
+		q.Set("category", in.Category)
+	}
+	if in.Mileage != 0 {
+		q.Set("mileage", strconv.FormatFloat(in.Mileage, 'f', -1, 64))
+	}
+	if in.Units != "" {
+		q.Set("units", in.Units)
+	}
+
+	var v Valuation
+	err := c.do(ctx, "GET", "/cars/valuation", q, nil, &v)
+	return v, err
+}
+
+// CarValuation estimates the market price of a listed car from the other
+// cars. units may be empty.
+func (c *Client) CarValuation(ctx context.Context, id, units string) (Valuation, error) {
+	q := url.Values{}
+	if units != "" {
+		q.Set("units", units)
+	}
+
+	var v Valuation
+	err := c.do(ctx, "GET", carPath(id)+"/valuation", q, nil, &v)
+	return v, err
+}
+
+// CarFinancing prices a loan for a car.
+func (c *Client) CarFinancing(ctx context.Context, id string, in FinancingQuery) (Financing, error) {
+	q := url.Values{}
+	if in.Down != 0 {
+		q.Set("down", strconv.FormatFloat(in.Down, 'f', -1, 64))
+	}
+	if in.Tier != "" {
+		q.Set("tier", in.Tier)
+	} else {
+		q.Set("apr", strconv.FormatFloat(in.Apr, 'f', -1, 64))
+	}
+	if in.Term != 0 {
+		q.Set("term", strconv.Itoa(in.Term))
+	}
+
+	var f Financing
+	err := c.do(ctx, "GET", carPath(id)+"/financing", q, nil, &f)
+	return f, err
+}
+
+// LenderRates lists the loan rates of each credit tier by term.
+func (c *Client) LenderRates(ctx context.Context) (map[string][]TermRate, error) {
+	var rates map[string][]TermRate
+	err := c.do(ctx, "GET", "/financing/rates", nil, nil, &rates)
+	return rates, err
+}
+
+// CompareCars lines up two or three cars field by field. units may be
+// empty. When cars are not found, MissingIds lists them from the error.
+func (c *Client) CompareCars(ctx context.Context, ids []string, units string) (Comparison, error) {
+	q := url.Values{"ids": {strings.Join(ids, ",")}}
+	if units != "" {
+		q.Set("units", units)
+	}
+
+	var out Comparison
+	err := c.do(ctx, "GET", "/cars:compare", q, nil, &out)
+	return out, err
+}
+
+// SearchCars runs a full-text search. units may be empty.
+func (c *Client) SearchCars(ctx context.Context, q, units string) ([]SearchResult, error) {
+	v := url.Values{"q": {q}}
+	if units != "" {
+		v.Set("units", units)
+	}
+
+	var results []SearchResult
+	err := c.do(ctx, "GET", "/cars/search", v, nil, &results)
+	return results, err
+}
+
+// CarFacets counts the cars matching opts per make, category, color, year
+// and price and mileage range.
+func (c *Client) CarFacets(ctx context.Context, opts *FacetOptions) (Facets, error) {
+	q := url.Values{}
+	if opts != nil {
+		q = opts.ListOptions.values()
+		if opts.PriceInterval != 0 {
+			q.Set("price_interval", formatFloat(opts.PriceInterval))
+		}
+		if opts.MileageInterval != 0 {
+			q.Set("mileage_interval", formatFloat(opts.MileageInterval))
+		}
+	}
+
+	var f Facets
+	err := c.do(ctx, "GET", "/cars/facets", q, nil, &f)
+	return f, err
+}
+
+// CarStats aggregates the cars matching opts.
+func (c *Client) CarStats(ctx context.Context, opts *StatsOptions) ([]StatsGroup, error) {
+	q := url.Values{}
+	if opts != nil {
+		q = opts.ListOptions.values()
+		if len(opts.GroupBy) > 0 {
+			q.Set("group_by", strings.Join(opts.GroupBy, ","))
+		}
+		if len(opts.Fields) > 0 {
+			q.Set("fields", strings.Join(opts.Fields, ","))
+		}
+		if len(opts.Percentiles) > 0 {
+			q.Set("percentiles", joinFloats(opts.Percentiles))
+		}
+	}
+
+	var groups []StatsGroup
+	err := c.do(ctx, "GET", "/cars/stats", q, nil, &groups)
+	return groups, err
+}
@@ -0,0 +1,193 @@
+// Package client is a typed Go client for the cars API.
+//
+//	c := client.New("http://localhost:8080", client.WithBasicAuth("alice", "secret"))
+//	car, err := c.GetCar(ctx, "JHk290Xj", nil)
+//	if client.IsNotFound(err) {
+//		...
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client calls the cars API. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	username   string
+	password   string
+	dealership string
+	maxRetries int
+	backoff    time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the http.Client used for requests.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithBasicAuth authenticates every request with basic auth. The server
+// records the user as the actor of changes and the holder of reservations.
+func WithBasicAuth(username, password string) Option {
+	return func(c *Client) { c.username, c.password = username, password }
+}
+
+// WithDealership sends the X-Dealership header, acting on that dealership's
+// inventory instead of the home dealership of the user. It needs
+// WithBasicAuth, and the user must be a group admin to name a dealership
+// other than their own.
+func WithDealership(dealership string) Option {
+	return func(c *Client) { c.dealership = dealership }
+}
+
+// WithRetries retries idempotent calls (GET, PUT, DELETE) up to maxRetries
+// times on network errors, 429 and 5xx responses, doubling backoff after
+// every attempt.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) { c.maxRetries, c.backoff = maxRetries, backoff }
+}
+
+// New returns a client for the server at baseURL, e.g.
+// "http://localhost:8080". By default idempotent calls are retried twice
+// starting at 200ms.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		maxRetries: 2,
+		backoff:    200 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func idempotent(method string) bool {
+	return method == "GET" || method == "PUT" || method == "DELETE"
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// do sends the request and decodes a successful JSON response into out,
+// which may be nil. Error responses are returned as *Error.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = b
+	}
+
+	attempts := 1
+	if idempotent(method) {
+		attempts += c.maxRetries
+	}
+
+	delay := c.backoff
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			delay *= 2
+		}
+
+		var res *http.Response
+		res, err = c.send(ctx, method, path, query, body)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+
+		err = decode(res, out)
+		if e, ok := err.(*Error); ok && retryable(e.StatusCode) {
+			continue
+		}
+		return err
+	}
+	return err
+}
+
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body []byte) (*http.Response, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("content-type", "application/json")
+	}
+	req.Header.Set("accept", "application/json")
+	c.authorize(req)
+
+	return c.httpClient.Do(req)
+}
+
+func (c *Client) authorize(req *http.Request) {
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	if c.dealership != "" {
+		req.Header.Set("X-Dealership", c.dealership)
+	}
+}
+
+func decode(res *http.Response, out interface{}) error {
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		e := &Error{StatusCode: res.StatusCode, Body: b}
+		var body struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(b, &body) == nil && body.Error != "" {
+			e.Message = body.Error
+		} else {
+			e.Message = strings.TrimSpace(string(b))
+		}
+		return e
+	}
+
+	if out == nil || len(b) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("decoding response: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Sentinel errors matched by errors.Is against an *Error.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrValidation = errors.New("validation failed")
+	ErrConflict   = errors.New("conflict")
+)
+
+// Error is an error response from the server. Message is the "error"
+// field of the body, e.g. "make field empty".
+type Error struct {
+	StatusCode int
+	Message    string
+	// Body is the raw response body.
+	Body []byte
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("cars api: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Is reports whether the response status matches one of the sentinel
+// errors: 404 is ErrNotFound, 400 is ErrValidation and 409 is ErrConflict.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrValidation:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	}
+	return false
+}
+
+// IsNotFound reports whether err is a 404 from the server.
+func IsNotFound(err error) bool { return errors.Is(err, ErrNotFound) }
+
+// IsValidation reports whether err is a 400 from the server.
+func IsValidation(err error) bool { return errors.Is(err, ErrValidation) }
+
+// IsConflict reports whether err is a 409 from the server.
+func IsConflict(err error) bool { return errors.Is(err, ErrConflict) }
+
+// Duplicates returns the listed cars a create was rejected for looking
+// like, or nil when err is not such a rejection.
+func Duplicates(err error) []DuplicateMatch {
+	var e *Error
+	if !errors.As(err, &e) || e.StatusCode != http.StatusConflict {
+		return nil
+	}
+	var body struct {
+		Duplicates []DuplicateMatch `json:"duplicates"`
+	}
+	if json.Unmarshal(e.Body, &body) != nil {
+		return nil
+	}
+	return body.Duplicates
+}
+
+// MissingIds returns the ids a comparison was rejected for not finding,
+// or nil when err is not such a rejection.
+func MissingIds(err error) []string {
+	var e *Error
+	if !errors.As(err, &e) || e.StatusCode != http.StatusNotFound {
+		return nil
+	}
+	var body struct {
+		Missing []string `json:"missing"`
+	}
+	if json.Unmarshal(e.Body, &body) != nil {
+		return nil
+	}
+	return body.Missing
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Events streams car events from GET /cars/events, calling fn for each
+// until ctx is cancelled, the server closes the stream or fn returns an
+// error. opts may be nil. Streams are not retried; resume by passing the
+// id of the last event received as opts.LastEventId.
+func (c *Client) Events(ctx context.Context, opts *EventOptions, fn func(Event) error) error {
+	q := url.Values{}
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/cars/events", nil)
+	if err != nil {
+		return err
+	}
+	if opts != nil {
+		if opts.Make != "" {
+			q.Set("make", opts.Make)
+		}
+		if opts.Category != "" {
+			q.Set("category", opts.Category)
+		}
+		if opts.LastEventId != 0 {
+			req.Header.Set("Last-Event-ID", strconv.FormatUint(opts.LastEventId, 10))
+		}
+	}
+	req.URL.RawQuery = q.Encode()
+	req.Header.Set("accept", "text/event-stream")
+	c.authorize(req)
+
+	// The stream outlives any client timeout, so only the transport is
+	// shared.
+	hc := *c.httpClient
+	hc.Timeout = 0
+	res, err := hc.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return decode(res, nil)
+	}
+	defer res.Body.Close()
+
+	var typ, data string
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if typ == "" && data == "" {
+				continue
+			}
+			e := Event{Type: typ}
+			if data != "" {
+				if err := json.Unmarshal([]byte(data), &e); err != nil {
+					return err
+				}
+			}
+			if err := fn(e); err != nil {
+				return err
+			}
+			typ, data = "", ""
+		case strings.HasPrefix(line, "event:"):
+			typ = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data += strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return scanner.Err()
+}
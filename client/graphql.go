@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// GraphQLError is an error reported in the errors of a GraphQL response.
+type GraphQLError struct {
+	Messages []string
+}
+
+func (e *GraphQLError) Error() string {
+	return "graphql: " + strings.Join(e.Messages, "; ")
+}
+
+// GraphQL runs a query or mutation against /graphql and decodes its data
+// into out, which may be nil. Errors in the response are returned as
+// *GraphQLError. It is not retried.
+func (c *Client) GraphQL(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	in := map[string]interface{}{"query": query, "variables": variables}
+
+	var res struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := c.do(ctx, "POST", "/graphql", nil, in, &res); err != nil {
+		return err
+	}
+
+	if len(res.Errors) > 0 {
+		e := &GraphQLError{}
+		for _, v := range res.Errors {
+			e.Messages = append(e.Messages, v.Message)
+		}
+		return e
+	}
+
+	if out == nil || len(res.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(res.Data, out); err != nil {
+		return fmt.Errorf("decoding response: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+)
+
+// CarMedia lists the photos of a car in display order.
+func (c *Client) CarMedia(ctx context.Context, id string) ([]Media, error) {
+	var ms []Media
+	err := c.do(ctx, "GET", carPath(id)+"/media", nil, nil, &ms)
+	return ms, err
+}
+
+// UploadCarMedia adds the JPEG, PNG or GIF photo read from r after the
+// other photos of a car. It is not retried.
+func (c *Client) UploadCarMedia(ctx context.Context, id, filename string, r io.Reader) (Media, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		return Media{}, err
+	}
+	if _, err := io.Copy(part, r); err != nil {
+		return Media{}, err
+	}
+	if err := mw.Close(); err != nil {
+		return Media{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+carPath(id)+"/media", &body)
+	if err != nil {
+		return Media{}, err
+	}
+	req.Header.Set("content-type", mw.FormDataContentType())
+	req.Header.Set("accept", "application/json")
+	c.authorize(req)
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return Media{}, err
+	}
+	var md Media
+	err = decode(res, &md)
+	return md, err
+}
+
+// MoveCarMedia moves a photo to position, counted from 0, and makes it the
+// primary photo of the car when primary is true.
+func (c *Client) MoveCarMedia(ctx context.Context, id, mediaId string, position int, primary bool) (Media, error) {
+	var md Media
+	in := map[string]interface{}{"position": position, "primary": primary}
+	err := c.do(ctx, "PATCH", carPath(id)+"/media/"+url.PathEscape(mediaId), nil, in, &md)
+	return md, err
+}
+
+// DeleteCarMedia removes a photo and its thumbnails.
+func (c *Client) DeleteCarMedia(ctx context.Context, id, mediaId string) error {
+	return c.do(ctx, "DELETE", carPath(id)+"/media/"+url.PathEscape(mediaId), nil, nil, nil)
+}
@@ -0,0 +1,400 @@
+package client
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Car mirrors the server's car. Mileage is in MileageUnit, "mi" or "km".
+// Status is one of the Status constants and is changed with TransitionCar.
+type Car struct {
+	Id            string   `json:"Id"`
+	Make          string   `json:"Make"`
+	Model         string   `json:"Model"`
+	Package       string   `json:"Package"`
+	Color         string   `json:"Color"`
+	Year          int      `json:"Year"`
+	Category      string   `json:"Category"`
+	Mileage       float64  `json:"Mileage"`
+	Price         float64  `json:"Price"`
+	MileageUnit   string   `json:"mileage_unit,omitempty"`
+	Dealership    string   `json:"dealership,omitempty"`
+	Status        string   `json:"status,omitempty"`
+	PreviousPrice float64  `json:"previous_price,omitempty"`
+	Vin           string   `json:"vin,omitempty"`
+	Country       string   `json:"country,omitempty"`
+	VinWarnings   []string `json:"vin_warnings,omitempty"`
+	Media         []Media  `json:"media,omitempty"`
+}
+
+// Lifecycle statuses of a car.
+const (
+	StatusIncoming  = "incoming"
+	StatusInTransit = "in_transit"
+	StatusAvailable = "available"
+	StatusReserved  = "reserved"
+	StatusSold      = "sold"
+)
+
+// Reservation holds a car for a customer until ExpiresAt. While it is
+// active only HeldBy may update, delete or transition the car.
+type Reservation struct {
+	Id        string    `json:"id"`
+	CarId     string    `json:"car_id"`
+	Customer  string    `json:"customer"`
+	HeldBy    string    `json:"held_by"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ScheduledPrice changes the price of a car at EffectiveAt.
+type ScheduledPrice struct {
+	Id          string    `json:"id"`
+	CarId       string    `json:"car_id"`
+	Price       float64   `json:"price"`
+	EffectiveAt time.Time `json:"effective_at"`
+	ScheduledBy string    `json:"scheduled_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// PricePoint is a price a car had from At on.
+type PricePoint struct {
+	Price float64   `json:"price"`
+	At    time.Time `json:"at"`
+}
+
+// PriceTimeline is the past and scheduled prices of a car.
+type PriceTimeline struct {
+	CarId         string           `json:"car_id"`
+	Price         float64          `json:"price"`
+	PreviousPrice float64          `json:"previous_price,omitempty"`
+	History       []PricePoint     `json:"history"`
+	Scheduled     []ScheduledPrice `json:"scheduled"`
+}
+
+// DuplicateMatch is a listed car that looks like the same vehicle, with
+// Reason "same vin" or a similar make, model, year, color and mileage.
+type DuplicateMatch struct {
+	Car    Car    `json:"car"`
+	Reason string `json:"reason"`
+}
+
+// DuplicateGroup is a set of listed cars that look like the same vehicle.
+type DuplicateGroup struct {
+	Reason string `json:"reason"`
+	Cars   []Car  `json:"cars"`
+}
+
+// ValuationQuery describes a car to value. Category is used when the
+// model has too few comparable cars, and Units may be empty.
+type ValuationQuery struct {
+	Make     string
+	Model    string
+	Category string
+	Year     int
+	Mileage  float64
+	Units    string
+}
+
+// Valuation is the estimated market price of a car, with a 95% range and
+// the comparable cars it was fitted over.
+type Valuation struct {
+	Make        string  `json:"make"`
+	Model       string  `json:"model"`
+	Category    string  `json:"category,omitempty"`
+	Year        int     `json:"year"`
+	Mileage     float64 `json:"mileage"`
+	Estimate    float64 `json:"estimate"`
+	Low         float64 `json:"low"`
+	High        float64 `json:"high"`
+	Confidence  float64 `json:"confidence"`
+	Basis       string  `json:"basis"`
+	Method      string  `json:"method"`
+	Comparables []Car   `json:"comparables"`
+}
+
+// FinancingQuery describes a car loan. The rate is Apr, or the lender rate
+// of Tier when Tier is set. A zero Term means 60 months.
+type FinancingQuery struct {
+	Down float64
+	Apr  float64
+	Tier string
+	Term int
+}
+
+// Installment is one monthly payment of a loan.
+type Installment struct {
+	Month     int     `json:"month"`
+	Payment   float64 `json:"payment"`
+	Principal float64 `json:"principal"`
+	Interest  float64 `json:"interest"`
+	Balance   float64 `json:"balance"`
+}
+
+// Financing is the cost of buying a car with a fixed rate loan.
+type Financing struct {
+	CarId          string        `json:"car_id"`
+	Price          float64       `json:"price"`
+	Down           float64       `json:"down"`
+	Principal      float64       `json:"principal"`
+	Tier           string        `json:"tier,omitempty"`
+	Apr            float64       `json:"apr"`
+	Term           int           `json:"term"`
+	MonthlyPayment float64       `json:"monthly_payment"`
+	TotalInterest  float64       `json:"total_interest"`
+	TotalCost      float64       `json:"total_cost"`
+	Schedule       []Installment `json:"schedule"`
+}
+
+// TermRate is the annual percentage rate of loans of up to Term months.
+type TermRate struct {
+	Term int     `json:"term"`
+	Apr  float64 `json:"apr"`
+}
+
+// FieldComparison is one field of compared cars, with a value per car.
+// Deltas, set for price, mileage and year, are the differences from the
+// first car.
+type FieldComparison struct {
+	Field   string        `json:"field"`
+	Values  []interface{} `json:"values"`
+	Differs bool          `json:"differs"`
+	Deltas  []float64     `json:"deltas,omitempty"`
+}
+
+// Comparison lines up cars field by field.
+type Comparison struct {
+	Ids    []string          `json:"ids"`
+	Cars   []Car             `json:"cars"`
+	Fields []FieldComparison `json:"fields"`
+}
+
+// VinInfo is what a VIN says about a car. Make is empty when the
+// manufacturer is unknown.
+type VinInfo struct {
+	Vin          string `json:"vin"`
+	Wmi          string `json:"wmi"`
+	Make         string `json:"make,omitempty"`
+	Country      string `json:"country,omitempty"`
+	Year         int    `json:"year"`
+	SerialNumber string `json:"serial_number"`
+}
+
+// Media is a photo of a car. Url and the Thumbnails, keyed by size
+// ("small", "medium", "large"), are paths on the server.
+type Media struct {
+	Id          string            `json:"id"`
+	CarId       string            `json:"car_id"`
+	ContentType string            `json:"content_type"`
+	Size        int               `json:"size"`
+	Width       int               `json:"width"`
+	Height      int               `json:"height"`
+	Position    int               `json:"position"`
+	Primary     bool              `json:"primary"`
+	Url         string            `json:"url"`
+	Thumbnails  map[string]string `json:"thumbnails"`
+	UploadedBy  string            `json:"uploaded_by"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// TrashedCar is a deleted car waiting in the trash.
+type TrashedCar struct {
+	Car       Car       `json:"car"`
+	DeletedAt time.Time `json:"deleted_at"`
+	DeletedBy string    `json:"deleted_by"`
+}
+
+// SearchResult is a car matching a full-text search, with the matched
+// terms of each field wrapped in <em>.
+type SearchResult struct {
+	Car        Car               `json:"car"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type RangeBucket struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count int     `json:"count"`
+}
+
+// Facets are the counts per value and range of the matching cars.
+type Facets struct {
+	Total    int           `json:"total"`
+	Make     []FacetCount  `json:"make"`
+	Category []FacetCount  `json:"category"`
+	Color    []FacetCount  `json:"color"`
+	Year     []FacetCount  `json:"year"`
+	Price    []RangeBucket `json:"price"`
+	Mileage  []RangeBucket `json:"mileage"`
+}
+
+type FieldStats struct {
+	Sum         float64            `json:"sum"`
+	Min         float64            `json:"min"`
+	Max         float64            `json:"max"`
+	Mean        float64            `json:"mean"`
+	Median      float64            `json:"median"`
+	Percentiles map[string]float64 `json:"percentiles"`
+}
+
+// StatsGroup holds the aggregates of the cars sharing Key.
+type StatsGroup struct {
+	Key     map[string]string     `json:"key"`
+	Count   int                   `json:"count"`
+	Metrics map[string]FieldStats `json:"metrics"`
+}
+
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// CarVersion is one entry of a car's history.
+type CarVersion struct {
+	Version int                    `json:"version"`
+	Action  string                 `json:"action"`
+	At      time.Time              `json:"at"`
+	Car     Car                    `json:"car"`
+	Changes map[string]FieldChange `json:"changes"`
+	// Actor and Reason are set on status transitions.
+	Actor  string `json:"actor,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Event is a car lifecycle event: car.created, car.updated, car.deleted or
+// car.restored. A "reset" event with no car means events were missed.
+type Event struct {
+	Id   uint64    `json:"id"`
+	Type string    `json:"type"`
+	At   time.Time `json:"at"`
+	Car  Car       `json:"car"`
+}
+
+type WebhookSubscription struct {
+	Id         string    `json:"id,omitempty"`
+	URL        string    `json:"url"`
+	Events     []string  `json:"events"`
+	Secret     string    `json:"secret,omitempty"`
+	Dealership string    `json:"dealership,omitempty"`
+	CreatedAt  time.Time `json:"created_at,omitempty"`
+}
+
+type DeliveryAttempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type WebhookDelivery struct {
+	Id             string            `json:"id"`
+	SubscriptionId string            `json:"subscription_id"`
+	Event          Event             `json:"event"`
+	Status         string            `json:"status"`
+	Attempts       []DeliveryAttempt `json:"attempts"`
+}
+
+// ListOptions filters and sorts cars like the GET /cars query parameters.
+// Zero values are left out. Mileage bounds are in Units.
+type ListOptions struct {
+	Units      string
+	Make       string
+	Model      string
+	Category   string
+	Color      string
+	Status     []string
+	MinYear    int
+	MaxYear    int
+	MinPrice   float64
+	MaxPrice   float64
+	MinMileage float64
+	MaxMileage float64
+	// Sort is a field name, prefixed with "-" for descending.
+	Sort string
+}
+
+func (o *ListOptions) values() url.Values {
+	v := url.Values{}
+	if o == nil {
+		return v
+	}
+	set := func(key, val string) {
+		if val != "" {
+			v.Set(key, val)
+		}
+	}
+	setInt := func(key string, val int) {
+		if val != 0 {
+			v.Set(key, strconv.Itoa(val))
+		}
+	}
+	setFloat := func(key string, val float64) {
+		if val != 0 {
+			v.Set(key, formatFloat(val))
+		}
+	}
+
+	set("units", o.Units)
+	set("make", o.Make)
+	set("model", o.Model)
+	set("category", o.Category)
+	set("color", o.Color)
+	set("status", strings.Join(o.Status, ","))
+	setInt("min_year", o.MinYear)
+	setInt("max_year", o.MaxYear)
+	setFloat("min_price", o.MinPrice)
+	setFloat("max_price", o.MaxPrice)
+	setFloat("min_mileage", o.MinMileage)
+	setFloat("max_mileage", o.MaxMileage)
+	set("sort", o.Sort)
+	return v
+}
+
+// GetOptions selects the units of a single car, and with AsOf a past
+// version of it.
+type GetOptions struct {
+	Units string
+	AsOf  time.Time
+}
+
+// FacetOptions filters the cars counted in the facets and sets the width
+// of the price and mileage buckets.
+type FacetOptions struct {
+	ListOptions
+	PriceInterval   float64
+	MileageInterval float64
+}
+
+// StatsOptions filters and groups the cars aggregated by CarStats.
+type StatsOptions struct {
+	ListOptions
+	GroupBy     []string
+	Fields      []string
+	Percentiles []float64
+}
+
+// EventOptions filters the event stream and resumes it after LastEventId.
+type EventOptions struct {
+	Make        string
+	Category    string
+	LastEventId uint64
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func joinFloats(fs []float64) string {
+	s := make([]string, len(fs))
+	for i, f := range fs {
+		s[i] = formatFloat(f)
+	}
+	return strings.Join(s, ",")
+}
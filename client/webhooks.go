@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"net/url"
+)
+
+func webhookPath(id string) string {
+	return "/webhooks/" + url.PathEscape(id)
+}
+
+// ListWebhooks lists the webhook subscriptions. Secrets are not returned.
+func (c *Client) ListWebhooks(ctx context.Context) ([]WebhookSubscription, error) {
+	var subs []WebhookSubscription
+	err := c.do(ctx, "GET", "/webhooks", nil, nil, &subs)
+	return subs, err
+}
+
+// GetWebhook gets a webhook subscription by id.
+func (c *Client) GetWebhook(ctx context.Context, id string) (WebhookSubscription, error) {
+	var s WebhookSubscription
+	err := c.do(ctx, "GET", webhookPath(id), nil, nil, &s)
+	return s, err
+}
+
+// CreateWebhook subscribes s.URL to s.Events, signed with s.Secret.
+func (c *Client) CreateWebhook(ctx context.Context, s WebhookSubscription) (WebhookSubscription, error) {
+	var out WebhookSubscription
+	err := c.do(ctx, "POST", "/webhooks", nil, s, &out)
+	return out, err
+}
+
+// DeleteWebhook deletes a subscription with its deliveries.
+func (c *Client) DeleteWebhook(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", webhookPath(id), nil, nil, nil)
+}
+
+// WebhookDeliveries lists the recent deliveries of a subscription.
+func (c *Client) WebhookDeliveries(ctx context.Context, id string) ([]WebhookDelivery, error) {
+	var d []WebhookDelivery
+	err := c.do(ctx, "GET", webhookPath(id)+"/deliveries", nil, nil, &d)
+	return d, err
+}
+
+// WebhookDeadLetters lists the deliveries of a subscription that failed
+// every retry.
+func (c *Client) WebhookDeadLetters(ctx context.Context, id string) ([]WebhookDelivery, error) {
+	var d []WebhookDelivery
+	err := c.do(ctx, "GET", webhookPath(id)+"/dead-letters", nil, nil, &d)
+	return d, err
+}
+
+// RedeliverWebhook sends a dead letter again.
+func (c *Client) RedeliverWebhook(ctx context.Context, id, deliveryId string) (WebhookDelivery, error) {
+	var d WebhookDelivery
+	err := c.do(ctx, "POST", webhookPath(id)+"/dead-letters/"+url.PathEscape(deliveryId)+":redeliver", nil, nil, &d)
+	return d, err
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"example/cars/client"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func clientServer(t *testing.T) (*carHandler, *httptest.Server) {
+	h := &carHandler{ stream: newEventStream(10, 0) }
+	mux := http.NewServeMux()
+	mux.Handle("/cars", h)
+	mux.Handle("/cars/", h)
+	mux.Handle("/cars:batch", h)
+	mux.Handle("/graphql", newGraphQLHandler(h, false))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return h, srv
+}
+
+var clientCar = client.Car{ Id: "clicar01", Make: "Skoda", Model: "Octavia", Package: "RS", Color: "Green", Year: 2022, Category: "Wagon", Mileage: 100, Price: 3500000 }
+
+func TestClient_WhenManagingCars(t *testing.T){
+	_, srv := clientServer(t)
+	addTestUser("alice")
+	c := client.New(srv.URL, client.WithBasicAuth("alice", "secret"))
+	ctx := context.Background()
+
+	_, err := c.CreateCar(ctx, clientCar)
+	assert.Equal(t, err, nil)
+
+	car, err := c.GetCar(ctx, "clicar01", &client.GetOptions{ Units: "km" })
+	assert.Equal(t, err, nil)
+	assert.Equal(t, car.Mileage, 160.93)
+
+	updated := clientCar
+	updated.Color = "Black"
+	_, err = c.UpdateCar(ctx, updated)
+	assert.Equal(t, err, nil)
+
+	cars, err := c.ListCars(ctx, &client.ListOptions{ Make: "Skoda", Color: "Black" })
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(cars), 1)
+
+	versions, err := c.CarHistory(ctx, "clicar01")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(versions), 2)
+
+	assert.Equal(t, c.DeleteCar(ctx, "clicar01"), nil)
+	trashed, err := c.ListDeletedCars(ctx, &client.ListOptions{ Make: "Skoda" })
+	assert.Equal(t, err, nil)
+	assert.Equal(t, trashed[0].DeletedBy, "alice")
+
+	_, err = c.RestoreCar(ctx, "clicar01")
+	assert.Equal(t, err, nil)
+}
+
+func TestClient_WhenServerReturnsErrors(t *testing.T){
+	_, srv := clientServer(t)
+	c := client.New(srv.URL)
+	ctx := context.Background()
+
+	_, err := c.GetCar(ctx, "clinotfound", nil)
+	assert.Equal(t, client.IsNotFound(err), true)
+
+	_, err = c.CreateCar(ctx, client.Car{ Id: "clicar02", Make: "Skoda" })
+	assert.Equal(t, client.IsValidation(err), true)
+	assert.Equal(t, err.(*client.Error).Message, "model field empty")
+
+	car := clientCar
+	car.Id = "clicar03"
+	c.CreateCar(ctx, car)
+	c.DeleteCar(ctx, "clicar03")
+	c.CreateCar(ctx, car)
+	_, err = c.RestoreCar(ctx, "clicar03")
+	assert.Equal(t, client.IsConflict(err), true)
+}
+
+func TestClient_WhenRetryingIdempotentCalls(t *testing.T){
+	h, _ := clientServer(t)
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) % 2 == 1 {
+			respondWithError(w, http.StatusServiceUnavailable, "try again")
+			return
+		}
+		h.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	c := client.New(srv.URL, client.WithRetries(1, time.Millisecond))
+	ctx := context.Background()
+
+	_, err := c.ListCars(ctx, nil)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(2))
+
+	car := clientCar
+	car.Id = "clicar04"
+	_, err = c.CreateCar(ctx, car)
+	assert.Equal(t, err.(*client.Error).StatusCode, http.StatusServiceUnavailable)
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(3))
+}
+
+func TestClient_WhenStreamingEvents(t *testing.T){
+	h, srv := clientServer(t)
+	h.stream.publish(carEvent{ Id: 1, Type: eventCreated, Car: Car{ Id: "a", Make: "Ford" } })
+	h.stream.publish(carEvent{ Id: 2, Type: eventCreated, Car: Car{ Id: "b", Make: "Seat" } })
+	c := client.New(srv.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var got client.Event
+	err := c.Events(ctx, &client.EventOptions{ Make: "seat" }, func(e client.Event) error {
+		got = e
+		cancel()
+		return nil
+	})
+
+	assert.Equal(t, err, context.Canceled)
+	assert.Equal(t, got.Car.Id, "b")
+}
+
+func TestClient_WhenRunningGraphQL(t *testing.T){
+	_, srv := clientServer(t)
+	c := client.New(srv.URL)
+	car := clientCar
+	car.Id = "clicar05"
+	car.Mileage = 50000
+	c.CreateCar(context.Background(), car)
+
+	var out struct{ Car client.Car `json:"car"` }
+	err := c.GraphQL(context.Background(), `query($id: ID!) { car(id: $id) { id make } }`, map[string]interface{}{ "id": "clicar05" }, &out)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, out.Car.Make, "Skoda")
+
+	err = c.GraphQL(context.Background(), `{ car(id: "clinotfound") { id } }`, nil, nil)
+	assert.Equal(t, err.Error(), "graphql: id not found")
+}
+
+func TestClient_WhenBatchIsRolledBack(t *testing.T){
+	_, srv := clientServer(t)
+	c := client.New(srv.URL)
+	car := clientCar
+	car.Id = "clicar06"
+	car.Mileage = 60000
+
+	out, err := c.Batch(context.Background(), client.BatchAtomic, []client.BatchOperation{
+		{ Op: client.BatchCreate, Car: car },
+		{ Op: client.BatchDelete, Id: "clinotfound" },
+	})
+
+	assert.Equal(t, client.IsNotFound(err), true)
+	assert.Equal(t, out.Committed, false)
+	assert.Equal(t, out.Results[0].Status, http.StatusFailedDependency)
+	assert.Equal(t, err.(*client.Error).Message, "id not found")
+}
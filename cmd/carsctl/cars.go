@@ -0,0 +1,438 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"example/cars/client"
+)
+
+func addFilterFlags(c *command, o *client.ListOptions) {
+	c.stringFlag(&o.Units, "units", "", "", "odometer units of the output and mileage filters: mi or km")
+	c.stringFlag(&o.Make, "make", "", "", "filter by make")
+	c.stringFlag(&o.Model, "model", "", "", "filter by model")
+	c.stringFlag(&o.Category, "category", "", "", "filter by category")
+	c.stringFlag(&o.Color, "color", "", "", "filter by color")
+	c.stringsFlag(&o.Status, "status", "filter by status: incoming, in_transit, available, reserved, sold")
+	c.intFlag(&o.MinYear, "min-year", 0, "minimum year")
+	c.intFlag(&o.MaxYear, "max-year", 0, "maximum year")
+	c.floatFlag(&o.MinPrice, "min-price", 0, "minimum price")
+	c.floatFlag(&o.MaxPrice, "max-price", 0, "maximum price")
+	c.floatFlag(&o.MinMileage, "min-mileage", 0, "minimum mileage, in units")
+	c.floatFlag(&o.MaxMileage, "max-mileage", 0, "maximum mileage, in units")
+	c.stringFlag(&o.Sort, "sort", "", "", "sort field, prefix with - for descending: id, make, model, year, mileage, price")
+
+	c.completeFlag("units", "mi", "km")
+	c.completeFlag("status", statuses...)
+	c.completeFlag("sort", "id", "make", "model", "year", "mileage", "price", "-id", "-make", "-model", "-year", "-mileage", "-price")
+}
+
+// addCarFlags binds the fields of a car to flags.
+func addCarFlags(c *command, car *client.Car) {
+	c.stringFlag(&car.Id, "id", "", "", "car id")
+	c.stringFlag(&car.Make, "make", "", "", "make")
+	c.stringFlag(&car.Model, "model", "", "", "model")
+	c.stringFlag(&car.Package, "package", "", "", "package")
+	c.stringFlag(&car.Color, "color", "", "", "color")
+	c.intFlag(&car.Year, "year", 0, "year")
+	c.stringFlag(&car.Category, "category", "", "", "category")
+	c.floatFlag(&car.Mileage, "mileage", 0, "mileage, in mileage-unit")
+	c.floatFlag(&car.Price, "price", 0, "price in cents")
+	c.stringFlag(&car.MileageUnit, "mileage-unit", "", "", "odometer units of mileage: mi or km")
+
+	c.completeFlag("mileage-unit", "mi", "km")
+}
+
+// completeCarIds completes the ids of the cars on the server.
+func completeCarIds(g *globalFlags) func(*command, []string, string) []string {
+	return func(c *command, args []string, toComplete string) []string {
+		p, err := g.resolve()
+		if err != nil {
+			return nil
+		}
+		cars, err := p.client().ListCars(c.context(), nil)
+		if err != nil {
+			return nil
+		}
+		ids := []string{}
+		for _, car := range cars {
+			ids = append(ids, fmt.Sprintf("%s\t%s %s %d", car.Id, car.Make, car.Model, car.Year))
+		}
+		return ids
+	}
+}
+
+func newListCmd(g *globalFlags) *command {
+	var o client.ListOptions
+	cmd := newCommand("list", "List cars")
+	cmd.args = noArgs
+	cmd.run = func(c *command, args []string) error {
+		p, err := g.resolve()
+		if err != nil {
+			return err
+		}
+		cars, err := p.client().ListCars(c.context(), &o)
+		if err != nil {
+			return err
+		}
+		return writeCars(c.stdout(), p.Output, cars)
+	}
+	addFilterFlags(cmd, &o)
+	return cmd
+}
+
+func newGetCmd(g *globalFlags) *command {
+	var units, asOf string
+	cmd := newCommand("get ID", "Get a car")
+	cmd.args = exactArgs(1)
+	cmd.completeArgs = completeCarIds(g)
+	cmd.run = func(c *command, args []string) error {
+		p, err := g.resolve()
+		if err != nil {
+			return err
+		}
+		opts := &client.GetOptions{Units: units}
+		if asOf != "" {
+			if opts.AsOf, err = time.Parse(time.RFC3339, asOf); err != nil {
+				return fail("as-of must be an RFC 3339 timestamp")
+			}
+		}
+		car, err := p.client().GetCar(c.context(), args[0], opts)
+		if err != nil {
+			return err
+		}
+		return writeCars(c.stdout(), p.Output, []client.Car{car})
+	}
+	cmd.stringFlag(&units, "units", "", "", "odometer units of the output: mi or km")
+	cmd.stringFlag(&asOf, "as-of", "", "", "RFC 3339 timestamp; shows the car as it was at that instant")
+	cmd.completeFlag("units", "mi", "km")
+	return cmd
+}
+
+// readCarFile reads a single car from a JSON file, or stdin for "-".
+func readCarFile(path string) (client.Car, error) {
+	f := os.Stdin
+	if path != "-" {
+		var err error
+		if f, err = os.Open(path); err != nil {
+			return client.Car{}, err
+		}
+		defer f.Close()
+	}
+	cars, err := readCars(f, formatJSON)
+	if err != nil {
+		return client.Car{}, err
+	}
+	if len(cars) != 1 {
+		return client.Car{}, fail("%s must hold exactly one car", path)
+	}
+	return cars[0], nil
+}
+
+func newCreateCmd(g *globalFlags) *command {
+	var car client.Car
+	var file string
+	cmd := newCommand("create", "Create a car from flags or a JSON file")
+	cmd.args = noArgs
+	cmd.run = func(c *command, args []string) error {
+		p, err := g.resolve()
+		if err != nil {
+			return err
+		}
+		if file != "" {
+			if car, err = readCarFile(file); err != nil {
+				return err
+			}
+		}
+		created, err := p.client().CreateCar(c.context(), car)
+		if err != nil {
+			return err
+		}
+		return writeCars(c.stdout(), p.Output, []client.Car{created})
+	}
+	addCarFlags(cmd, &car)
+	cmd.stringFlag(&file, "file", "f", "", "JSON file with the car, - for stdin")
+	return cmd
+}
+
+func newUpdateCmd(g *globalFlags) *command {
+	var changes client.Car
+	var file string
+	cmd := newCommand("update ID", "Update the given fields of a car, or replace it with a JSON file")
+	cmd.args = exactArgs(1)
+	cmd.completeArgs = completeCarIds(g)
+	cmd.run = func(c *command, args []string) error {
+		p, err := g.resolve()
+		if err != nil {
+			return err
+		}
+		cl := p.client()
+
+		var car client.Car
+		if file != "" {
+			if car, err = readCarFile(file); err != nil {
+				return err
+			}
+		} else {
+			if car, err = cl.GetCar(c.context(), args[0], nil); err != nil {
+				return err
+			}
+			mergeChanged(c, &car, changes)
+		}
+		car.Id = args[0]
+
+		updated, err := cl.UpdateCar(c.context(), car)
+		if err != nil {
+			return err
+		}
+		return writeCars(c.stdout(), p.Output, []client.Car{updated})
+	}
+	addCarFlags(cmd, &changes)
+	cmd.hiddenFlags["id"] = true
+	cmd.stringFlag(&file, "file", "f", "", "JSON file with the whole car, - for stdin")
+	return cmd
+}
+
+// mergeChanged copies the fields whose flags were set onto car.
+func mergeChanged(c *command, car *client.Car, changes client.Car) {
+	set := func(name string, apply func()) {
+		if c.changed(name) {
+			apply()
+		}
+	}
+	set("make", func() { car.Make = changes.Make })
+	set("model", func() { car.Model = changes.Model })
+	set("package", func() { car.Package = changes.Package })
+	set("color", func() { car.Color = changes.Color })
+	set("year", func() { car.Year = changes.Year })
+	set("category", func() { car.Category = changes.Category })
+	set("mileage", func() { car.Mileage = changes.Mileage })
+	set("price", func() { car.Price = changes.Price })
+	set("mileage-unit", func() { car.MileageUnit = changes.MileageUnit })
+}
+
+func newDeleteCmd(g *globalFlags) *command {
+	cmd := newCommand("delete ID...", "Move cars to the trash")
+	cmd.args = minimumArgs(1)
+	cmd.completeArgs = completeCarIds(g)
+	cmd.run = func(c *command, args []string) error {
+		p, err := g.resolve()
+		if err != nil {
+			return err
+		}
+		cl := p.client()
+		for _, id := range args {
+			if err := cl.DeleteCar(c.context(), id); err != nil {
+				return fail("deleting %s: %v", id, err)
+			}
+			fmt.Fprintf(c.stdout(), "deleted %s\n", id)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// statuses are the lifecycle statuses of a car.
+var statuses = []string{client.StatusIncoming, client.StatusInTransit, client.StatusAvailable, client.StatusReserved, client.StatusSold}
+
+// transitionStatuses are the statuses transition can move a car to. Cars
+// become reserved with reserve.
+var transitionStatuses = []string{client.StatusIncoming, client.StatusInTransit, client.StatusAvailable, client.StatusSold}
+
+func newTransitionCmd(g *globalFlags) *command {
+	var reason string
+	cmd := newCommand("transition ID STATUS", "Change the status of a car")
+	cmd.long = "Moves a car to STATUS. Incoming and in_transit cars become available, available cars can be sold, and reserved or sold cars can go back to available. Use reserve to hold a car."
+	cmd.args = exactArgs(2)
+	cmd.completeArgs = func(c *command, args []string, toComplete string) []string {
+		if len(args) == 1 {
+			return transitionStatuses
+		}
+		return completeCarIds(g)(c, args, toComplete)
+	}
+	cmd.run = func(c *command, args []string) error {
+		p, err := g.resolve()
+		if err != nil {
+			return err
+		}
+		car, err := p.client().TransitionCar(c.context(), args[0], args[1], reason)
+		if err != nil {
+			return err
+		}
+		return writeCars(c.stdout(), p.Output, []client.Car{car})
+	}
+	cmd.stringFlag(&reason, "reason", "", "", "why the status changes, recorded in the history")
+	return cmd
+}
+
+func newReserveCmd(g *globalFlags) *command {
+	var customer string
+	var hours int
+	cmd := newCommand("reserve ID", "Hold a car for a customer")
+	cmd.long = "Holds an available car for a customer for 48 hours, or --hours up to a week. Until the hold expires or is released only you can update, delete or transition the car."
+	cmd.args = exactArgs(1)
+	cmd.completeArgs = completeCarIds(g)
+	cmd.run = func(c *command, args []string) error {
+		p, err := g.resolve()
+		if err != nil {
+			return err
+		}
+		r, err := p.client().ReserveCar(c.context(), args[0], customer, hours)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout(), "reserved %s for %s until %s (reservation %s)\n", r.CarId, r.Customer, r.ExpiresAt.Format(time.RFC3339), r.Id)
+		return nil
+	}
+	cmd.stringFlag(&customer, "customer", "", "", "customer reference")
+	cmd.intFlag(&hours, "hours", 0, "hours to hold the car, 48 by default")
+	cmd.required = []string{"customer"}
+	return cmd
+}
+
+func newReleaseCmd(g *globalFlags) *command {
+	cmd := newCommand("release ID", "Release your hold on a car")
+	cmd.args = exactArgs(1)
+	cmd.completeArgs = completeCarIds(g)
+	cmd.run = func(c *command, args []string) error {
+		p, err := g.resolve()
+		if err != nil {
+			return err
+		}
+		cl := p.client()
+		rs, err := cl.CarReservations(c.context(), args[0])
+		if err != nil {
+			return err
+		}
+		if len(rs) == 0 {
+			return fail("%s is not held", args[0])
+		}
+		if _, err := cl.ReleaseReservation(c.context(), args[0], rs[0].Id); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout(), "released %s\n", args[0])
+		return nil
+	}
+	return cmd
+}
+
+// formatOf picks json or csv from the file extension.
+func formatOf(path, fallback string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return formatJSON
+	case ".csv":
+		return formatCSV
+	}
+	return fallback
+}
+
+func newImportCmd(g *globalFlags) *command {
+	var upsert, allowDuplicates bool
+	var format string
+	cmd := newCommand("import FILE", "Create cars from a CSV or JSON file, - for stdin")
+	cmd.long = "Creates every car of the file, continuing past failures. With --upsert, cars whose id already exists are updated instead. Cars that look like listed ones fail unless --allow-duplicates is given."
+	cmd.args = exactArgs(1)
+	cmd.run = func(c *command, args []string) error {
+		p, err := g.resolve()
+		if err != nil {
+			return err
+		}
+
+		f := os.Stdin
+		if args[0] != "-" {
+			if f, err = os.Open(args[0]); err != nil {
+				return err
+			}
+			defer f.Close()
+		}
+		if format == "" {
+			format = formatOf(args[0], formatCSV)
+		}
+		cars, err := readCars(f, format)
+		if err != nil {
+			return err
+		}
+
+		created, updated, failed := importCars(c.context(), p.client(), cars, upsert, allowDuplicates, func(car client.Car, err error) {
+			fmt.Fprintf(c.stderr(), "%s: %v\n", car.Id, err)
+		})
+		fmt.Fprintf(c.stdout(), "created %d, updated %d, failed %d\n", created, updated, failed)
+		if failed > 0 {
+			return fail("%d cars failed to import", failed)
+		}
+		return nil
+	}
+	cmd.boolFlag(&upsert, "upsert", false, "update cars whose id already exists")
+	cmd.boolFlag(&allowDuplicates, "allow-duplicates", false, "create cars that look like listed ones")
+	cmd.stringFlag(&format, "format", "", "", "file format, csv or json; guessed from the extension")
+	cmd.completeFlag("format", formatCSV, formatJSON)
+	return cmd
+}
+
+func importCars(ctx context.Context, c *client.Client, cars []client.Car, upsert, allowDuplicates bool, report func(client.Car, error)) (created, updated, failed int) {
+	create := c.CreateCar
+	if allowDuplicates {
+		create = c.CreateCarAllowingDuplicate
+	}
+	for _, car := range cars {
+		_, err := create(ctx, car)
+		if err == nil {
+			created++
+			continue
+		}
+		if e, ok := err.(*client.Error); ok && upsert && e.Message == "id already exists" {
+			if _, err = c.UpdateCar(ctx, car); err == nil {
+				updated++
+				continue
+			}
+		}
+		report(car, err)
+		failed++
+	}
+	return created, updated, failed
+}
+
+func newExportCmd(g *globalFlags) *command {
+	var o client.ListOptions
+	var file string
+	cmd := newCommand("export", "Export cars as CSV or JSON")
+	cmd.long = "Writes the cars matching the filters to stdout or --file. The format is -o, else guessed from the file extension, else csv."
+	cmd.args = noArgs
+	cmd.run = func(c *command, args []string) error {
+		p, err := g.resolve()
+		if err != nil {
+			return err
+		}
+		format := g.output
+		if format == "" {
+			format = formatOf(file, formatCSV)
+		}
+		if format == formatTable {
+			return fail("export format must be csv or json")
+		}
+
+		cars, err := p.client().ListCars(c.context(), &o)
+		if err != nil {
+			return err
+		}
+
+		w := c.stdout()
+		if file != "" {
+			f, err := os.Create(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		return writeCars(w, format, cars)
+	}
+	addFilterFlags(cmd, &o)
+	cmd.stringFlag(&file, "file", "f", "", "file to write instead of stdout")
+	return cmd
+}
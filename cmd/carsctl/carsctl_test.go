@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"example/cars/client"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func run(args ...string) (string, error) {
+	var out bytes.Buffer
+	err := newRootCmd().execute(context.Background(), args, &out, &out)
+	return out.String(), err
+}
+
+func TestReadCars_WhenCsvColumnsAreReordered(t *testing.T) {
+	in := "price,id,make,year,mileage\n1999900,JHk290Xj,Ford,2010,120123\n"
+
+	cars, err := readCars(strings.NewReader(in), formatCSV)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, cars, []client.Car{{Id: "JHk290Xj", Make: "Ford", Year: 2010, Mileage: 120123, Price: 1999900}})
+}
+
+func TestReadCars_WhenCsvNumberIsInvalid(t *testing.T) {
+	_, err := readCars(strings.NewReader("id,year\na,new\n"), formatCSV)
+
+	assert.Equal(t, err.Error(), "line 2: year must be a number")
+}
+
+func TestWriteCars_WhenRoundTrippingCsv(t *testing.T) {
+	cars := []client.Car{{Id: "fWl37la", Make: "Toyota", Model: "Camry", Package: "SE", Color: "White", Year: 2019, Category: "Sedan", Mileage: 3999.5, Price: 2899000, MileageUnit: "mi"}}
+
+	var buf bytes.Buffer
+	assert.Equal(t, writeCars(&buf, formatCSV, cars), nil)
+	got, err := readCars(&buf, formatCSV)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, got, cars)
+}
+
+func TestResolve_WhenFlagsOverrideProfile(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.json")
+	_, err := run("--config", config, "--server", "http://staging:8080", "--user", "alice", "profile", "set", "staging")
+	assert.Equal(t, err, nil)
+	_, err = run("--config", config, "--server", "http://prod:8080", "-o", "json", "profile", "set", "prod")
+	assert.Equal(t, err, nil)
+
+	g := &globalFlags{config: config, profile: "prod", user: "bob"}
+	p, err := g.resolve()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, p, profile{Server: "http://prod:8080", User: "bob", Output: "json"})
+
+	g = &globalFlags{config: config}
+	p, _ = g.resolve()
+	assert.Equal(t, p.Server, "http://staging:8080")
+
+	_, err = run("--config", config, "--profile", "dev", "list")
+	assert.Equal(t, strings.Contains(err.Error(), `profile "dev" not found`), true)
+}
+
+func TestImportCars_WhenUpserting(t *testing.T) {
+	methods := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		w.Header().Set("content-type", "application/json")
+		if r.Method == "POST" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"id already exists"}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	created, updated, failed := importCars(context.Background(), client.New(srv.URL), []client.Car{{Id: "a"}}, true, false, func(client.Car, error) {})
+	assert.Equal(t, []int{created, updated, failed}, []int{0, 1, 0})
+	assert.Equal(t, methods, []string{"POST", "PUT"})
+
+	_, _, failed = importCars(context.Background(), client.New(srv.URL), []client.Car{{Id: "a"}}, false, false, func(client.Car, error) {})
+	assert.Equal(t, failed, 1)
+}
+
+func TestImportCars_WhenDuplicate(t *testing.T) {
+	queries := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		w.Header().Set("content-type", "application/json")
+		if r.URL.Query().Get("allow_duplicate") != "true" {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error":"possible duplicate of b","duplicates":[{"car":{"id":"b"},"reason":"same vin"}]}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	var reported error
+	_, _, failed := importCars(context.Background(), client.New(srv.URL), []client.Car{{Id: "a"}}, false, false, func(_ client.Car, err error) { reported = err })
+	assert.Equal(t, failed, 1)
+	assert.Equal(t, client.Duplicates(reported)[0].Car.Id, "b")
+
+	created, _, _ := importCars(context.Background(), client.New(srv.URL), []client.Car{{Id: "a"}}, false, true, func(client.Car, error) {})
+	assert.Equal(t, created, 1)
+	assert.Equal(t, queries, []string{"", "allow_duplicate=true"})
+}
+
+func TestTransitionCmd_WhenGivenReason(t *testing.T) {
+	var path, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		b := new(bytes.Buffer)
+		b.ReadFrom(r.Body)
+		body = b.String()
+		w.Header().Set("content-type", "application/json")
+		w.Write([]byte(`{"id":"a","status":"sold"}`))
+	}))
+	defer srv.Close()
+
+	out, err := run("--config", filepath.Join(t.TempDir(), "config.json"), "--server", srv.URL, "-o", "csv", "transition", "a", "sold", "--reason", "paid in full")
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, path, "/cars/a:transition")
+	assert.Equal(t, body, `{"reason":"paid in full","status":"sold"}`)
+	assert.Equal(t, strings.HasSuffix(strings.TrimSpace(out), "sold,"), true)
+}
+
+func TestExecute_WhenParsingFlagsAndArgs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	_, err := run("--config", path, "reserve", "a")
+	assert.Equal(t, err.Error(), `required flag(s) "customer" not set`)
+
+	_, err = run("--config", path, "get")
+	assert.Equal(t, err.Error(), "accepts 1 arg(s), received 0")
+
+	_, err = run("--config", path, "sell", "a")
+	assert.Equal(t, err.Error(), `unknown command "sell" for "carsctl"`)
+
+	_, err = run("--config", path, "list", "--colour", "red")
+	assert.Equal(t, err.Error(), "flag provided but not defined: -colour")
+
+	out, err := run("profile", "--help")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, strings.Contains(out, "Available Commands:\n  list  "), true)
+
+	out, err = run("help", "list")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, strings.Contains(out, "--status strings"), true)
+	assert.Equal(t, strings.Contains(out, "Global Flags:\n"), true)
+
+	// Global flags and their shorthands are accepted after the arguments.
+	_, err = run("profile", "set", "prod", "-o", "csv", "--server", "http://prod:8080", "--config", path)
+	assert.Equal(t, err, nil)
+	cfg, _ := loadConfig(path)
+	assert.Equal(t, cfg, config{Current: "prod", Profiles: map[string]profile{"prod": {Server: "http://prod:8080", Output: "csv"}}})
+}
+
+func TestComplete_WhenCompletingCommandsFlagsAndArgs(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.json")
+	run("--config", config, "--server", "http://prod:8080", "profile", "set", "prod")
+	run("--config", config, "--server", "http://staging:8080", "profile", "set", "staging")
+
+	out, _ := run("__complete", "pro")
+	assert.Equal(t, out, "profile\tManage the connection profiles of each environment\n")
+
+	out, _ = run("__complete", "--config", config, "profile", "use", "")
+	assert.Equal(t, out, "prod\nstaging\n")
+
+	out, _ = run("__complete", "--config", config, "list", "-p", "s")
+	assert.Equal(t, out, "staging\n")
+
+	out, _ = run("__complete", "list", "--sort", "-m")
+	assert.Equal(t, out, "-make\n-model\n-mileage\n")
+
+	out, _ = run("__complete", "transition", "a", "")
+	assert.Equal(t, out, "incoming\nin_transit\navailable\nsold\n")
+
+	out, _ = run("__complete", "import", "--ups")
+	assert.Equal(t, out, "--upsert\tupdate cars whose id already exists\n")
+
+	out, err := run("completion", "bash")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, strings.Contains(out, "complete -o default -F _carsctl carsctl"), true)
+}
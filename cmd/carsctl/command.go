@@ -0,0 +1,385 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// command is a carsctl command. Commands with children dispatch to them;
+// the others run with their flags parsed from anywhere on the command line.
+type command struct {
+	// use is the name of the command followed by its arguments, e.g.
+	// "get ID".
+	use     string
+	summary string
+	long    string
+	// args checks the positional arguments, any are accepted when nil.
+	args func(args []string) error
+	run  func(c *command, args []string) error
+	// completeArgs lists the candidates for the next positional argument,
+	// optionally followed by a tab and a description.
+	completeArgs func(c *command, args []string, toComplete string) []string
+	hidden       bool
+	// rawArgs passes all arguments to run, flags included.
+	rawArgs bool
+
+	parent   *command
+	children []*command
+
+	flags       *flag.FlagSet
+	shorthands  map[string]string
+	hiddenFlags map[string]bool
+	// globalFlags are the flags every command accepts.
+	globalFlags map[string]bool
+	// valueTypes names the values of the flags that are not booleans.
+	valueTypes map[string]string
+	required   []string
+	// completeFlags lists the candidate values of flags.
+	completeFlags map[string]func() []string
+
+	// Set on the root command by execute.
+	ctx         context.Context
+	out, errOut io.Writer
+}
+
+func newCommand(use, summary string) *command {
+	fs := flag.NewFlagSet(strings.Fields(use)[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+	return &command{
+		use:           use,
+		summary:       summary,
+		flags:         fs,
+		shorthands:    map[string]string{},
+		hiddenFlags:   map[string]bool{},
+		globalFlags:   map[string]bool{},
+		valueTypes:    map[string]string{},
+		completeFlags: map[string]func() []string{},
+	}
+}
+
+func (c *command) name() string {
+	return c.flags.Name()
+}
+
+func (c *command) path() string {
+	if c.parent == nil {
+		return c.name()
+	}
+	return c.parent.path() + " " + c.name()
+}
+
+func (c *command) root() *command {
+	for c.parent != nil {
+		c = c.parent
+	}
+	return c
+}
+
+func (c *command) context() context.Context { return c.root().ctx }
+func (c *command) stdout() io.Writer        { return c.root().out }
+func (c *command) stderr() io.Writer        { return c.root().errOut }
+
+func (c *command) add(children ...*command) {
+	for _, child := range children {
+		child.parent = c
+		c.children = append(c.children, child)
+	}
+}
+
+func (c *command) child(name string) *command {
+	for _, child := range c.children {
+		if child.name() == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// walk calls fn on c and all its descendants.
+func (c *command) walk(fn func(*command)) {
+	fn(c)
+	for _, child := range c.children {
+		child.walk(fn)
+	}
+}
+
+// The flag functions below define a flag with an optional one letter
+// shorthand, e.g. -o for --output.
+
+func (c *command) stringFlag(p *string, name, short, value, usage string) {
+	c.flags.StringVar(p, name, value, usage)
+	c.valueTypes[name] = "string"
+	c.shorthand(name, short)
+}
+
+func (c *command) intFlag(p *int, name string, value int, usage string) {
+	c.flags.IntVar(p, name, value, usage)
+	c.valueTypes[name] = "int"
+}
+
+func (c *command) floatFlag(p *float64, name string, value float64, usage string) {
+	c.flags.Float64Var(p, name, value, usage)
+	c.valueTypes[name] = "float"
+}
+
+func (c *command) boolFlag(p *bool, name string, value bool, usage string) {
+	c.flags.BoolVar(p, name, value, usage)
+}
+
+// stringsFlag defines a flag taking comma separated values, which may be
+// repeated.
+func (c *command) stringsFlag(p *[]string, name, usage string) {
+	c.flags.Var((*stringsValue)(p), name, usage)
+	c.valueTypes[name] = "strings"
+}
+
+func (c *command) shorthand(name, short string) {
+	if short != "" {
+		f := c.flags.Lookup(name)
+		c.flags.Var(f.Value, short, f.Usage)
+		c.shorthands[name] = short
+		c.valueTypes[short] = c.valueTypes[name]
+	}
+}
+
+// completeFlag completes the values of a flag with a fixed list.
+func (c *command) completeFlag(name string, values ...string) {
+	c.completeFlags[name] = func() []string { return values }
+}
+
+// longName returns the long name of the flag named name or its shorthand.
+func (c *command) longName(name string) string {
+	for long, short := range c.shorthands {
+		if short == name {
+			return long
+		}
+	}
+	return name
+}
+
+func (c *command) changed(name string) bool {
+	changed := false
+	c.flags.Visit(func(f *flag.Flag) {
+		changed = changed || f.Name == name || f.Name == c.shorthands[name]
+	})
+	return changed
+}
+
+type stringsValue []string
+
+func (v *stringsValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return strings.Join(*v, ",")
+}
+
+func (v *stringsValue) Set(s string) error {
+	*v = append(*v, strings.Split(s, ",")...)
+	return nil
+}
+
+func noArgs(args []string) error {
+	if len(args) > 0 {
+		return fail("unknown command %q", args[0])
+	}
+	return nil
+}
+
+func exactArgs(n int) func([]string) error {
+	return func(args []string) error {
+		if len(args) != n {
+			return fail("accepts %d arg(s), received %d", n, len(args))
+		}
+		return nil
+	}
+}
+
+func minimumArgs(n int) func([]string) error {
+	return func(args []string) error {
+		if len(args) < n {
+			return fail("requires at least %d arg(s), only received %d", n, len(args))
+		}
+		return nil
+	}
+}
+
+// execute runs the command named by args, printing errors to errOut.
+func (c *command) execute(ctx context.Context, args []string, out, errOut io.Writer) error {
+	c.ctx, c.out, c.errOut = ctx, out, errOut
+	err := c.dispatch(args)
+	if err != nil {
+		fmt.Fprintln(errOut, "Error:", err)
+	}
+	return err
+}
+
+func (c *command) dispatch(args []string) error {
+	if len(c.children) > 0 {
+		if err := c.flags.Parse(args); err != nil {
+			return c.parseError(err)
+		}
+		args = c.flags.Args()
+		if len(args) == 0 {
+			return c.help(c.stdout())
+		}
+		if args[0] == "help" {
+			return c.helpFor(args[1:])
+		}
+		child := c.child(args[0])
+		if child == nil {
+			return fail("unknown command %q for %q", args[0], c.path())
+		}
+		return child.dispatch(args[1:])
+	}
+
+	if c.rawArgs {
+		return c.run(c, args)
+	}
+	args, err := parseInterspersed(c.flags, args)
+	if err != nil {
+		return c.parseError(err)
+	}
+	for _, name := range c.required {
+		if !c.changed(name) {
+			return fail("required flag(s) %q not set", name)
+		}
+	}
+	if c.args != nil {
+		if err := c.args(args); err != nil {
+			return err
+		}
+	}
+	return c.run(c, args)
+}
+
+func (c *command) parseError(err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		return c.help(c.stdout())
+	}
+	return err
+}
+
+// parseInterspersed parses the flags of args wherever they are, and
+// returns the other arguments. Arguments after "--" are never flags.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// helpFor prints the help of the command named by path, e.g. "profile set".
+func (c *command) helpFor(path []string) error {
+	cmd := c
+	for _, name := range path {
+		if cmd = cmd.child(name); cmd == nil {
+			return fail("unknown help topic %q", strings.Join(path, " "))
+		}
+	}
+	return cmd.help(c.stdout())
+}
+
+func (c *command) help(w io.Writer) error {
+	if c.long != "" {
+		fmt.Fprintf(w, "%s\n\n", c.long)
+	} else if c.summary != "" {
+		fmt.Fprintf(w, "%s\n\n", c.summary)
+	}
+
+	fmt.Fprintln(w, "Usage:")
+	use := c.use
+	if c.parent != nil {
+		use = c.parent.path() + " " + use
+	}
+	if len(c.children) > 0 {
+		fmt.Fprintf(w, "  %s [command]\n", use)
+	} else {
+		fmt.Fprintf(w, "  %s [flags]\n", use)
+	}
+
+	if len(c.children) > 0 {
+		fmt.Fprintln(w, "\nAvailable Commands:")
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, child := range c.children {
+			if !child.hidden {
+				fmt.Fprintf(tw, "  %s\t%s\n", child.name(), child.summary)
+			}
+		}
+		tw.Flush()
+	}
+
+	var local, global []string
+	for _, name := range c.flagNames() {
+		if c.globalFlags[name] {
+			global = append(global, name)
+		} else {
+			local = append(local, name)
+		}
+	}
+	c.printFlags(w, "Flags", local)
+	c.printFlags(w, "Global Flags", global)
+
+	if len(c.children) > 0 {
+		fmt.Fprintf(w, "\nUse \"%s [command] --help\" for more information about a command.\n", c.path())
+	}
+	return nil
+}
+
+func (c *command) printFlags(w io.Writer, title string, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s:\n", title)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		f := c.flags.Lookup(name)
+		flagName := "    --" + name
+		if short := c.shorthands[name]; short != "" {
+			flagName = "-" + short + ", --" + name
+		}
+		if t := c.valueTypes[name]; t != "" {
+			flagName += " " + t
+		}
+		usage := f.Usage
+		if f.DefValue != "" && f.DefValue != "0" && f.DefValue != "false" {
+			usage += fmt.Sprintf(" (default %q)", f.DefValue)
+		}
+		fmt.Fprintf(tw, "  %s\t%s\n", flagName, usage)
+	}
+	tw.Flush()
+}
+
+// flagNames lists the visible flags of c by their long names, sorted.
+func (c *command) flagNames() []string {
+	shorts := map[string]bool{}
+	for _, short := range c.shorthands {
+		shorts[short] = true
+	}
+	names := []string{}
+	c.flags.VisitAll(func(f *flag.Flag) {
+		if !shorts[f.Name] && !c.hiddenFlags[f.Name] {
+			names = append(names, f.Name)
+		}
+	})
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Completion scripts call the hidden __complete command with the words
+// of the command line, the last being the word completed, and offer the
+// lines it prints. A tab separates a candidate from its description. The
+// scripts fall back to file names when there is no candidate.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for carsctl
+_carsctl() {
+	local IFS=$'\n'
+	COMPREPLY=($(carsctl __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null | cut -f1))
+}
+complete -o default -F _carsctl carsctl
+`,
+	"zsh": `#compdef carsctl
+_carsctl() {
+	local -a candidates described
+	local c
+	candidates=("${(@f)$(carsctl __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	candidates=(${candidates:#})
+	if (( ${#candidates} == 0 )); then
+		_files
+		return
+	fi
+	for c in $candidates; do
+		if [[ $c == *$'\t'* ]]; then
+			described+=("${${c%%$'\t'*}//:/\\:}:${c#*$'\t'}")
+		else
+			described+=("${c//:/\\:}")
+		fi
+	done
+	_describe carsctl described
+}
+compdef _carsctl carsctl
+`,
+	"fish": `function __carsctl_complete
+	set -l candidates (carsctl __complete (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)
+	if test (count $candidates) -eq 0
+		__fish_complete_path (commandline -ct)
+		return
+	end
+	printf '%s\n' $candidates
+end
+complete -c carsctl -f -a '(__carsctl_complete)'
+`,
+}
+
+func newCompletionCmd() *command {
+	cmd := newCommand("completion SHELL", "Print the shell completion script for bash, zsh or fish")
+	cmd.long = `Prints the completion script of SHELL, bash, zsh or fish. To load it in the current shell:
+
+	source <(carsctl completion bash)
+	source <(carsctl completion zsh)
+	carsctl completion fish | source`
+	cmd.args = exactArgs(1)
+	cmd.completeArgs = func(c *command, args []string, toComplete string) []string {
+		if len(args) > 0 {
+			return nil
+		}
+		return []string{"bash", "zsh", "fish"}
+	}
+	cmd.run = func(c *command, args []string) error {
+		script, ok := completionScripts[args[0]]
+		if !ok {
+			return fail("unsupported shell %q, use bash, zsh or fish", args[0])
+		}
+		_, err := fmt.Fprint(c.stdout(), script)
+		return err
+	}
+	return cmd
+}
+
+func newCompleteCmd() *command {
+	cmd := newCommand("__complete WORDS...", "List the completions of a command line")
+	cmd.hidden = true
+	cmd.rawArgs = true
+	cmd.run = func(c *command, args []string) error {
+		if len(args) == 0 {
+			args = []string{""}
+		}
+		for _, candidate := range c.root().complete(args) {
+			fmt.Fprintln(c.stdout(), candidate)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// complete lists the candidates for the last of words given the words
+// before it, which select the command and set its flags.
+func (c *command) complete(words []string) []string {
+	before, toComplete := words[:len(words)-1], words[len(words)-1]
+	cmd := c
+	positional := []string{}
+	flagArgs := []string{}
+	var values func() []string
+	dashes := false
+
+	for i := 0; i < len(before); i++ {
+		w := before[i]
+		switch {
+		case dashes || w == "-" || !strings.HasPrefix(w, "-"):
+			if len(cmd.children) > 0 && len(positional) == 0 {
+				if cmd = cmd.child(w); cmd == nil {
+					return nil
+				}
+				continue
+			}
+			positional = append(positional, w)
+		case w == "--":
+			dashes = true
+		default:
+			name := strings.TrimLeft(w, "-")
+			takesValue := !strings.Contains(name, "=") && cmd.valueTypes[name] != ""
+			if takesValue && i+1 == len(before) {
+				// toComplete is the value of this flag.
+				values = func() []string { return nil }
+				if fn := cmd.completeFlags[cmd.longName(name)]; fn != nil {
+					values = fn
+				}
+				continue
+			}
+			flagArgs = append(flagArgs, w)
+			if takesValue {
+				i++
+				flagArgs = append(flagArgs, before[i])
+			}
+		}
+	}
+	// Set the flags given so far, e.g. --config for completing profiles.
+	cmd.flags.Parse(flagArgs)
+
+	var candidates []string
+	switch {
+	case values != nil:
+		candidates = values()
+	case strings.HasPrefix(toComplete, "-") && !dashes:
+		for _, name := range cmd.flagNames() {
+			candidates = append(candidates, "--"+name+"\t"+cmd.flags.Lookup(name).Usage)
+		}
+	case len(cmd.children) > 0:
+		for _, child := range cmd.children {
+			if !child.hidden {
+				candidates = append(candidates, child.name()+"\t"+child.summary)
+			}
+		}
+	case cmd.completeArgs != nil:
+		candidates = cmd.completeArgs(cmd, positional, toComplete)
+	}
+
+	matching := []string{}
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, toComplete) {
+			matching = append(matching, candidate)
+		}
+	}
+	return matching
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"example/cars/client"
+)
+
+// profile holds the settings of one environment.
+type profile struct {
+	Server     string `json:"server"`
+	User       string `json:"user,omitempty"`
+	Password   string `json:"password,omitempty"`
+	Dealership string `json:"dealership,omitempty"`
+	Output     string `json:"output,omitempty"`
+}
+
+// config is the carsctl config file, by default
+// $XDG_CONFIG_HOME/carsctl/config.json.
+type config struct {
+	Current  string             `json:"current"`
+	Profiles map[string]profile `json:"profiles"`
+}
+
+const defaultServer = "http://localhost:8080"
+
+func defaultConfigPath() string {
+	if v := os.Getenv("CARSCTL_CONFIG"); v != "" {
+		return v
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "carsctl.json"
+	}
+	return filepath.Join(dir, "carsctl", "config.json")
+}
+
+// loadConfig reads the config file. A missing file is an empty config.
+func loadConfig(path string) (config, error) {
+	cfg := config{Profiles: map[string]profile{}}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return cfg, fail("reading %s: %v", path, err)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]profile{}
+	}
+	return cfg, nil
+}
+
+func (c config) save(path string) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+func (c config) names() []string {
+	names := []string{}
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolve merges the selected profile under the flags. The profile is
+// --profile, else $CARSCTL_PROFILE, else the current one.
+func (g *globalFlags) resolve() (profile, error) {
+	cfg, err := loadConfig(g.config)
+	if err != nil {
+		return profile{}, err
+	}
+
+	name := g.profile
+	if name == "" {
+		name = os.Getenv("CARSCTL_PROFILE")
+	}
+	if name == "" {
+		name = cfg.Current
+	}
+
+	var p profile
+	if name != "" {
+		var ok bool
+		if p, ok = cfg.Profiles[name]; !ok {
+			return profile{}, fail("profile %q not found in %s", name, g.config)
+		}
+	}
+
+	override := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	override(&p.Server, g.server)
+	override(&p.User, g.user)
+	override(&p.Password, g.password)
+	override(&p.Dealership, g.dealership)
+	override(&p.Output, g.output)
+
+	if p.Server == "" {
+		p.Server = defaultServer
+	}
+	if p.Output == "" {
+		p.Output = formatTable
+	}
+	if !validFormat(p.Output) {
+		return profile{}, fail("output must be one of table, json, csv")
+	}
+	return p, nil
+}
+
+func (p profile) client() *client.Client {
+	opts := []client.Option{}
+	if p.User != "" {
+		opts = append(opts, client.WithBasicAuth(p.User, p.Password))
+	}
+	if p.Dealership != "" {
+		opts = append(opts, client.WithDealership(p.Dealership))
+	}
+	return client.New(p.Server, opts...)
+}
@@ -0,0 +1,84 @@
+// Command carsctl operates the car inventory of a running cars server.
+//
+//	carsctl profile set prod --server https://cars.example.com --user alice
+//	carsctl --profile prod list --make Toyota --sort -price
+//	carsctl export -o csv > cars.csv
+//	carsctl import cars.csv
+//
+// Shell completion scripts are printed by "carsctl completion bash|zsh|fish".
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+func main() {
+	if err := newRootCmd().execute(context.Background(), os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		os.Exit(1)
+	}
+}
+
+// globalFlags are the connection and output settings shared by every
+// command. Unset flags fall back to the selected profile.
+type globalFlags struct {
+	config     string
+	profile    string
+	server     string
+	user       string
+	password   string
+	dealership string
+	output     string
+}
+
+func newRootCmd() *command {
+	g := &globalFlags{}
+
+	root := newCommand("carsctl", "Operate the car inventory of a cars server")
+	root.add(
+		newListCmd(g),
+		newGetCmd(g),
+		newCreateCmd(g),
+		newUpdateCmd(g),
+		newDeleteCmd(g),
+		newTransitionCmd(g),
+		newReserveCmd(g),
+		newReleaseCmd(g),
+		newImportCmd(g),
+		newExportCmd(g),
+		newProfileCmd(g),
+		newCompletionCmd(),
+		newCompleteCmd(),
+	)
+	// Every command accepts the global flags, before or after its
+	// arguments.
+	root.walk(g.register)
+	return root
+}
+
+func (g *globalFlags) register(c *command) {
+	c.stringFlag(&g.config, "config", "", defaultConfigPath(), "config file with the profiles")
+	c.stringFlag(&g.profile, "profile", "p", "", "profile to use instead of the current one")
+	c.stringFlag(&g.server, "server", "", "", "server URL, e.g. http://localhost:8080")
+	c.stringFlag(&g.user, "user", "", "", "basic auth user")
+	c.stringFlag(&g.password, "password", "", "", "basic auth password")
+	c.stringFlag(&g.dealership, "dealership", "", "", "dealership to act on, by default the home dealership of the user")
+	c.stringFlag(&g.output, "output", "o", "", "output format: table, json or csv")
+	for _, name := range []string{"config", "profile", "server", "user", "password", "dealership", "output"} {
+		c.globalFlags[name] = true
+	}
+
+	c.completeFlag("output", formats...)
+	c.completeFlags["profile"] = func() []string {
+		cfg, err := loadConfig(g.config)
+		if err != nil {
+			return nil
+		}
+		return cfg.names()
+	}
+}
+
+func fail(format string, a ...interface{}) error {
+	return fmt.Errorf(format, a...)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"example/cars/client"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+var formats = []string{formatTable, formatJSON, formatCSV}
+
+func validFormat(f string) bool {
+	return f == formatTable || f == formatJSON || f == formatCSV
+}
+
+// csvHeader is the column order of CSV exports and imports.
+var csvHeader = []string{"id", "make", "model", "package", "color", "year", "category", "mileage", "price", "mileage_unit", "status", "vin"}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func carRecord(c client.Car) []string {
+	return []string{c.Id, c.Make, c.Model, c.Package, c.Color, strconv.Itoa(c.Year), c.Category, formatNumber(c.Mileage), formatNumber(c.Price), c.MileageUnit, c.Status, c.Vin}
+}
+
+// writeCars prints cars in the given format.
+func writeCars(w io.Writer, format string, cars []client.Car) error {
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(cars)
+	case formatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write(csvHeader)
+		for _, c := range cars {
+			cw.Write(carRecord(c))
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(csvHeader, "\t")))
+		for _, c := range cars {
+			fmt.Fprintln(tw, strings.Join(carRecord(c), "\t"))
+		}
+		return tw.Flush()
+	}
+}
+
+// readCars parses cars from JSON (an array or a single object) or from CSV
+// with a csvHeader header row, in any column order.
+func readCars(r io.Reader, format string) ([]client.Car, error) {
+	if format == formatJSON {
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		var cars []client.Car
+		if err := json.Unmarshal(b, &cars); err != nil {
+			var car client.Car
+			if json.Unmarshal(b, &car) != nil {
+				return nil, err
+			}
+			cars = []client.Car{car}
+		}
+		return cars, nil
+	}
+
+	cr := csv.NewReader(r)
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []client.Car{}, nil
+	}
+
+	col := map[string]int{}
+	for i, name := range rows[0] {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := col["id"]; !ok {
+		return nil, fail("csv header must include id")
+	}
+
+	cars := []client.Car{}
+	for n, row := range rows[1:] {
+		get := func(name string) string {
+			if i, ok := col[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		number := func(name string) (float64, error) {
+			v := get(name)
+			if v == "" {
+				return 0, nil
+			}
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return 0, fail("line %d: %s must be a number", n+2, name)
+			}
+			return f, nil
+		}
+
+		c := client.Car{Id: get("id"), Make: get("make"), Model: get("model"), Package: get("package"), Color: get("color"), Category: get("category"), MileageUnit: get("mileage_unit"), Status: get("status"), Vin: get("vin")}
+		year, err := number("year")
+		if err != nil {
+			return nil, err
+		}
+		c.Year = int(year)
+		if c.Mileage, err = number("mileage"); err != nil {
+			return nil, err
+		}
+		if c.Price, err = number("price"); err != nil {
+			return nil, err
+		}
+		cars = append(cars, c)
+	}
+	return cars, nil
+}
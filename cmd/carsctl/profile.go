@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+)
+
+func newProfileCmd(g *globalFlags) *command {
+	cmd := newCommand("profile", "Manage the connection profiles of each environment")
+
+	completeNames := func(c *command, args []string, toComplete string) []string {
+		cfg, err := loadConfig(g.config)
+		if err != nil || len(args) > 0 {
+			return nil
+		}
+		return cfg.names()
+	}
+
+	list := newCommand("list", "List the profiles")
+	list.args = noArgs
+	list.run = func(c *command, args []string) error {
+		cfg, err := loadConfig(g.config)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(c.stdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "CURRENT\tNAME\tSERVER\tUSER")
+		for _, name := range cfg.names() {
+			current := ""
+			if name == cfg.Current {
+				current = "*"
+			}
+			p := cfg.Profiles[name]
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", current, name, p.Server, p.User)
+		}
+		return tw.Flush()
+	}
+
+	set := newCommand("set NAME", "Create or update a profile from the global flags")
+	set.long = "Saves --server, --user, --password, --dealership and --output under NAME. Flags that are not given keep their saved value. The first profile becomes the current one."
+	set.args = exactArgs(1)
+	set.completeArgs = completeNames
+	set.run = func(c *command, args []string) error {
+		cfg, err := loadConfig(g.config)
+		if err != nil {
+			return err
+		}
+		if g.output != "" && !validFormat(g.output) {
+			return fail("output must be one of table, json, csv")
+		}
+
+		p := cfg.Profiles[args[0]]
+		override := func(dst *string, v string) {
+			if v != "" {
+				*dst = v
+			}
+		}
+		override(&p.Server, g.server)
+		override(&p.User, g.user)
+		override(&p.Password, g.password)
+		override(&p.Dealership, g.dealership)
+		override(&p.Output, g.output)
+		cfg.Profiles[args[0]] = p
+		if cfg.Current == "" {
+			cfg.Current = args[0]
+		}
+
+		return cfg.save(g.config)
+	}
+
+	use := newCommand("use NAME", "Make a profile the current one")
+	use.args = exactArgs(1)
+	use.completeArgs = completeNames
+	use.run = func(c *command, args []string) error {
+		cfg, err := loadConfig(g.config)
+		if err != nil {
+			return err
+		}
+		if _, ok := cfg.Profiles[args[0]]; !ok {
+			return fail("profile %q not found", args[0])
+		}
+		cfg.Current = args[0]
+		return cfg.save(g.config)
+	}
+
+	remove := newCommand("delete NAME", "Delete a profile")
+	remove.args = exactArgs(1)
+	remove.completeArgs = completeNames
+	remove.run = func(c *command, args []string) error {
+		cfg, err := loadConfig(g.config)
+		if err != nil {
+			return err
+		}
+		if _, ok := cfg.Profiles[args[0]]; !ok {
+			return fail("profile %q not found", args[0])
+		}
+		delete(cfg.Profiles, args[0])
+		if cfg.Current == args[0] {
+			cfg.Current = ""
+		}
+		return cfg.save(g.config)
+	}
+
+	cmd.add(list, set, use, remove)
+	return cmd
+}
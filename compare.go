@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// How many cars a comparison takes.
+const (
+	minCompared = 2
+	maxCompared = 3
+)
+
+// comparedFields are the fields of a comparison, in order. Numeric fields
+// also get deltas.
+var comparedFields = []struct {
+	name  string
+	value func(Car) interface{}
+}{
+	{"make", func(c Car) interface{} { return c.Make }},
+	{"model", func(c Car) interface{} { return c.Model }},
+	{"package", func(c Car) interface{} { return c.Package }},
+	{"color", func(c Car) interface{} { return c.Color }},
+	{"year", func(c Car) interface{} { return c.Year }},
+	{"category", func(c Car) interface{} { return c.Category }},
+	{"mileage", func(c Car) interface{} { return c.Mileage }},
+	{"price", func(c Car) interface{} { return c.Price }},
+	{"status", func(c Car) interface{} { return c.Status }},
+	{"vin", func(c Car) interface{} { return c.Vin }},
+	{"country", func(c Car) interface{} { return c.Country }},
+}
+
+// fieldComparison is one field of the compared cars, with a value per car
+// in the order of the ids. Deltas are the differences from the first car.
+// @Description a field of the compared cars
+type fieldComparison struct {
+	Field   string        `json:"field"`
+	Values  []interface{} `json:"values"`
+	Differs bool          `json:"differs"`
+	Deltas  []float64     `json:"deltas,omitempty"`
+}
+
+// comparison lines up cars field by field.
+// @Description cars compared field by field
+type comparison struct {
+	Ids    []string          `json:"ids"`
+	Cars   []Car             `json:"cars"`
+	Fields []fieldComparison `json:"fields"`
+}
+
+// compareError rejects a comparison of cars that are not listed. It is
+// written as the response body of the 404.
+// @Description a comparison of unknown cars
+type compareError struct {
+	Message string   `json:"error"`
+	Missing []string `json:"missing"`
+}
+
+func (e *compareError) Error() string {
+	return e.Message
+}
+
+// compare lines up cars field by field. Text is compared ignoring case.
+func compare(cars []Car) comparison {
+	out := comparison{Ids: []string{}, Cars: cars, Fields: []fieldComparison{}}
+	for _, c := range cars {
+		out.Ids = append(out.Ids, c.Id)
+	}
+
+	for _, f := range comparedFields {
+		fc := fieldComparison{Field: f.name, Values: []interface{}{}}
+		for _, c := range cars {
+			v := f.value(c)
+			if len(fc.Values) > 0 && !sameValue(fc.Values[0], v) {
+				fc.Differs = true
+			}
+			fc.Values = append(fc.Values, v)
+		}
+		if numeric := numericFields[f.name]; numeric != nil {
+			fc.Deltas = []float64{}
+			for _, c := range cars {
+				fc.Deltas = append(fc.Deltas, math.Round((numeric(c)-numeric(cars[0]))*100)/100)
+			}
+		}
+		out.Fields = append(out.Fields, fc)
+	}
+	return out
+}
+
+func sameValue(a, b interface{}) bool {
+	if s, ok := a.(string); ok {
+		return strings.EqualFold(s, b.(string))
+	}
+	return a == b
+}
+
+// compareCars looks up the cars of ids and compares them with mileage in
+// units, failing with a *compareError that lists every id not found.
+func (db *Db) compareCars(ids []string, units string) (comparison, error) {
+	cars := []Car{}
+	missing := []string{}
+	for _, id := range ids {
+		car, err := db.getById(id)
+		if err != nil {
+			missing = append(missing, id)
+			continue
+		}
+		cars = append(cars, db.withMedia(car).inUnits(units))
+	}
+	if len(missing) > 0 {
+		return comparison{}, &compareError{Message: fmt.Sprintf("ids not found: %s", strings.Join(missing, ", ")), Missing: missing}
+	}
+	return compare(cars), nil
+}
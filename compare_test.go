@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare_WhenCarsDiffer(t *testing.T){
+	a := *persistTestCar("a")
+	b := a
+	b.Id, b.Color, b.Mileage, b.Price, b.Year = "b", "gray", 1299, 2299000, 2012
+
+	out := compare([]Car{a, b})
+
+	assert.Equal(t, out.Ids, []string{"a", "b"})
+	fields := map[string]fieldComparison{}
+	for _, f := range out.Fields {
+		fields[f.Field] = f
+	}
+	assert.Equal(t, fields["color"], fieldComparison{Field: "color", Values: []interface{}{"Gray", "gray"}, Differs: false})
+	assert.Equal(t, fields["price"].Differs, true)
+	assert.Equal(t, fields["price"].Deltas, []float64{0, -200000})
+	assert.Equal(t, fields["mileage"].Deltas, []float64{0, 500})
+	assert.Equal(t, fields["year"].Deltas, []float64{0, -1})
+	assert.Equal(t, fields["make"].Deltas, []float64(nil))
+}
+
+func TestCompareHandler_WhenRequested(t *testing.T){
+	for i, id := range []string{"cmpcar1", "cmpcar2"} {
+		car := persistTestCar(id)
+		car.Mileage = 1000 * float64(i+1)
+		car.createCar()
+	}
+	h := &carHandler{}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/cars:compare?ids=cmpcar2,cmpcar1&units=km", nil))
+	var out comparison
+	json.Unmarshal(w.Body.Bytes(), &out)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, out.Ids, []string{"cmpcar2", "cmpcar1"})
+	assert.Equal(t, out.Fields[6].Field, "mileage")
+	assert.Equal(t, out.Fields[6].Deltas, []float64{0, -1609.35})
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/cars:compare?ids=cmpcar1,nocmp1,nocmp2", nil))
+	var missing compareError
+	json.Unmarshal(w.Body.Bytes(), &missing)
+	assert.Equal(t, w.Code, http.StatusNotFound)
+	assert.Equal(t, missing, compareError{Message: "ids not found: nocmp1, nocmp2", Missing: []string{"nocmp1", "nocmp2"}})
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/cars:compare?ids=cmpcar1", nil))
+	assert.Equal(t, w.Body.String(), `{"error":"ids must list 2 or 3 cars"}`)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/cars:compare?ids=cmpcar1,cmpcar1", nil))
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+}
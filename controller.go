@@ -2,9 +2,13 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // getAll godoc
@@ -13,13 +17,39 @@ import (
 // @Tags		car
 // @Accept		json
 // @Produce		json
+// @Param		units		query			string			false			"Odometer units of the response and mileage filters"	Enums(mi, km)
+// @Param		make		query			string			false			"Filter by make"
+// @Param		model		query			string			false			"Filter by model"
+// @Param		category	query			string			false			"Filter by category"
+// @Param		color		query			string			false			"Filter by color"
+// @Param		status		query			string			false			"Filter by status, comma separated: incoming, in_transit, available, reserved, sold"
+// @Param		min_year	query			int				false			"Minimum year"
+// @Param		max_year	query			int				false			"Maximum year"
+// @Param		min_price	query			number			false			"Minimum price"
+// @Param		max_price	query			number			false			"Maximum price"
+// @Param		min_mileage	query			number			false			"Minimum mileage, in units"
+// @Param		max_mileage	query			number			false			"Maximum mileage, in units"
+// @Param		sort		query			string			false			"Sort field, prefix with - for descending"
+// @Param		deleted		query			bool			false			"List the cars in the trash instead, as trashedCar objects"
 // @Success		200 		{array} 		Car			"OK"
+// @Failure		400			{string}		string			"BadRequest"
 // @Router		/cars		[get]
 func (h *carHandler) getAll(w http.ResponseWriter, r *http.Request){
 	defer h.Unlock()
 	h.Lock()
 
-	car := Car{}
+	filter, err := parseCarQuery(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if r.URL.Query().Get("deleted") == "true" {
+		h.getDeleted(w, r, filter)
+		return
+	}
+
+	car := Car{Dealership: dealershipOf(r.Context())}
 	q, err := car.getAllCars()
 
 	if err != nil {
@@ -27,9 +57,247 @@ func (h *carHandler) getAll(w http.ResponseWriter, r *http.Request){
 		return
 	}
 	
+	respondWithJSON(w, http.StatusOK, carsInUnits(filter.apply(q), filter.units))
+}
+
+func (h *carHandler) getDeleted(w http.ResponseWriter, r *http.Request, filter carQuery) {
+	car := Car{Dealership: dealershipOf(r.Context())}
+	q, err := car.getDeletedCars()
+
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	out := []trashedCar{}
+	for _, v := range q {
+		if filter.match(v.Car) {
+			v.Car = v.Car.inUnits(filter.units)
+			out = append(out, v)
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, out)
+}
+
+// search godoc
+// @Summary		Search cars
+// @Description	Full-text search over make, model, package, color and category. Matching is case-insensitive and tolerates small typos; results are ranked by relevance and matched words are wrapped in <em> tags
+// @Tags		car
+// @Accept		json
+// @Produce		json
+// @Param		q			query			string			true			"Search terms"
+// @Param		units		query			string			false			"Odometer units of the response"	Enums(mi, km)
+// @Success		200			{array}			searchResult	"OK"
+// @Failure		400			{string}		string			"BadRequest"
+// @Router		/cars/search [get]
+func (h *carHandler) search(w http.ResponseWriter, r *http.Request) {
+	defer h.Unlock()
+	h.Lock()
+
+	units, err := parseUnits(r.URL.Query().Get("units"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	car := Car{Dealership: dealershipOf(r.Context())}
+	q, err := car.searchCars(r.URL.Query().Get("q"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	for i := range q {
+		q[i].Car = q[i].Car.inUnits(units)
+	}
+
+	respondWithJSON(w, http.StatusOK, q)
+}
+
+// facets godoc
+// @Summary		Get inventory facets
+// @Description	Counts cars per make, category, color and year, and buckets price and mileage into ranges. Accepts the same filters as the car listing
+// @Tags		car
+// @Accept		json
+// @Produce		json
+// @Param		units				query		string		false		"Odometer units of the mileage buckets and filters"	Enums(mi, km)
+// @Param		make				query		string		false		"Filter by make"
+// @Param		model				query		string		false		"Filter by model"
+// @Param		category			query		string		false		"Filter by category"
+// @Param		color				query		string		false		"Filter by color"
+// @Param		status				query		string		false		"Filter by status, comma separated: incoming, in_transit, available, reserved, sold"
+// @Param		min_year			query		int			false		"Minimum year"
+// @Param		max_year			query		int			false		"Maximum year"
+// @Param		min_price			query		number		false		"Minimum price"
+// @Param		max_price			query		number		false		"Maximum price"
+// @Param		min_mileage			query		number		false		"Minimum mileage, in units"
+// @Param		max_mileage			query		number		false		"Maximum mileage, in units"
+// @Param		price_interval		query		number		false		"Width of the price buckets"
+// @Param		mileage_interval	query		number		false		"Width of the mileage buckets, in units"
+// @Success		200			{object}		facets			"OK"
+// @Failure		400			{string}		string			"BadRequest"
+// @Router		/cars/facets [get]
+func (h *carHandler) facets(w http.ResponseWriter, r *http.Request) {
+	defer h.Unlock()
+	h.Lock()
+
+	filter, err := parseCarQuery(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	priceInterval, err := floatParam(r.URL.Query(), "price_interval")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if r.URL.Query().Get("price_interval") == "" {
+		priceInterval = defaultPriceInterval
+	}
+
+	mileageInterval, err := floatParam(r.URL.Query(), "mileage_interval")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if r.URL.Query().Get("mileage_interval") == "" {
+		mileageInterval = defaultMileageInterval
+	}
+
+	car := Car{Dealership: dealershipOf(r.Context())}
+	q, err := car.getFacets(filter, priceInterval, mileageInterval)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, q)
+}
+
+// stats godoc
+// @Summary		Get inventory statistics
+// @Description	Groups the filtered cars by the group_by fields and computes count, sum, min, max, mean, median and percentiles of the requested numeric fields. Accepts the same filters as the car listing
+// @Tags		car
+// @Accept		json
+// @Produce		json
+// @Param		group_by		query		string		false		"Comma separated fields to group by: make, model, package, color, category, year"
+// @Param		fields			query		string		false		"Comma separated numeric fields to aggregate: price, mileage, year. Defaults to price,mileage"
+// @Param		percentiles		query		string		false		"Comma separated percentiles between 0 and 100. Defaults to 25,50,75,90"
+// @Param		units			query		string		false		"Odometer units of the mileage aggregates and filters"	Enums(mi, km)
+// @Param		make			query		string		false		"Filter by make"
+// @Param		model			query		string		false		"Filter by model"
+// @Param		category		query		string		false		"Filter by category"
+// @Param		color			query		string		false		"Filter by color"
+// @Param		status			query		string		false		"Filter by status, comma separated: incoming, in_transit, available, reserved, sold"
+// @Param		min_year		query		int			false		"Minimum year"
+// @Param		max_year		query		int			false		"Maximum year"
+// @Param		min_price		query		number		false		"Minimum price"
+// @Param		max_price		query		number		false		"Maximum price"
+// @Param		min_mileage		query		number		false		"Minimum mileage, in units"
+// @Param		max_mileage		query		number		false		"Maximum mileage, in units"
+// @Success		200			{array}			statsGroup		"OK"
+// @Failure		400			{string}		string			"BadRequest"
+// @Router		/cars/stats [get]
+func (h *carHandler) stats(w http.ResponseWriter, r *http.Request) {
+	defer h.Unlock()
+	h.Lock()
+
+	filter, err := parseCarQuery(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s, err := parseStatsQuery(r.URL.Query().Get("group_by"), r.URL.Query().Get("fields"), r.URL.Query().Get("percentiles"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	car := Car{Dealership: dealershipOf(r.Context())}
+	q, err := car.getStats(filter, s)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	respondWithJSON(w, http.StatusOK, q)
 }
 
+// events godoc
+// @Summary		Stream car events
+// @Description	Streams car.created, car.updated, car.deleted and car.restored events as Server-Sent Events. Clients reconnecting with Last-Event-ID receive the events they missed from a bounded replay buffer; if some were already evicted, or the id is from before the server restarted, a "reset" event is sent first and the client should refetch the listing
+// @Tags		car
+// @Produce		text/event-stream
+// @Param		make			query		string		false		"Only events for cars of this make"
+// @Param		category		query		string		false		"Only events for cars of this category"
+// @Param		Last-Event-ID	header		string		false		"Id of the last event received"
+// @Success		200			{object}		carEvent		"OK"
+// @Failure		400			{string}		string			"BadRequest"
+// @Router		/cars/events [get]
+func (h *carHandler) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	var lastId uint64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Last-Event-ID must be an event id")
+			return
+		}
+		lastId = id
+	}
+
+	filter := eventFilter{dealership: dealershipOf(r.Context()), make: r.URL.Query().Get("make"), category: r.URL.Query().Get("category")}
+
+	ch, backlog, complete := h.stream.subscribe(lastId)
+	defer h.stream.unsubscribe(ch)
+
+	w.Header().Set("content-type", "text/event-stream")
+	w.Header().Set("cache-control", "no-cache")
+	w.Header().Set("connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if lastId != 0 && !complete {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, e := range backlog {
+		if filter.match(e) {
+			writeEvent(w, e)
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			if filter.match(e) {
+				if err := writeEvent(w, e); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	}
+}
+
 // getById godoc
 // @Summary		Get a car
 // @Description	Gets a single car from the database corresponding to the id in the path. Otherwise, returns error
@@ -37,38 +305,260 @@ func (h *carHandler) getAll(w http.ResponseWriter, r *http.Request){
 // @Accept		json
 // @Produce		json
 // @Param		id			path			string			true			"Car Id"
+// @Param		units		query			string			false			"Odometer units of the response"	Enums(mi, km)
+// @Param		as_of		query			string			false			"RFC 3339 timestamp; returns the car as it was at that instant"
 // @Success		200			{object}		Car				"OK"
+// @Failure		400			{string}		string			"BadRequest"
 // @Failure		404			{string}		string			"NotFound"
 // @Router		/cars/{id} 	[get]
 func (h *carHandler) getById(w http.ResponseWriter, r *http.Request) {
 	defer h.Unlock()
 	h.Lock()
 
+	units, err := parseUnits(r.URL.Query().Get("units"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var asOf time.Time
+	if v := r.URL.Query().Get("as_of"); v != "" {
+		asOf, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "as_of must be an RFC 3339 timestamp")
+			return
+		}
+	}
+
 	id := idFromUrl(r)
 
-	car := Car{Id: id}
+	car := Car{Id: id, Dealership: dealershipOf(r.Context())}
 	if id != "-1" {
 		query, err := car.getCarById()
+		if !asOf.IsZero() {
+			query, err = car.getCarAsOf(asOf)
+		}
 		
 		if err != nil {
 			respondWithError(w, http.StatusNotFound, err.Error())
 			return
 		}
 
-		respondWithJSON(w, http.StatusOK, query)
+		respondWithJSON(w, http.StatusOK, query.inUnits(units))
+		return
+	}
+}
+
+// duplicates godoc
+// @Summary		Report duplicate listings
+// @Description	Groups the cars that look like the same vehicle: the same vin, or, when either has no vin, the same make, model, year and color with a similar mileage. Names are compared ignoring case and punctuation, allowing one typo
+// @Tags		car
+// @Accept		json
+// @Produce		json
+// @Param		units				query			string				false			"Odometer units of the response"	Enums(mi, km)
+// @Success		200					{array}			duplicateGroup		"OK"
+// @Failure		400					{string}		string				"BadRequest"
+// @Router		/cars/duplicates	[get]
+func (h *carHandler) duplicates(w http.ResponseWriter, r *http.Request) {
+	defer h.Unlock()
+	h.Lock()
+
+	units, err := parseUnits(r.URL.Query().Get("units"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	car := Car{Dealership: dealershipOf(r.Context())}
+	q, err := car.getDuplicates()
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	for i := range q {
+		q[i].Cars = carsInUnits(q[i].Cars, units)
+	}
+	respondWithJSON(w, http.StatusOK, q)
+}
+
+// valuation godoc
+// @Summary		Value a car
+// @Description	Estimates the market price of a car by least squares regression of price on year and mileage over the priced cars of the same make and model. With fewer than three of those, cars of the same make and category, then of the same category, are used instead. Returns the estimate, a 95% range and the comparables used
+// @Tags		car
+// @Accept		json
+// @Produce		json
+// @Param		make			query		string		true		"Make"
+// @Param		model			query		string		true		"Model"
+// @Param		category		query		string		false		"Category, to fall back on when the model has too few comparables"
+// @Param		year			query		int			true		"Year"
+// @Param		mileage			query		number		false		"Mileage, in units"
+// @Param		units			query		string		false		"Odometer units of the mileage and the response"	Enums(mi, km)
+// @Success		200			{object}		valuation		"OK"
+// @Failure		400			{string}		string			"BadRequest"
+// @Failure		422			{string}		string			"UnprocessableEntity, not enough comparable cars"
+// @Router		/cars/valuation [get]
+func (h *carHandler) valuation(w http.ResponseWriter, r *http.Request) {
+	defer h.Unlock()
+	h.Lock()
+
+	q, err := parseValuationQuery(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	car := Car{Dealership: dealershipOf(r.Context())}
+	v, err := car.getValuation(q)
+	if err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, v.inUnits(q.units))
+}
+
+// carValuation godoc
+// @Summary		Value a listed car
+// @Description	Estimates the market price of the car from the other cars in the store, as GET /cars/valuation does for its make, model, category, year and mileage
+// @Tags		car
+// @Accept		json
+// @Produce		json
+// @Param		id			path			string			true			"Car Id"
+// @Param		units		query			string			false			"Odometer units of the response"	Enums(mi, km)
+// @Success		200			{object}		valuation		"OK"
+// @Failure		400			{string}		string			"BadRequest"
+// @Failure		404			{string}		string			"NotFound"
+// @Failure		422			{string}		string			"UnprocessableEntity, not enough comparable cars"
+// @Router		/cars/{id}/valuation [get]
+func (h *carHandler) carValuation(w http.ResponseWriter, r *http.Request) {
+	defer h.Unlock()
+	h.Lock()
+
+	units, err := parseUnits(r.URL.Query().Get("units"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	car := Car{Id: idFromUrl(r), Dealership: dealershipOf(r.Context())}
+	v, err := car.getCarValuation()
+	if err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, v.inUnits(units))
+}
+
+// financing godoc
+// @Summary		Finance a car
+// @Description	Amortises the price of the car less the down payment over equal monthly payments at a fixed rate. The rate is apr, or the lender rate of the credit tier for the term as listed by GET /financing/rates. Returns the monthly payment, the total interest and cost, and the schedule of payments; the last payment absorbs the rounding to cents
+// @Tags		car
+// @Accept		json
+// @Produce		json
+// @Param		id			path			string			true			"Car Id"
+// @Param		down		query			number			false			"Down payment"
+// @Param		apr			query			number			false			"Annual percentage rate, e.g. 6.9"
+// @Param		tier		query			string			false			"Credit tier whose lender rate to use instead of apr"
+// @Param		term		query			int				false			"Loan length in months, between 1 and 120. Defaults to 60"
+// @Success		200			{object}		financing		"OK"
+// @Failure		400			{string}		string			"BadRequest"
+// @Failure		404			{string}		string			"NotFound"
+// @Router		/cars/{id}/financing [get]
+func (h *carHandler) financing(w http.ResponseWriter, r *http.Request) {
+	defer h.Unlock()
+	h.Lock()
+
+	q, err := parseFinancingQuery(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	car := Car{Id: idFromUrl(r), Dealership: dealershipOf(r.Context())}
+	f, err := car.getFinancing(q)
+	if err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, f)
+}
+
+// compare godoc
+// @Summary		Compare cars
+// @Description	Lines up two or three cars field by field, in the order of ids. Each field tells whether the cars differ, ignoring case, and price, mileage and year carry the differences from the first car. Unknown ids are listed together in the 404
+// @Tags		car
+// @Accept		json
+// @Produce		json
+// @Param		ids			query			string			true			"Comma separated ids of two or three cars"
+// @Param		units		query			string			false			"Odometer units of the response"	Enums(mi, km)
+// @Success		200			{object}		comparison		"OK"
+// @Failure		400			{string}		string			"BadRequest"
+// @Failure		404			{object}		compareError	"NotFound, with the ids not found"
+// @Router		/cars:compare [get]
+func (h *carHandler) compare(w http.ResponseWriter, r *http.Request) {
+	defer h.Unlock()
+	h.Lock()
+
+	units, err := parseUnits(r.URL.Query().Get("units"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	car := Car{Dealership: dealershipOf(r.Context())}
+	q, err := car.getComparison(splitList(r.URL.Query().Get("ids")), units)
+	if e, ok := err.(*compareError); ok {
+		respondWithJSON(w, statusOf(err), e)
+		return
+	}
+	if err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, q)
+}
+
+// history godoc
+// @Summary		Get a car's history
+// @Description	Lists every recorded version of the car, oldest first, with the fields changed by each mutation. Deleted cars keep their history
+// @Tags		car
+// @Accept		json
+// @Produce		json
+// @Param		id			path			string			true			"Car Id"
+// @Success		200			{array}			carVersion		"OK"
+// @Failure		404			{string}		string			"NotFound"
+// @Router		/cars/{id}/history [get]
+func (h *carHandler) history(w http.ResponseWriter, r *http.Request) {
+	defer h.Unlock()
+	h.Lock()
+
+	car := Car{Id: idFromUrl(r), Dealership: dealershipOf(r.Context())}
+	q, err := car.getCarHistory()
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, err.Error())
 		return
 	}
+
+	respondWithJSON(w, http.StatusOK, q)
 }
 
 // post godoc
 // @Summary		Create a new car
-// @Description	Creates a new car in the database. In case of existing id returns error
+// @Description	Creates a new car in the database. New cars start incoming, in_transit or available, the default; the status is then changed with :transition, and a car becomes reserved only by holding it through /cars/{id}/reservations. When a vin is given its check digit is validated, empty make, year and country are filled from it, and submitted values that disagree with it are listed in vin_warnings. A car with the vin of a listed car, or without a vin but with the make, model, year and color and a similar mileage of one, is rejected with the possible duplicates unless allow_duplicate is true. In case of existing id returns error
 // @Tags		car
 // @Accept		json
 // @Produce		json
 // @Param		car			body			Car				true			"Car JSON Object"
+// @Param		allow_duplicate	query		bool			false			"Create the car even if it looks like a listed one"
 // @Success		201			{object}		Car				"OK"
 // @Failure		400			{string}		string			"BadRequest"
+// @Failure		409			{object}		duplicateError	"Conflict, the car looks like a listed one"
+// @Param		Idempotency-Key	header	string	false	"Replays the stored response when the request is repeated with the same key"
+// @Failure		422			{string}		string			"Idempotency-Key reused with a different request"
 // @Router		/cars 		[post]
 func (h *carHandler) post(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
@@ -84,16 +574,21 @@ func (h *carHandler) post(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var car Car
-	if r.URL.String() == "/cars" || r.URL.String() == "/cars/"{
+	if r.URL.Path == "/cars" || r.URL.Path == "/cars/"{
 		err = json.Unmarshal(body, &car)
 		if err != nil {
 			respondWithError(w, http.StatusBadRequest, err.Error())
 			return
 		}
+		car.Dealership = dealershipOf(r.Context())
 		defer h.Unlock()
 		h.Lock()
-		q, err := car.createCar()
+		q, err := car.createListing(r.URL.Query().Get("allow_duplicate") == "true")
 
+		if _, ok := err.(*duplicateError); ok {
+			respondWithJSON(w, statusOf(err), err)
+			return
+		}
 		if err != nil {
 			respondWithError(w, http.StatusBadRequest, err.Error())
 			return
@@ -106,7 +601,7 @@ func (h *carHandler) post(w http.ResponseWriter, r *http.Request) {
 
 // put godoc
 // @Summary		Update a car
-// @Description	Updates an existing car from the database corresponding to the id sent. Otherwise, returns error
+// @Description	Updates an existing car from the database corresponding to the id sent. The status is kept; it is changed with :transition. Otherwise, returns error
 // @Tags			car
 // @Accept		json
 // @Produce		json
@@ -114,6 +609,9 @@ func (h *carHandler) post(w http.ResponseWriter, r *http.Request) {
 // @Success		200			{object}		Car				"OK"
 // @Failure		400			{string}		string			"BadRequest"
 // @Failure		404			{string}		string
+// @Failure		409			{string}		string			"Conflict, the car is held by someone else"
+// @Param		Idempotency-Key	header	string	false	"Replays the stored response when the request is repeated with the same key"
+// @Failure		422			{string}		string			"Idempotency-Key reused with a different request"
 // @Router		/cars		[put]
 func (h *carHandler) put(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
@@ -136,23 +634,21 @@ func (h *carHandler) put(w http.ResponseWriter, r *http.Request) {
 			respondWithError(w, http.StatusBadRequest, err.Error())
 			return
 		}
+		car.Dealership = dealershipOf(r.Context())
 
 		defer h.Unlock()
 		h.Lock()		
 
+		if err := car.checkHold(actorFromRequest(r)); err != nil {
+			respondWithError(w, statusOf(err), err.Error())
+			return
+		}
+
 		q, err := car.updateCar()
 
 		if err != nil{
-			if err.Error() == "id field empty" || err.Error() == "make field empty" || err.Error() == "model field empty" || 
-			err.Error() == "package field empty" || err.Error() == "color field empty" || err.Error() == "year field must be gt 0" ||
-			err.Error() == "category field empty" || err.Error() == "mileage field must be gt 0" || err.Error() == "price field must be gt 0" {
-				respondWithError(w, http.StatusBadRequest, err.Error())
-				return
-			}
-			if err.Error() == "id not found" {
-				respondWithError(w, http.StatusNotFound, err.Error())
-				return
-			}
+			respondWithError(w, statusOf(err), err.Error())
+			return
 		}
 		respondWithJSON(w, http.StatusOK, q)
 		return
@@ -162,13 +658,16 @@ func (h *carHandler) put(w http.ResponseWriter, r *http.Request) {
 
 // delete godoc
 // @Summary		Delete a car
-// @Description  Deletes an existing car from the database corresponding to the id in the path. Otherwise, returns error
+// @Description  Moves an existing car corresponding to the id in the path to the trash, from where it can be restored until the retention period expires. Otherwise, returns error
 // @Tags			car
 // @Accept		json
 // @Produce		json
 // @Param		id			path			string			true			"Car Id"
 // @Success		204			{string}		string			"NoContent"
 // @Failure		404			{string}		string			"NotFound"
+// @Failure		409			{string}		string			"Conflict, the car is held by someone else"
+// @Param		Idempotency-Key	header	string	false	"Replays the stored response when the request is repeated with the same key"
+// @Failure		422			{string}		string			"Idempotency-Key reused with a different request"
 // @Router		/cars/{id}	[delete]
 func (h *carHandler) delete(w http.ResponseWriter, r *http.Request) {
 	defer h.Unlock()
@@ -176,13 +675,18 @@ func (h *carHandler) delete(w http.ResponseWriter, r *http.Request) {
 
 	id := idFromUrl(r)
 
-	car := Car{Id: id}
+	car := Car{Id: id, Dealership: dealershipOf(r.Context())}
 
 	if id != "-1"{
-		q, err := car.deleteCar()
+		if err := car.checkHold(actorFromRequest(r)); err != nil {
+			respondWithError(w, statusOf(err), err.Error())
+			return
+		}
+
+		q, err := car.deleteCar(actorFromRequest(r))
 
 		if err != nil {
-			respondWithError(w, http.StatusNotFound, err.Error())
+			respondWithError(w, statusOf(err), err.Error())
 			return
 		}
 
@@ -191,6 +695,597 @@ func (h *carHandler) delete(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// restore godoc
+// @Summary		Restore a deleted car
+// @Description	Moves a car back from the trash. Fails if the car was purged or another car now uses its id
+// @Tags			car
+// @Accept		json
+// @Produce		json
+// @Param		id			path			string			true			"Car Id"
+// @Success		200			{object}		Car				"OK"
+// @Failure		404			{string}		string			"NotFound"
+// @Failure		409			{string}		string			"Conflict"
+// @Param		Idempotency-Key	header	string	false	"Replays the stored response when the request is repeated with the same key"
+// @Failure		422			{string}		string			"Idempotency-Key reused with a different request"
+// @Router		/cars/{id}:restore	[post]
+func (h *carHandler) restore(w http.ResponseWriter, r *http.Request) {
+	defer h.Unlock()
+	h.Lock()
+
+	id, _ := customMethod(r)
+	car := Car{Id: id, Dealership: dealershipOf(r.Context())}
+
+	q, err := car.restoreCar()
+	if err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, q)
+}
+
+// transition godoc
+// @Summary		Change the status of a car
+// @Description	Moves the car along its lifecycle: incoming and in_transit cars become available, available cars can be sold, and reserved or sold cars can go back to available. A car becomes reserved only by holding it through /cars/{id}/reservations. The actor and reason are recorded in the car's history
+// @Tags			car
+// @Accept		json
+// @Produce		json
+// @Param		id					path			string				true			"Car Id"
+// @Param		transition			body			statusTransition	true			"Status Transition JSON Object"
+// @Param		Idempotency-Key		header			string				false			"Replays the stored response when the request is repeated with the same key"
+// @Success		200					{object}		Car					"OK"
+// @Failure		400					{string}		string				"BadRequest"
+// @Failure		404					{string}		string				"NotFound"
+// @Failure		409					{string}		string				"Conflict, the transition is not allowed or the car is held by someone else"
+// @Failure		422					{string}		string				"Idempotency-Key reused with a different request"
+// @Router		/cars/{id}:transition	[post]
+func (h *carHandler) transition(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ct := r.Header.Get("content-type")
+	if ct != "application/json" {
+		respondWithError(w, http.StatusUnsupportedMediaType, "content type 'application/json' required")
+		return
+	}
+
+	var t statusTransition
+	err = json.Unmarshal(body, &t)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	defer h.Unlock()
+	h.Lock()
+
+	id, _ := customMethod(r)
+	car := Car{Id: id, Dealership: dealershipOf(r.Context())}
+
+	if err := car.checkHold(actorFromRequest(r)); err != nil {
+		respondWithError(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	q, err := car.transitionCar(t, actorFromRequest(r))
+	if err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, q)
+}
+
+// getReservations godoc
+// @Summary		Get the hold on a car
+// @Description	Lists the active reservation of the car, if any
+// @Tags			car
+// @Accept		json
+// @Produce		json
+// @Param		id							path			string			true			"Car Id"
+// @Success		200							{array}			reservation		"OK"
+// @Failure		404							{string}		string			"NotFound"
+// @Router		/cars/{id}/reservations		[get]
+func (h *carHandler) getReservations(w http.ResponseWriter, r *http.Request) {
+	defer h.Unlock()
+	h.Lock()
+
+	car := Car{Id: idFromUrl(r), Dealership: dealershipOf(r.Context())}
+
+	q, err := car.getReservations()
+	if err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, q)
+}
+
+// reserve godoc
+// @Summary		Hold a car for a customer
+// @Description	Reserves an available car for 48 hours, or the given hours up to a week, for the authenticated rep. While the hold is active only the rep who placed it can update, delete or transition the car. It is released automatically when it expires
+// @Tags			car
+// @Accept		json
+// @Produce		json
+// @Param		id							path			string				true			"Car Id"
+// @Param		reservation					body			reservationRequest	true			"Reservation JSON Object"
+// @Param		Idempotency-Key				header			string				false			"Replays the stored response when the request is repeated with the same key"
+// @Success		201							{object}		reservation			"Created"
+// @Failure		400							{string}		string				"BadRequest"
+// @Failure		401							{string}		string				"Unauthorized"
+// @Failure		404							{string}		string				"NotFound"
+// @Failure		409							{string}		string				"Conflict, the car is already held or not available"
+// @Failure		422							{string}		string				"Idempotency-Key reused with a different request"
+// @Router		/cars/{id}/reservations		[post]
+func (h *carHandler) reserve(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ct := r.Header.Get("content-type")
+	if ct != "application/json" {
+		respondWithError(w, http.StatusUnsupportedMediaType, "content type 'application/json' required")
+		return
+	}
+
+	var req reservationRequest
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	defer h.Unlock()
+	h.Lock()
+
+	actor := actorFromRequest(r)
+	if actor == anonymousActor {
+		respondWithError(w, http.StatusUnauthorized, errAuthRequired.Error())
+		return
+	}
+	car := Car{Id: idFromUrl(r), Dealership: dealershipOf(r.Context())}
+
+	q, err := car.reserveCar(req, actor)
+	if err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, q)
+}
+
+// release godoc
+// @Summary		Release a hold on a car
+// @Description	Ends the reservation before it expires and makes the car available again. Only the rep who placed the hold can release it
+// @Tags			car
+// @Accept		json
+// @Produce		json
+// @Param		id										path			string			true			"Car Id"
+// @Param		reservation								path			string			true			"Reservation Id"
+// @Param		Idempotency-Key							header			string			false			"Replays the stored response when the request is repeated with the same key"
+// @Success		200										{object}		Car				"OK"
+// @Failure		401										{string}		string			"Unauthorized"
+// @Failure		403										{string}		string			"Forbidden, the hold belongs to someone else"
+// @Failure		404										{string}		string			"NotFound"
+// @Failure		422										{string}		string			"Idempotency-Key reused with a different request"
+// @Router		/cars/{id}/reservations/{reservation}	[delete]
+func (h *carHandler) release(w http.ResponseWriter, r *http.Request) {
+	defer h.Unlock()
+	h.Lock()
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 5 {
+		respondWithError(w, http.StatusNotFound, "reservation not found")
+		return
+	}
+	actor := actorFromRequest(r)
+	if actor == anonymousActor {
+		respondWithError(w, http.StatusUnauthorized, errAuthRequired.Error())
+		return
+	}
+	car := Car{Id: idFromUrl(r), Dealership: dealershipOf(r.Context())}
+
+	q, err := car.releaseReservation(parts[4], actor)
+	if err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, q)
+}
+
+// getPrices godoc
+// @Summary		Get the price timeline of a car
+// @Description	Lists the prices the car has had, oldest first, and the price changes scheduled for it
+// @Tags			car
+// @Accept		json
+// @Produce		json
+// @Param		id						path			string			true			"Car Id"
+// @Success		200						{object}		priceTimeline	"OK"
+// @Failure		404						{string}		string			"NotFound"
+// @Router		/cars/{id}/prices		[get]
+func (h *carHandler) getPrices(w http.ResponseWriter, r *http.Request) {
+	defer h.Unlock()
+	h.Lock()
+
+	car := Car{Id: idFromUrl(r), Dealership: dealershipOf(r.Context())}
+
+	q, err := car.getCarPrices()
+	if err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, q)
+}
+
+// schedulePrice godoc
+// @Summary		Schedule a price change
+// @Description	Schedules the price of the car to change at effective_at. A background scheduler applies it, keeping the old price as previous_price
+// @Tags			car
+// @Accept		json
+// @Produce		json
+// @Param		id						path			string			true			"Car Id"
+// @Param		price					body			priceChange		true			"Price Change JSON Object"
+// @Param		Idempotency-Key			header			string			false			"Replays the stored response when the request is repeated with the same key"
+// @Success		201						{object}		scheduledPrice	"Created"
+// @Failure		400						{string}		string			"BadRequest"
+// @Failure		404						{string}		string			"NotFound"
+// @Failure		422						{string}		string			"Idempotency-Key reused with a different request"
+// @Failure		409						{string}		string			"Conflict, the car is held by someone else"
+// @Router		/cars/{id}/prices		[post]
+func (h *carHandler) schedulePrice(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ct := r.Header.Get("content-type")
+	if ct != "application/json" {
+		respondWithError(w, http.StatusUnsupportedMediaType, "content type 'application/json' required")
+		return
+	}
+
+	var p priceChange
+	err = json.Unmarshal(body, &p)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	defer h.Unlock()
+	h.Lock()
+
+	car := Car{Id: idFromUrl(r), Dealership: dealershipOf(r.Context())}
+
+	if err := car.checkHold(actorFromRequest(r)); err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	q, err := car.schedulePrice(p, actorFromRequest(r))
+	if err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, q)
+}
+
+// cancelPrice godoc
+// @Summary		Cancel a scheduled price change
+// @Description	Removes a price change that has not been applied yet
+// @Tags			car
+// @Accept		json
+// @Produce		json
+// @Param		id								path			string			true			"Car Id"
+// @Param		schedule						path			string			true			"Scheduled Price Id"
+// @Param		Idempotency-Key					header			string			false			"Replays the stored response when the request is repeated with the same key"
+// @Success		204								{string}		string			"NoContent"
+// @Failure		404								{string}		string			"NotFound"
+// @Failure		422								{string}		string			"Idempotency-Key reused with a different request"
+// @Failure		409								{string}		string			"Conflict, the car is held by someone else"
+// @Router		/cars/{id}/prices/{schedule}	[delete]
+func (h *carHandler) cancelPrice(w http.ResponseWriter, r *http.Request) {
+	defer h.Unlock()
+	h.Lock()
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 5 {
+		respondWithError(w, http.StatusNotFound, "scheduled price not found")
+		return
+	}
+	car := Car{Id: idFromUrl(r), Dealership: dealershipOf(r.Context())}
+
+	if err := car.checkHold(actorFromRequest(r)); err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	_, err := car.cancelScheduledPrice(parts[4])
+	if err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// getMedia godoc
+// @Summary		List the photos of a car
+// @Description	Lists the photos of the car in display order. Each carries the URL of the original upload and of its small, medium and large thumbnails
+// @Tags			media
+// @Accept		json
+// @Produce		json
+// @Param		id						path			string			true			"Car Id"
+// @Success		200						{array}			media			"OK"
+// @Failure		404						{string}		string			"NotFound"
+// @Router		/cars/{id}/media		[get]
+func (h *carHandler) getMedia(w http.ResponseWriter, r *http.Request) {
+	defer h.Unlock()
+	h.Lock()
+
+	car := Car{Id: idFromUrl(r), Dealership: dealershipOf(r.Context())}
+
+	q, err := car.getCarMedia()
+	if err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, q)
+}
+
+// serveMedia godoc
+// @Summary		Download a photo
+// @Description	Returns the original upload. Its thumbnails are served the same way at /cars/{id}/media/{media}/small, /medium and /large
+// @Tags			media
+// @Produce		jpeg,png,gif
+// @Param		id							path			string			true			"Car Id"
+// @Param		media						path			string			true			"Media Id"
+// @Success		200							{file}			file			"OK"
+// @Failure		404							{string}		string			"NotFound"
+// @Router		/cars/{id}/media/{media}	[get]
+func (h *carHandler) serveMedia(w http.ResponseWriter, r *http.Request) {
+	defer h.Unlock()
+	h.Lock()
+
+	id, size := mediaFromUrl(r)
+	car := Car{Id: idFromUrl(r), Dealership: dealershipOf(r.Context())}
+
+	data, contentType, err := car.getMediaBlob(id, size)
+	if err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	w.Header().Set("content-type", contentType)
+	w.Header().Set("content-length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// uploadMedia godoc
+// @Summary		Upload a photo
+// @Description	Adds a JPEG, PNG or GIF photo after the other photos of the car and generates its thumbnails. The type is sniffed from the content, not taken from the request. The first photo of a car becomes its primary photo
+// @Tags			media
+// @Accept		mpfd
+// @Produce		json
+// @Param		id						path			string			true			"Car Id"
+// @Param		file					formData		file			true			"Photo"
+// @Param		Idempotency-Key			header			string			false			"Replays the stored response when the request is repeated with the same key"
+// @Success		201						{object}		media			"Created"
+// @Failure		400						{string}		string			"BadRequest"
+// @Failure		404						{string}		string			"NotFound"
+// @Failure		413						{string}		string			"Photo larger than MEDIA_MAX_BYTES or 40 megapixels"
+// @Failure		415						{string}		string			"UnsupportedMediaType"
+// @Failure		409						{string}		string			"Conflict, the car is held by someone else"
+// @Router		/cars/{id}/media		[post]
+func (h *carHandler) uploadMedia(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	parts, err := r.MultipartReader()
+	if err != nil {
+		respondWithError(w, http.StatusUnsupportedMediaType, "content type 'multipart/form-data' required")
+		return
+	}
+
+	var data []byte
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+		// Read one byte past the limit so oversized uploads are rejected.
+		data, err = ioutil.ReadAll(io.LimitReader(part, maxMediaBytes+1))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		break
+	}
+
+	// Decoding is slow, so it is done before taking the lock.
+	car := Car{Id: idFromUrl(r), Dealership: dealershipOf(r.Context())}
+	decoded, err := car.decodeMedia(data)
+	if err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	defer h.Unlock()
+	h.Lock()
+
+	if err := car.checkHold(actorFromRequest(r)); err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	q, err := car.uploadMedia(decoded, actorFromRequest(r))
+	if err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, q)
+}
+
+// updateMedia godoc
+// @Summary		Reorder a photo or make it primary
+// @Description	Moves the photo to position, counted from 0, and makes it the primary photo of the car when primary is true
+// @Tags			media
+// @Accept		json
+// @Produce		json
+// @Param		id							path			string			true			"Car Id"
+// @Param		media						path			string			true			"Media Id"
+// @Param		update						body			mediaUpdate		true			"Media Update JSON Object"
+// @Param		Idempotency-Key				header			string			false			"Replays the stored response when the request is repeated with the same key"
+// @Success		200							{object}		media			"OK"
+// @Failure		400							{string}		string			"BadRequest"
+// @Failure		404							{string}		string			"NotFound"
+// @Failure		409							{string}		string			"Conflict, the car is held by someone else"
+// @Router		/cars/{id}/media/{media}	[patch]
+func (h *carHandler) updateMedia(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ct := r.Header.Get("content-type")
+	if ct != "application/json" {
+		respondWithError(w, http.StatusUnsupportedMediaType, "content type 'application/json' required")
+		return
+	}
+
+	var u mediaUpdate
+	err = json.Unmarshal(body, &u)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	defer h.Unlock()
+	h.Lock()
+
+	id, _ := mediaFromUrl(r)
+	car := Car{Id: idFromUrl(r), Dealership: dealershipOf(r.Context())}
+
+	if err := car.checkHold(actorFromRequest(r)); err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	q, err := car.updateMedia(id, u)
+	if err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, q)
+}
+
+// deleteMedia godoc
+// @Summary		Delete a photo
+// @Description	Removes the photo and its thumbnails. When it was the primary photo, the first remaining photo becomes primary
+// @Tags			media
+// @Accept		json
+// @Produce		json
+// @Param		id							path			string			true			"Car Id"
+// @Param		media						path			string			true			"Media Id"
+// @Param		Idempotency-Key				header			string			false			"Replays the stored response when the request is repeated with the same key"
+// @Success		204							{string}		string			"NoContent"
+// @Failure		404							{string}		string			"NotFound"
+// @Failure		409							{string}		string			"Conflict, the car is held by someone else"
+// @Router		/cars/{id}/media/{media}	[delete]
+func (h *carHandler) deleteMedia(w http.ResponseWriter, r *http.Request) {
+	defer h.Unlock()
+	h.Lock()
+
+	id, _ := mediaFromUrl(r)
+	car := Car{Id: idFromUrl(r), Dealership: dealershipOf(r.Context())}
+
+	if err := car.checkHold(actorFromRequest(r)); err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	_, err := car.deleteMedia(id)
+	if err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// batch godoc
+// @Summary		Apply a batch of car operations
+// @Description	Applies an ordered list of create, update and delete operations. Creates that look like a listed car fail with 409 and the possible duplicates unless allow_duplicate is set. In atomic mode (the default) either every operation is applied or none is, and the operations that did not fail report 424. In best_effort mode every operation that can be applied is. Each result carries the status code the equivalent single request would have returned
+// @Tags			car
+// @Accept		json
+// @Produce		json
+// @Param		batch			body			batchRequest	true			"Batch JSON Object"
+// @Param		Idempotency-Key	header			string			false			"Replays the stored response when the request is repeated with the same key"
+// @Success		200				{object}		batchResponse	"Committed"
+// @Failure		400				{object}		batchResponse	"BadRequest, or the atomic batch was rolled back"
+// @Failure		404				{object}		batchResponse	"NotFound, the atomic batch was rolled back"
+// @Failure		409				{object}		batchResponse	"Conflict, the atomic batch was rolled back"
+// @Router		/cars:batch		[post]
+func (h *carHandler) batch(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ct := r.Header.Get("content-type")
+	if ct != "application/json" {
+		respondWithError(w, http.StatusUnsupportedMediaType, "content type 'application/json' required")
+		return
+	}
+
+	var b batchRequest
+	err = json.Unmarshal(body, &b)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	defer h.Unlock()
+	h.Lock()
+
+	car := Car{Dealership: dealershipOf(r.Context())}
+	q, err := car.applyBatch(b, actorFromRequest(r))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !q.Committed {
+		// Report the first failure, as the single request would have.
+		for _, v := range q.Results {
+			if v.Status != http.StatusFailedDependency {
+				respondWithJSON(w, v.Status, q)
+				return
+			}
+		}
+	}
+	respondWithJSON(w, http.StatusOK, q)
+}
+
 func respondWithError(w http.ResponseWriter, code int, msg string) {
 	respondWithJSON(w, code, map[string]string{"error": msg})
 }
@@ -204,7 +1299,7 @@ func respondWithJSON(w http.ResponseWriter, code int, data interface{}) {
 }
 
 func idFromUrl(r *http.Request) (string) {
-	parts := strings.Split(r.URL.String(), "/")
+	parts := strings.Split(r.URL.Path, "/")
 
 	if len(parts) < 3 {
 		return "-1"
@@ -227,9 +1322,58 @@ func idFromUrl(r *http.Request) (string) {
 	return id
 }
 
+// actionFromUrl returns the path segment following the car id, e.g.
+// "history" in /cars/{id}/history, or "" when there is none.
+func actionFromUrl(r *http.Request) string {
+	parts := strings.Split(r.URL.Path, "/")
+
+	if len(parts) < 4 {
+		return ""
+	}
+
+	return parts[3]
+}
+
+// mediaFromUrl returns the media id and thumbnail size in
+// /cars/{id}/media/{media}/{size}. Either is "" when missing.
+func mediaFromUrl(r *http.Request) (id string, size string) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	if len(parts) > 4 {
+		id = parts[4]
+	}
+	if len(parts) > 5 {
+		size = parts[5]
+	}
+	return id, size
+}
+
+// customMethod splits a path segment such as "{id}:restore" into the id and
+// the custom method name. method is "" for plain ids.
+func customMethod(r *http.Request) (id string, method string) {
+	id = idFromUrl(r)
+	if i := strings.LastIndex(id, ":"); i != -1 {
+		return id[:i], id[i+1:]
+	}
+	return id, ""
+}
+
+// anonymousActor is recorded for requests made without credentials.
+const anonymousActor = "anonymous"
+
+// actorFromRequest identifies who made the request, for audit fields and
+// holds: the authenticated basic auth user, or anonymousActor.
+func actorFromRequest(r *http.Request) string {
+	user, err := userFromRequest(r)
+	if err != nil || user == "" {
+		return anonymousActor
+	}
+	return user
+}
+
 /*
 func splitUrlParameters(r *http.Request) ([]string){
-	parts := strings.Split(r.URL.String(), "/")
+	parts := strings.Split(r.URL.Path, "/")
 	return parts
 }
 */
\ No newline at end of file
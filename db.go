@@ -1,9 +1,26 @@
 package main
 
-import "fmt"
+import (
+	"errors"
+	"time"
+)
+
+// Errors looking up a car by id.
+var (
+	errIdNotFound = errors.New("id not found")
+	errIdExists   = errors.New("id already exists")
+)
 
 type Db struct {
-	cars []Car
+	cars     []Car
+	index    searchIndex
+	history  carHistory
+	trash    carTrash
+	holds    carHolds
+	prices   priceSchedule
+	media    carMedia
+	webhooks []webhookSubscription
+	wal      *wal
 }
 
 func (db *Db) getAll() ([]Car, error) {
@@ -18,27 +35,39 @@ func (db *Db) getById(id string) (Car, error) {
 		}
 	}
 
-	return Car{}, fmt.Errorf("id not found")
+	return Car{}, errIdNotFound
 }
 
 func (db *Db) add(c *Car) (Car, error){
-	car := Car{Id: c.Id, Make: c.Make, Model: c.Model, Package: c.Package, Color: c.Color, Year: c.Year, Category: c.Category, Mileage: c.Mileage, Price: c.Price}
+	car := Car{Id: c.Id, Make: c.Make, Model: c.Model, Package: c.Package, Color: c.Color, Year: c.Year, Category: c.Category, Mileage: c.Mileage, Price: c.Price, MileageUnit: c.MileageUnit, Dealership: c.Dealership, Status: c.Status, Vin: c.Vin, Country: c.Country, VinWarnings: c.VinWarnings}
+	if car.Status == "" {
+		car.Status = statusAvailable
+	}
 
 	for _, v := range db.cars{
 		if car.Id == v.Id {
-			return car, fmt.Errorf("id already exists")
+			return car, errIdExists
 		}
 	}
 
+	if err := db.wal.append(walRecord{Op: opAdd, Car: car}); err != nil {
+		return Car{}, err
+	}
+
 	db.cars = append(db.cars, car)
+	db.index.add(car)
+	db.history.record(actionCreated, car)
 	return car, nil
 }
 
 func (db *Db) update(c *Car) (Car, error) {
-	car := Car{Id: c.Id, Make: c.Make, Model: c.Model, Package: c.Package, Color: c.Color, Year: c.Year, Category: c.Category, Mileage: c.Mileage, Price: c.Price}
+	car := Car{Id: c.Id, Make: c.Make, Model: c.Model, Package: c.Package, Color: c.Color, Year: c.Year, Category: c.Category, Mileage: c.Mileage, Price: c.Price, MileageUnit: c.MileageUnit, Dealership: c.Dealership, Vin: c.Vin, Country: c.Country, VinWarnings: c.VinWarnings}
 	
 	for i, v := range db.cars{
 		if v.Id == car.Id {
+			if err := db.wal.append(walRecord{Op: opUpdate, Car: car}); err != nil {
+				return Car{}, err
+			}
 			db.cars[i].Make = car.Make
 			db.cars[i].Model = car.Model
 			db.cars[i].Package = car.Package
@@ -46,14 +75,23 @@ func (db *Db) update(c *Car) (Car, error) {
 			db.cars[i].Year = car.Year
 			db.cars[i].Category = car.Category
 			db.cars[i].Mileage = car.Mileage
-			db.cars[i].Price = car.Price
-			return car, nil 
+			db.setPrice(i, car.Price)
+			db.cars[i].MileageUnit = car.MileageUnit
+			db.cars[i].Vin = car.Vin
+			db.cars[i].Country = car.Country
+			db.cars[i].VinWarnings = car.VinWarnings
+			db.index.remove(car.Id)
+			db.index.add(db.cars[i])
+			db.history.record(actionUpdated, db.cars[i])
+			return db.cars[i], nil
 		}
 	}
-	return Car{}, fmt.Errorf("id not found")
+	return Car{}, errIdNotFound
 }
 
-func (db *Db) delete(id string) (Car, error){
+// delete moves the car to the trash, from where it can be restored until
+// it is purged.
+func (db *Db) delete(id, actor string) (Car, error){
 	index := -1
 
 	for i, v := range db.cars {
@@ -63,12 +101,110 @@ func (db *Db) delete(id string) (Car, error){
 	}
 
 	if index != -1 {
+		if err := db.wal.append(walRecord{Op: opDelete, Id: id, Actor: actor}); err != nil {
+			return Car{}, err
+		}
+		car := db.cars[index]
+		db.holds.remove(id)
+		delete(db.prices.byCar, id)
+		db.history.record(actionDeleted, car)
+		db.trash.put(car, actor)
 		if index < len(db.cars)-1 {
 			db.cars[len(db.cars)-1], db.cars[index] = db.cars[index], db.cars[len(db.cars)-1]
 		}
 		db.cars = db.cars[:len(db.cars)-1]
-		return Car{}, nil
+		db.index.remove(id)
+		return car, nil
+	}
+
+	return Car{}, errIdNotFound
+}
+
+// transition moves the car to status, recording who moved it and why.
+func (db *Db) transition(id, status, actor, reason string) (Car, error) {
+	for i, v := range db.cars {
+		if v.Id == id {
+			if err := canTransition(v.Status, status); err != nil {
+				return Car{}, err
+			}
+			if err := db.wal.append(walRecord{Op: opTransition, Id: id, Status: status, Actor: actor, Reason: reason}); err != nil {
+				return Car{}, err
+			}
+			db.cars[i].Status = status
+			if status != statusReserved {
+				db.holds.remove(id)
+			}
+			db.history.recordBy(actionTransitioned, db.cars[i], actor, reason)
+			return db.cars[i], nil
+		}
+	}
+
+	return Car{}, errIdNotFound
+}
+
+func (db *Db) search(q string) ([]searchResult, error) {
+	return db.index.search(q, db.cars), nil
+}
+
+func (db *Db) getHistory(id string) ([]carVersion, error) {
+	return db.history.list(id)
+}
+
+func (db *Db) getAsOf(id string, t time.Time) (Car, error) {
+	return db.history.asOf(id, t)
+}
+
+func (db *Db) getTrash() ([]trashedCar, error) {
+	return db.trash.cars, nil
+}
+
+func (db *Db) restore(id string) (Car, error) {
+	for _, v := range db.cars {
+		if v.Id == id {
+			return Car{}, errIdExists
+		}
 	}
 
-	return Car{}, fmt.Errorf("id not found")
+	if !db.trash.contains(id) {
+		return Car{}, errIdNotFound
+	}
+
+	if err := db.wal.append(walRecord{Op: opRestore, Id: id}); err != nil {
+		return Car{}, err
+	}
+
+	t, err := db.trash.take(id)
+	if err != nil {
+		return Car{}, err
+	}
+
+	// Deleting the car dropped its hold, so it is no longer reserved.
+	if _, held := db.holds.get(id); t.Car.Status == statusReserved && !held {
+		t.Car.Status = statusAvailable
+	}
+	db.cars = append(db.cars, t.Car)
+	db.index.add(t.Car)
+	db.history.record(actionRestored, t.Car)
+	return t.Car, nil
+}
+
+func (db *Db) purge(cutoff time.Time) (int, error) {
+	if db.trash.expired(cutoff) == 0 {
+		return 0, nil
+	}
+
+	if err := db.wal.append(walRecord{Op: opPurge, Cutoff: cutoff}); err != nil {
+		return 0, err
+	}
+
+	for _, v := range db.trash.cars {
+		if v.DeletedAt.Before(cutoff) {
+			db.dropMedia(v.Car.Id)
+			// A car created since under the same id keeps the history.
+			if _, err := db.getById(v.Car.Id); err != nil {
+				db.history.drop(v.Car.Id)
+			}
+		}
+	}
+	return db.trash.purge(cutoff), nil
 }
\ No newline at end of file
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultDealership holds the cars of requests that name no dealership.
+// Its store is the global db.
+const defaultDealership = "default"
+
+var validDealership = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Errors authenticating a user or resolving their dealership.
+var (
+	errDealershipNotFound   = errors.New("dealership not found")
+	errInvalidCredentials   = errors.New("invalid credentials")
+	errAuthRequired         = errors.New("authentication required")
+	errDealershipNotAllowed = errors.New("dealership not allowed")
+)
+
+// dealershipRegistry holds the isolated inventory of every dealership and
+// who may access them. Users authenticate with their password and map to
+// their home dealership; group admins may act on any dealership and read
+// across all of them.
+type dealershipRegistry struct {
+	sync.Mutex
+	dbs    map[string]*Db
+	users  map[string]dealershipUser
+	admins map[string]bool
+}
+
+// dealershipUser is the password and home dealership of a user.
+type dealershipUser struct {
+	password string
+	home     string
+}
+
+var dealerships = dealershipRegistry{dbs: map[string]*Db{defaultDealership: &db}}
+
+// open registers the dealerships and loads their stores. The default
+// dealership lives in dir and the others in dir/dealerships/<name>.
+func (reg *dealershipRegistry) open(dir string, names []string) error {
+	if err := db.open(dir); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if name == defaultDealership {
+			continue
+		}
+		if !validDealership.MatchString(name) {
+			return fmt.Errorf("dealership names must be lowercase letters, digits and dashes: %q", name)
+		}
+		d := &Db{}
+		if err := d.open(filepath.Join(dir, "dealerships", name)); err != nil {
+			return fmt.Errorf("dealership %s: %v", name, err)
+		}
+		reg.add(name, d)
+	}
+	return nil
+}
+
+func (reg *dealershipRegistry) add(name string, d *Db) {
+	defer reg.Unlock()
+	reg.Lock()
+
+	reg.dbs[name] = d
+}
+
+func (reg *dealershipRegistry) get(name string) (*Db, error) {
+	defer reg.Unlock()
+	reg.Lock()
+
+	d, ok := reg.dbs[name]
+	if !ok {
+		return nil, errDealershipNotFound
+	}
+	return d, nil
+}
+
+// names lists the dealerships in order.
+func (reg *dealershipRegistry) names() []string {
+	defer reg.Unlock()
+	reg.Lock()
+
+	names := []string{}
+	for name := range reg.dbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// each calls f with the store of every dealership, in order.
+func (reg *dealershipRegistry) each(f func(name string, d *Db)) {
+	for _, name := range reg.names() {
+		d, _ := reg.get(name)
+		f(name, d)
+	}
+}
+
+func (reg *dealershipRegistry) isAdmin(user string) bool {
+	defer reg.Unlock()
+	reg.Lock()
+
+	return user != "" && reg.admins[user]
+}
+
+// authenticate checks the password of user, in constant time.
+func (reg *dealershipRegistry) authenticate(user, password string) error {
+	reg.Lock()
+	u, ok := reg.users[user]
+	reg.Unlock()
+
+	want := sha256.Sum256([]byte(u.password))
+	got := sha256.Sum256([]byte(password))
+	if subtle.ConstantTimeCompare(want[:], got[:]) != 1 || !ok {
+		return errInvalidCredentials
+	}
+	return nil
+}
+
+// resolve picks the dealership of a request from the authenticated user
+// and the dealership it asks for. Only an authenticated user may ask for
+// a dealership, and only for their home one unless they are a group
+// admin. Without one the request goes to the user's home dealership, or
+// the default one when anonymous.
+func (reg *dealershipRegistry) resolve(user, requested string) (string, error) {
+	reg.Lock()
+	u, known := reg.users[user]
+	admin := user != "" && reg.admins[user]
+	reg.Unlock()
+
+	if requested == "" {
+		if known {
+			return u.home, nil
+		}
+		return defaultDealership, nil
+	}
+	if !known {
+		return "", errAuthRequired
+	}
+	if requested != u.home && !admin {
+		return "", errDealershipNotAllowed
+	}
+	if _, err := reg.get(requested); err != nil {
+		return "", err
+	}
+	return requested, nil
+}
+
+// parseDealershipUsers reads "user:password=dealership" entries separated
+// by commas, as in DEALERSHIP_USERS.
+func parseDealershipUsers(v string) (map[string]dealershipUser, error) {
+	users := map[string]dealershipUser{}
+	for _, entry := range splitList(v) {
+		i, j := strings.Index(entry, ":"), strings.LastIndex(entry, "=")
+		if i <= 0 || j < i+2 || !validDealership.MatchString(entry[j+1:]) {
+			return nil, fmt.Errorf("DEALERSHIP_USERS entries must be user:password=dealership")
+		}
+		users[entry[:i]] = dealershipUser{password: entry[i+1 : j], home: entry[j+1:]}
+	}
+	return users, nil
+}
+
+// dealershipStatus is the HTTP status for a failed authentication or
+// resolve.
+func dealershipStatus(err error) int {
+	switch {
+	case errors.Is(err, errDealershipNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errInvalidCredentials), errors.Is(err, errAuthRequired):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusForbidden
+	}
+}
+
+// userFromRequest returns the user authenticated by the basic auth
+// credentials of r, or "" when there are none. Wrong credentials fail
+// rather than fall back to anonymous.
+func userFromRequest(r *http.Request) (string, error) {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return "", nil
+	}
+	if err := dealerships.authenticate(user, password); err != nil {
+		return "", err
+	}
+	return user, nil
+}
+
+func dealershipFromRequest(r *http.Request) (string, error) {
+	user, err := userFromRequest(r)
+	if err != nil {
+		return "", err
+	}
+	return dealerships.resolve(user, r.Header.Get("X-Dealership"))
+}
+
+// dealershipKey carries the resolved dealership of a request.
+const dealershipKey contextKey = "dealership"
+
+func withDealership(r *http.Request, dealership string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), dealershipKey, dealership))
+}
+
+// dealershipOf returns the dealership resolved for the request, or the
+// default one.
+func dealershipOf(ctx context.Context) string {
+	if d, ok := ctx.Value(dealershipKey).(string); ok && d != "" {
+		return d
+	}
+	return defaultDealership
+}
+
+// orDefault treats an empty dealership as the default one.
+func orDefault(dealership string) string {
+	if dealership == "" {
+		return defaultDealership
+	}
+	return dealership
+}
+
+// store returns the inventory of the car's dealership, defaulting an
+// empty dealership.
+func (c *Car) store() (*Db, error) {
+	c.Dealership = orDefault(c.Dealership)
+	return dealerships.get(c.Dealership)
+}
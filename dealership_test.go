@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func dealershipSetup() {
+	dealerships.add("north", &Db{})
+	dealerships.add("south", &Db{})
+
+	dealerships.Lock()
+	dealerships.users = map[string]dealershipUser{"nancy": {password: "secret", home: "north"}, "gail": {password: "secret", home: "default"}}
+	dealerships.admins = map[string]bool{"gail": true}
+	dealerships.Unlock()
+}
+
+// addTestUser registers user with the password "secret" and the default
+// dealership as their home.
+func addTestUser(user string) {
+	dealerships.Lock()
+	defer dealerships.Unlock()
+	if dealerships.users == nil {
+		dealerships.users = map[string]dealershipUser{}
+	}
+	dealerships.users[user] = dealershipUser{password: "secret", home: defaultDealership}
+}
+
+func authAs(r *http.Request, user string) {
+	addTestUser(user)
+	r.SetBasicAuth(user, "secret")
+}
+
+func dealershipDo(method, path, user, dealership, body string) *httptest.ResponseRecorder {
+	return dealershipDoAs(method, path, user, "secret", dealership, body)
+}
+
+func dealershipDoAs(method, path, user, password, dealership, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("content-type", "application/json")
+	if user != "" {
+		r.SetBasicAuth(user, password)
+	}
+	if dealership != "" {
+		r.Header.Set("X-Dealership", dealership)
+	}
+	w := httptest.NewRecorder()
+	if strings.HasPrefix(path, "/group/") {
+		(&groupHandler{cars: &carHandler{}}).ServeHTTP(w, r)
+	} else {
+		(&carHandler{}).ServeHTTP(w, r)
+	}
+	return w
+}
+
+func TestDealership_WhenSameIdInTwoDealerships(t *testing.T){
+	dealershipSetup()
+
+	w := dealershipDo("POST", "/cars", "gail", "north", batchCarJSON("dealercar"))
+	assert.Equal(t, w.Code, http.StatusCreated)
+	w = dealershipDo("POST", "/cars", "gail", "south", strings.Replace(batchCarJSON("dealercar"), "Nissan", "Volga", 1))
+	assert.Equal(t, w.Code, http.StatusCreated)
+
+	var car Car
+	w = dealershipDo("GET", "/cars/dealercar", "gail", "north", "")
+	json.Unmarshal(w.Body.Bytes(), &car)
+	assert.Equal(t, car.Dealership, "north")
+	assert.Equal(t, car.Make, "Nissan")
+
+	w = dealershipDo("GET", "/cars/dealercar", "gail", "south", "")
+	json.Unmarshal(w.Body.Bytes(), &car)
+	assert.Equal(t, car.Dealership, "south")
+	assert.Equal(t, car.Make, "Volga")
+
+	w = dealershipDo("GET", "/cars/dealercar", "", "", "")
+	assert.Equal(t, w.Code, http.StatusNotFound)
+}
+
+func TestDealership_WhenUserHasHomeDealership(t *testing.T){
+	dealershipSetup()
+
+	w := dealershipDo("POST", "/cars", "nancy", "", batchCarJSON("homecar"))
+	assert.Equal(t, w.Code, http.StatusCreated)
+
+	north, _ := dealerships.get("north")
+	_, err := north.getById("homecar")
+	assert.Equal(t, err, nil)
+}
+
+func TestDealership_WhenUserAsksForAnotherDealership(t *testing.T){
+	dealershipSetup()
+
+	w := dealershipDo("GET", "/cars", "nancy", "south", "")
+	assert.Equal(t, w.Code, http.StatusForbidden)
+	assert.Equal(t, strings.Contains(w.Body.String(), "dealership not allowed"), true)
+
+	w = dealershipDo("GET", "/cars", "gail", "south", "")
+	assert.Equal(t, w.Code, http.StatusOK)
+}
+
+func TestDealership_WhenAnonymousAsksForDealership(t *testing.T){
+	dealershipSetup()
+
+	w := dealershipDo("GET", "/cars", "", "north", "")
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+	assert.Equal(t, strings.Contains(w.Body.String(), "authentication required"), true)
+
+	w = dealershipDo("POST", "/cars", "", "north", batchCarJSON("anoncar"))
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+	north, _ := dealerships.get("north")
+	_, err := north.getById("anoncar")
+	assert.NotEqual(t, err, nil)
+}
+
+func TestDealership_WhenPasswordIsWrong(t *testing.T){
+	dealershipSetup()
+
+	w := dealershipDoAs("GET", "/cars", "nancy", "guess", "north", "")
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+	assert.Equal(t, strings.Contains(w.Body.String(), "invalid credentials"), true)
+
+	w = dealershipDoAs("GET", "/cars", "nancy", "guess", "", "")
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+
+	w = dealershipDoAs("GET", "/cars", "mallory", "secret", "", "")
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+}
+
+func TestDealership_WhenDealershipIsUnknown(t *testing.T){
+	dealershipSetup()
+
+	w := dealershipDo("GET", "/cars", "gail", "atlantis", "")
+	assert.Equal(t, w.Code, http.StatusNotFound)
+	assert.Equal(t, strings.Contains(w.Body.String(), "dealership not found"), true)
+}
+
+func TestGroup_WhenAdminListsCars(t *testing.T){
+	dealershipSetup()
+	dealershipDo("POST", "/cars", "gail", "north", batchCarJSON("groupcar1"))
+	dealershipDo("POST", "/cars", "gail", "south", batchCarJSON("groupcar2"))
+
+	var cars []Car
+	w := dealershipDo("GET", "/group/cars?make=Nissan", "gail", "", "")
+	json.Unmarshal(w.Body.Bytes(), &cars)
+	assert.Equal(t, w.Code, http.StatusOK)
+	dealershipOfId := map[string]string{}
+	for _, c := range cars {
+		dealershipOfId[c.Id] = c.Dealership
+	}
+	assert.Equal(t, dealershipOfId["groupcar1"], "north")
+	assert.Equal(t, dealershipOfId["groupcar2"], "south")
+
+	cars = nil
+	w = dealershipDo("GET", "/group/cars?dealership=south", "gail", "", "")
+	json.Unmarshal(w.Body.Bytes(), &cars)
+	assert.Equal(t, len(cars), 1)
+	assert.Equal(t, cars[0].Id, "groupcar2")
+	assert.Equal(t, cars[0].Dealership, "south")
+}
+
+func TestGroup_WhenUserIsNotAdmin(t *testing.T){
+	dealershipSetup()
+
+	w := dealershipDo("GET", "/group/cars", "nancy", "", "")
+	assert.Equal(t, w.Code, http.StatusForbidden)
+}
+
+func TestGroup_WhenAdminPasswordIsWrong(t *testing.T){
+	dealershipSetup()
+
+	w := dealershipDoAs("GET", "/group/cars", "gail", "guess", "", "")
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+
+	w = dealershipDo("GET", "/group/cars", "", "", "")
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+}
+
+func TestParseDealershipUsers_WhenValid(t *testing.T){
+	users, err := parseDealershipUsers("nancy:pa:ss=word=north, gail:secret=default")
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, users["nancy"], dealershipUser{password: "pa:ss=word", home: "north"})
+	assert.Equal(t, users["gail"], dealershipUser{password: "secret", home: "default"})
+
+	for _, v := range []string{"nancy=north", ":secret=north", "nancy:=north", "nancy:secret="} {
+		_, err = parseDealershipUsers(v)
+		assert.NotEqual(t, err, nil, v)
+	}
+}
@@ -36,6 +36,96 @@ const docTemplate = `{
                     "car"
                 ],
                 "summary": "Get all cars",
+                "parameters": [
+                    {
+                        "enum": [
+                            "mi",
+                            "km"
+                        ],
+                        "type": "string",
+                        "description": "Odometer units of the response and mileage filters",
+                        "name": "units",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by make",
+                        "name": "make",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by model",
+                        "name": "model",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by color",
+                        "name": "color",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by status, comma separated: incoming, in_transit, available, reserved, sold",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum year",
+                        "name": "min_year",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum year",
+                        "name": "max_year",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum price",
+                        "name": "min_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Maximum price",
+                        "name": "max_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum mileage, in units",
+                        "name": "min_mileage",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Maximum mileage, in units",
+                        "name": "max_mileage",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort field, prefix with - for descending",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "List the cars in the trash instead, as trashedCar objects",
+                        "name": "deleted",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -45,11 +135,17 @@ const docTemplate = `{
                                 "$ref": "#/definitions/main.Car"
                             }
                         }
+                    },
+                    "400": {
+                        "description": "BadRequest",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             },
             "put": {
-                "description": "Updates an existing car from the database corresponding to the id sent. Otherwise, returns error",
+                "description": "Updates an existing car from the database corresponding to the id sent. The status is kept; it is changed with :transition. Otherwise, returns error",
                 "consumes": [
                     "application/json"
                 ],
@@ -69,6 +165,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.Car"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Replays the stored response when the request is repeated with the same key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -89,11 +191,23 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "409": {
+                        "description": "Conflict, the car is held by someone else",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reused with a different request",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             },
             "post": {
-                "description": "Creates a new car in the database. In case of existing id returns error",
+                "description": "Creates a new car in the database. New cars start incoming, in_transit or available, the default; the status is then changed with :transition, and a car becomes reserved only by holding it through /cars/{id}/reservations. When a vin is given its check digit is validated, empty make, year and country are filled from it, and submitted values that disagree with it are listed in vin_warnings. A car with the vin of a listed car, or without a vin but with the make, model, year and color and a similar mileage of one, is rejected with the possible duplicates unless allow_duplicate is true. In case of existing id returns error",
                 "consumes": [
                     "application/json"
                 ],
@@ -113,6 +227,18 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.Car"
                         }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Create the car even if it looks like a listed one",
+                        "name": "allow_duplicate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Replays the stored response when the request is repeated with the same key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -127,13 +253,25 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "409": {
+                        "description": "Conflict, the car looks like a listed one",
+                        "schema": {
+                            "$ref": "#/definitions/main.duplicateError"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reused with a different request",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
-        "/cars/{id}": {
+        "/cars/duplicates": {
             "get": {
-                "description": "Gets a single car from the database corresponding to the id in the path. Otherwise, returns error",
+                "description": "Groups the cars that look like the same vehicle: the same vin, or, when either has no vin, the same make, model, year and color with a similar mileage. Names are compared ignoring case and punctuation, allowing one typo",
                 "consumes": [
                     "application/json"
                 ],
@@ -143,33 +281,87 @@ const docTemplate = `{
                 "tags": [
                     "car"
                 ],
-                "summary": "Get a car",
+                "summary": "Report duplicate listings",
                 "parameters": [
                     {
+                        "enum": [
+                            "mi",
+                            "km"
+                        ],
                         "type": "string",
-                        "description": "Car Id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Odometer units of the response",
+                        "name": "units",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.Car"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.duplicateGroup"
+                            }
                         }
                     },
-                    "404": {
-                        "description": "NotFound",
+                    "400": {
+                        "description": "BadRequest",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Deletes an existing car from the database corresponding to the id in the path. Otherwise, returns error",
+            }
+        },
+        "/cars/events": {
+            "get": {
+                "description": "Streams car.created, car.updated, car.deleted and car.restored events as Server-Sent Events. Clients reconnecting with Last-Event-ID receive the events they missed from a bounded replay buffer; if some were already evicted, or the id is from before the server restarted, a \"reset\" event is sent first and the client should refetch the listing",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "car"
+                ],
+                "summary": "Stream car events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only events for cars of this make",
+                        "name": "make",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events for cars of this category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Id of the last event received",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.carEvent"
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/cars/facets": {
+            "get": {
+                "description": "Counts cars per make, category, color and year, and buckets price and mileage into ranges. Accepts the same filters as the car listing",
                 "consumes": [
                     "application/json"
                 ],
@@ -179,64 +371,2989 @@ const docTemplate = `{
                 "tags": [
                     "car"
                 ],
-                "summary": "Delete a car",
+                "summary": "Get inventory facets",
                 "parameters": [
                     {
+                        "enum": [
+                            "mi",
+                            "km"
+                        ],
                         "type": "string",
-                        "description": "Car Id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Odometer units of the mileage buckets and filters",
+                        "name": "units",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by make",
+                        "name": "make",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by model",
+                        "name": "model",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by color",
+                        "name": "color",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by status, comma separated: incoming, in_transit, available, reserved, sold",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum year",
+                        "name": "min_year",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum year",
+                        "name": "max_year",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum price",
+                        "name": "min_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Maximum price",
+                        "name": "max_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum mileage, in units",
+                        "name": "min_mileage",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Maximum mileage, in units",
+                        "name": "max_mileage",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Width of the price buckets",
+                        "name": "price_interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Width of the mileage buckets, in units",
+                        "name": "mileage_interval",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "NoContent",
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.facets"
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
                         "schema": {
                             "type": "string"
                         }
+                    }
+                }
+            }
+        },
+        "/cars/search": {
+            "get": {
+                "description": "Full-text search over make, model, package, color and category. Matching is case-insensitive and tolerates small typos; results are ranked by relevance and matched words are wrapped in \u003cem\u003e tags",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "car"
+                ],
+                "summary": "Search cars",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search terms",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
                     },
-                    "404": {
-                        "description": "NotFound",
+                    {
+                        "enum": [
+                            "mi",
+                            "km"
+                        ],
+                        "type": "string",
+                        "description": "Odometer units of the response",
+                        "name": "units",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.searchResult"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "main.Car": {
-            "description": "car information",
-            "type": "object",
-            "properties": {
-                "category": {
-                    "type": "string"
-                },
-                "color": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "string"
-                },
-                "make": {
-                    "type": "string"
-                },
-                "mileage": {
-                    "type": "number"
-                },
-                "model": {
-                    "type": "string"
-                },
-                "package": {
-                    "type": "string"
-                },
-                "price": {
-                    "type": "number"
-                },
-                "year": {
-                    "type": "integer"
+        },
+        "/cars/stats": {
+            "get": {
+                "description": "Groups the filtered cars by the group_by fields and computes count, sum, min, max, mean, median and percentiles of the requested numeric fields. Accepts the same filters as the car listing",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "car"
+                ],
+                "summary": "Get inventory statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields to group by: make, model, package, color, category, year",
+                        "name": "group_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated numeric fields to aggregate: price, mileage, year. Defaults to price,mileage",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated percentiles between 0 and 100. Defaults to 25,50,75,90",
+                        "name": "percentiles",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "mi",
+                            "km"
+                        ],
+                        "type": "string",
+                        "description": "Odometer units of the mileage aggregates and filters",
+                        "name": "units",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by make",
+                        "name": "make",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by model",
+                        "name": "model",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by color",
+                        "name": "color",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by status, comma separated: incoming, in_transit, available, reserved, sold",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum year",
+                        "name": "min_year",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum year",
+                        "name": "max_year",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum price",
+                        "name": "min_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Maximum price",
+                        "name": "max_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum mileage, in units",
+                        "name": "min_mileage",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Maximum mileage, in units",
+                        "name": "max_mileage",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.statsGroup"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/cars/valuation": {
+            "get": {
+                "description": "Estimates the market price of a car by least squares regression of price on year and mileage over the priced cars of the same make and model. With fewer than three of those, cars of the same make and category, then of the same category, are used instead. Returns the estimate, a 95% range and the comparables used",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "car"
+                ],
+                "summary": "Value a car",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Make",
+                        "name": "make",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Model",
+                        "name": "model",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Category, to fall back on when the model has too few comparables",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Year",
+                        "name": "year",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Mileage, in units",
+                        "name": "mileage",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "mi",
+                            "km"
+                        ],
+                        "type": "string",
+                        "description": "Odometer units of the mileage and the response",
+                        "name": "units",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.valuation"
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "UnprocessableEntity, not enough comparable cars",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/cars/{id}": {
+            "get": {
+                "description": "Gets a single car from the database corresponding to the id in the path. Otherwise, returns error",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "car"
+                ],
+                "summary": "Get a car",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Car Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "mi",
+                            "km"
+                        ],
+                        "type": "string",
+                        "description": "Odometer units of the response",
+                        "name": "units",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 timestamp; returns the car as it was at that instant",
+                        "name": "as_of",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Car"
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Moves an existing car corresponding to the id in the path to the trash, from where it can be restored until the retention period expires. Otherwise, returns error",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "car"
+                ],
+                "summary": "Delete a car",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Car Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Replays the stored response when the request is repeated with the same key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "NoContent",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict, the car is held by someone else",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reused with a different request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/cars/{id}/financing": {
+            "get": {
+                "description": "Amortises the price of the car less the down payment over equal monthly payments at a fixed rate. The rate is apr, or the lender rate of the credit tier for the term as listed by GET /financing/rates. Returns the monthly payment, the total interest and cost, and the schedule of payments; the last payment absorbs the rounding to cents",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "car"
+                ],
+                "summary": "Finance a car",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Car Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Down payment",
+                        "name": "down",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Annual percentage rate, e.g. 6.9",
+                        "name": "apr",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Credit tier whose lender rate to use instead of apr",
+                        "name": "tier",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Loan length in months, between 1 and 120. Defaults to 60",
+                        "name": "term",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.financing"
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/cars/{id}/history": {
+            "get": {
+                "description": "Lists every recorded version of the car, oldest first, with the fields changed by each mutation. Deleted cars keep their history",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "car"
+                ],
+                "summary": "Get a car's history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Car Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.carVersion"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/cars/{id}/media": {
+            "get": {
+                "description": "Lists the photos of the car in display order. Each carries the URL of the original upload and of its small, medium and large thumbnails",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "List the photos of a car",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Car Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.media"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Adds a JPEG, PNG or GIF photo after the other photos of the car and generates its thumbnails. The type is sniffed from the content, not taken from the request. The first photo of a car becomes its primary photo",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Upload a photo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Car Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Photo",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Replays the stored response when the request is repeated with the same key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.media"
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict, the car is held by someone else",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "Photo larger than MEDIA_MAX_BYTES or 40 megapixels",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "UnsupportedMediaType",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/cars/{id}/media/{media}": {
+            "get": {
+                "description": "Returns the original upload. Its thumbnails are served the same way at /cars/{id}/media/{media}/small, /medium and /large",
+                "produces": [
+                    "image/jpeg",
+                    "image/png",
+                    "image/gif"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Download a photo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Car Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Media Id",
+                        "name": "media",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Removes the photo and its thumbnails. When it was the primary photo, the first remaining photo becomes primary",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Delete a photo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Car Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Media Id",
+                        "name": "media",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Replays the stored response when the request is repeated with the same key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "NoContent",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict, the car is held by someone else",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Moves the photo to position, counted from 0, and makes it the primary photo of the car when primary is true",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Reorder a photo or make it primary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Car Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Media Id",
+                        "name": "media",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Media Update JSON Object",
+                        "name": "update",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.mediaUpdate"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Replays the stored response when the request is repeated with the same key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.media"
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict, the car is held by someone else",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/cars/{id}/prices": {
+            "get": {
+                "description": "Lists the prices the car has had, oldest first, and the price changes scheduled for it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "car"
+                ],
+                "summary": "Get the price timeline of a car",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Car Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.priceTimeline"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Schedules the price of the car to change at effective_at. A background scheduler applies it, keeping the old price as previous_price",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "car"
+                ],
+                "summary": "Schedule a price change",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Car Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Price Change JSON Object",
+                        "name": "price",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.priceChange"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Replays the stored response when the request is repeated with the same key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.scheduledPrice"
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict, the car is held by someone else",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reused with a different request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/cars/{id}/prices/{schedule}": {
+            "delete": {
+                "description": "Removes a price change that has not been applied yet",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "car"
+                ],
+                "summary": "Cancel a scheduled price change",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Car Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Scheduled Price Id",
+                        "name": "schedule",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Replays the stored response when the request is repeated with the same key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "NoContent",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict, the car is held by someone else",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reused with a different request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/cars/{id}/reservations": {
+            "get": {
+                "description": "Lists the active reservation of the car, if any",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "car"
+                ],
+                "summary": "Get the hold on a car",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Car Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.reservation"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Reserves an available car for 48 hours, or the given hours up to a week, for the authenticated rep. While the hold is active only the rep who placed it can update, delete or transition the car. It is released automatically when it expires",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "car"
+                ],
+                "summary": "Hold a car for a customer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Car Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reservation JSON Object",
+                        "name": "reservation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.reservationRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Replays the stored response when the request is repeated with the same key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.reservation"
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict, the car is already held or not available",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reused with a different request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/cars/{id}/reservations/{reservation}": {
+            "delete": {
+                "description": "Ends the reservation before it expires and makes the car available again. Only the rep who placed the hold can release it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "car"
+                ],
+                "summary": "Release a hold on a car",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Car Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reservation Id",
+                        "name": "reservation",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Replays the stored response when the request is repeated with the same key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Car"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden, the hold belongs to someone else",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reused with a different request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/cars/{id}/valuation": {
+            "get": {
+                "description": "Estimates the market price of the car from the other cars in the store, as GET /cars/valuation does for its make, model, category, year and mileage",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "car"
+                ],
+                "summary": "Value a listed car",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Car Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "mi",
+                            "km"
+                        ],
+                        "type": "string",
+                        "description": "Odometer units of the response",
+                        "name": "units",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.valuation"
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "UnprocessableEntity, not enough comparable cars",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/cars/{id}:restore": {
+            "post": {
+                "description": "Moves a car back from the trash. Fails if the car was purged or another car now uses its id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "car"
+                ],
+                "summary": "Restore a deleted car",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Car Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Replays the stored response when the request is repeated with the same key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Car"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reused with a different request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/cars/{id}:transition": {
+            "post": {
+                "description": "Moves the car along its lifecycle: incoming and in_transit cars become available, available cars can be sold, and reserved or sold cars can go back to available. A car becomes reserved only by holding it through /cars/{id}/reservations. The actor and reason are recorded in the car's history",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "car"
+                ],
+                "summary": "Change the status of a car",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Car Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Status Transition JSON Object",
+                        "name": "transition",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.statusTransition"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Replays the stored response when the request is repeated with the same key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Car"
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict, the transition is not allowed or the car is held by someone else",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reused with a different request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/cars:batch": {
+            "post": {
+                "description": "Applies an ordered list of create, update and delete operations. Creates that look like a listed car fail with 409 and the possible duplicates unless allow_duplicate is set. In atomic mode (the default) either every operation is applied or none is, and the operations that did not fail report 424. In best_effort mode every operation that can be applied is. Each result carries the status code the equivalent single request would have returned",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "car"
+                ],
+                "summary": "Apply a batch of car operations",
+                "parameters": [
+                    {
+                        "description": "Batch JSON Object",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.batchRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Replays the stored response when the request is repeated with the same key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Committed",
+                        "schema": {
+                            "$ref": "#/definitions/main.batchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest, or the atomic batch was rolled back",
+                        "schema": {
+                            "$ref": "#/definitions/main.batchResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound, the atomic batch was rolled back",
+                        "schema": {
+                            "$ref": "#/definitions/main.batchResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict, the atomic batch was rolled back",
+                        "schema": {
+                            "$ref": "#/definitions/main.batchResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/cars:compare": {
+            "get": {
+                "description": "Lines up two or three cars field by field, in the order of ids. Each field tells whether the cars differ, ignoring case, and price, mileage and year carry the differences from the first car. Unknown ids are listed together in the 404",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "car"
+                ],
+                "summary": "Compare cars",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Comma separated ids of two or three cars",
+                        "name": "ids",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "mi",
+                            "km"
+                        ],
+                        "type": "string",
+                        "description": "Odometer units of the response",
+                        "name": "units",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.comparison"
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound, with the ids not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.compareError"
+                        }
+                    }
+                }
+            }
+        },
+        "/financing/rates": {
+            "get": {
+                "description": "Lists the annual percentage rates of each credit tier by loan term. A loan is charged the rate of the shortest listed term that covers it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "financing"
+                ],
+                "summary": "List lender rates",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/main.termRate"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/graphql": {
+            "post": {
+                "description": "Runs a GraphQL query or mutation over the car inventory. The schema exposes car(id), cars(filter, sort, first, after) and the createCar, updateCar and deleteCar mutations, and can be introspected. Queries may also be sent with GET, in the query, variables and operationName parameters; mutations must be POSTed. In development mode a GraphiQL page is served to browsers on GET",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "graphql"
+                ],
+                "summary": "Execute a GraphQL request",
+                "parameters": [
+                    {
+                        "description": "GraphQL request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.graphqlRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "GraphQL result with data and errors",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/group/cars": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Lists the cars of all dealerships, or only of the one in the dealership filter. Requires a group admin",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "group"
+                ],
+                "summary": "Get the cars of every dealership",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by dealership",
+                        "name": "dealership",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "mi",
+                            "km"
+                        ],
+                        "type": "string",
+                        "description": "Odometer units of the response and mileage filters",
+                        "name": "units",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by make",
+                        "name": "make",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by model",
+                        "name": "model",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by color",
+                        "name": "color",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by status, comma separated: incoming, in_transit, available, reserved, sold",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum year",
+                        "name": "min_year",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum year",
+                        "name": "max_year",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum price",
+                        "name": "min_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Maximum price",
+                        "name": "max_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum mileage, in units",
+                        "name": "min_mileage",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Maximum mileage, in units",
+                        "name": "max_mileage",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort field, prefix with - for descending",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.Car"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/group/dealerships": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "Lists the names of the dealerships. Requires a group admin",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "group"
+                ],
+                "summary": "List dealerships",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/vin/{vin}": {
+            "get": {
+                "description": "Validates the check digit of the VIN and decodes its manufacturer, country and model year. make is omitted when the manufacturer is unknown",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "vin"
+                ],
+                "summary": "Decode a VIN",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Vehicle identification number",
+                        "name": "vin",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.vinInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "description": "Lists the webhook subscriptions of the dealership. Secrets are not returned",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "List webhook subscriptions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.webhookSubscription"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Subscribes a URL to car events. URLs on localhost or at private, loopback or link-local addresses are rejected, also when a host name resolves to one at delivery time. Every delivery is a JSON POST signed in the X-Webhook-Signature header with the hex HMAC-SHA256 of \"\u003cX-Webhook-Timestamp\u003e.\u003cbody\u003e\" keyed with the secret. Failed deliveries are retried with exponential backoff before being moved to the dead letters",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Create a webhook subscription",
+                "parameters": [
+                    {
+                        "description": "Subscription JSON Object",
+                        "name": "subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.webhookSubscription"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Replays the stored response when the request is repeated with the same key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.webhookSubscription"
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reused with a different request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "get": {
+                "description": "Gets the webhook subscription corresponding to the id in the path. The secret is not returned",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Get a webhook subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.webhookSubscription"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes the webhook subscription corresponding to the id in the path, with its delivery log and dead letters",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Delete a webhook subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Replays the stored response when the request is repeated with the same key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "NoContent",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reused with a different request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/dead-letters": {
+            "get": {
+                "description": "Lists the deliveries of the subscription that failed every retry",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "List dead letters",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.webhookDelivery"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/dead-letters/{delivery}:redeliver": {
+            "post": {
+                "description": "Removes the delivery from the dead letters and sends it again, with a fresh round of retries",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Redeliver a dead letter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Delivery Id",
+                        "name": "delivery",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/main.webhookDelivery"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "Lists the most recent deliveries of the subscription with every attempt made",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "List webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription Id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.webhookDelivery"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "main.Car": {
+            "description": "car information",
+            "type": "object",
+            "properties": {
+                "Category": {
+                    "type": "string"
+                },
+                "Color": {
+                    "type": "string"
+                },
+                "Id": {
+                    "type": "string"
+                },
+                "Make": {
+                    "type": "string"
+                },
+                "Mileage": {
+                    "type": "number"
+                },
+                "Model": {
+                    "type": "string"
+                },
+                "Package": {
+                    "type": "string"
+                },
+                "Price": {
+                    "type": "number"
+                },
+                "Year": {
+                    "type": "integer"
+                },
+                "country": {
+                    "type": "string"
+                },
+                "dealership": {
+                    "type": "string"
+                },
+                "media": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.media"
+                    }
+                },
+                "mileage_unit": {
+                    "type": "string",
+                    "enum": [
+                        "mi",
+                        "km"
+                    ]
+                },
+                "previous_price": {
+                    "type": "number"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "incoming",
+                        "in_transit",
+                        "available",
+                        "reserved",
+                        "sold"
+                    ]
+                },
+                "vin": {
+                    "type": "string"
+                },
+                "vin_warnings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "main.batchOperation": {
+            "type": "object",
+            "properties": {
+                "allow_duplicate": {
+                    "type": "boolean"
+                },
+                "car": {
+                    "$ref": "#/definitions/main.Car"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "op": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete"
+                    ]
+                }
+            }
+        },
+        "main.batchRequest": {
+            "description": "ordered car operations applied together",
+            "type": "object",
+            "properties": {
+                "mode": {
+                    "type": "string",
+                    "enum": [
+                        "atomic",
+                        "best_effort"
+                    ]
+                },
+                "operations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.batchOperation"
+                    }
+                }
+            }
+        },
+        "main.batchResponse": {
+            "description": "result of a batch, per operation",
+            "type": "object",
+            "properties": {
+                "committed": {
+                    "type": "boolean"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.batchResult"
+                    }
+                }
+            }
+        },
+        "main.batchResult": {
+            "type": "object",
+            "properties": {
+                "car": {
+                    "$ref": "#/definitions/main.Car"
+                },
+                "duplicates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.duplicateMatch"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.carEvent": {
+            "description": "a car lifecycle event",
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "car": {
+                    "$ref": "#/definitions/main.Car"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "car.created",
+                        "car.updated",
+                        "car.deleted",
+                        "car.restored"
+                    ]
+                }
+            }
+        },
+        "main.carVersion": {
+            "description": "a recorded version of a car",
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "enum": [
+                        "created",
+                        "updated",
+                        "deleted",
+                        "restored",
+                        "transitioned"
+                    ]
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "at": {
+                    "type": "string"
+                },
+                "car": {
+                    "$ref": "#/definitions/main.Car"
+                },
+                "changes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/main.fieldChange"
+                    }
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.compareError": {
+            "description": "a comparison of unknown cars",
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "missing": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "main.comparison": {
+            "description": "cars compared field by field",
+            "type": "object",
+            "properties": {
+                "cars": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Car"
+                    }
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.fieldComparison"
+                    }
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "main.deliveryAttempt": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status_code": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.duplicateError": {
+            "description": "a car rejected as a possible duplicate",
+            "type": "object",
+            "properties": {
+                "duplicates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.duplicateMatch"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.duplicateGroup": {
+            "description": "cars that look like the same vehicle",
+            "type": "object",
+            "properties": {
+                "cars": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Car"
+                    }
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.duplicateMatch": {
+            "description": "a possible duplicate of a car",
+            "type": "object",
+            "properties": {
+                "car": {
+                    "$ref": "#/definitions/main.Car"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.facetCount": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.facets": {
+            "description": "value counts and numeric histograms over the filtered inventory",
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.facetCount"
+                    }
+                },
+                "color": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.facetCount"
+                    }
+                },
+                "make": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.facetCount"
+                    }
+                },
+                "mileage": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.rangeBucket"
+                    }
+                },
+                "price": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.rangeBucket"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "year": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.facetCount"
+                    }
+                }
+            }
+        },
+        "main.fieldChange": {
+            "type": "object",
+            "properties": {
+                "from": {},
+                "to": {}
+            }
+        },
+        "main.fieldComparison": {
+            "description": "a field of the compared cars",
+            "type": "object",
+            "properties": {
+                "deltas": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                },
+                "differs": {
+                    "type": "boolean"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "values": {
+                    "type": "array",
+                    "items": {}
+                }
+            }
+        },
+        "main.fieldStats": {
+            "type": "object",
+            "properties": {
+                "max": {
+                    "type": "number"
+                },
+                "mean": {
+                    "type": "number"
+                },
+                "median": {
+                    "type": "number"
+                },
+                "min": {
+                    "type": "number"
+                },
+                "percentiles": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number"
+                    }
+                },
+                "sum": {
+                    "type": "number"
+                }
+            }
+        },
+        "main.financing": {
+            "description": "the payments of a car loan",
+            "type": "object",
+            "properties": {
+                "apr": {
+                    "type": "number"
+                },
+                "car_id": {
+                    "type": "string"
+                },
+                "down": {
+                    "type": "number"
+                },
+                "monthly_payment": {
+                    "type": "number"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "principal": {
+                    "type": "number"
+                },
+                "schedule": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.installment"
+                    }
+                },
+                "term": {
+                    "type": "integer"
+                },
+                "tier": {
+                    "type": "string"
+                },
+                "total_cost": {
+                    "type": "number"
+                },
+                "total_interest": {
+                    "type": "number"
+                }
+            }
+        },
+        "main.graphqlRequest": {
+            "type": "object",
+            "properties": {
+                "operationName": {
+                    "type": "string"
+                },
+                "query": {
+                    "type": "string"
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            }
+        },
+        "main.installment": {
+            "description": "a monthly loan payment",
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "number"
+                },
+                "interest": {
+                    "type": "number"
+                },
+                "month": {
+                    "type": "integer"
+                },
+                "payment": {
+                    "type": "number"
+                },
+                "principal": {
+                    "type": "number"
+                }
+            }
+        },
+        "main.media": {
+            "description": "a photo of a car",
+            "type": "object",
+            "properties": {
+                "car_id": {
+                    "type": "string"
+                },
+                "content_type": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "height": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "position": {
+                    "type": "integer"
+                },
+                "primary": {
+                    "type": "boolean"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "thumbnails": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "uploaded_by": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "width": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.mediaUpdate": {
+            "description": "media ordering request",
+            "type": "object",
+            "properties": {
+                "position": {
+                    "type": "integer"
+                },
+                "primary": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "main.priceChange": {
+            "description": "scheduled price change request",
+            "type": "object",
+            "properties": {
+                "effective_at": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number"
+                }
+            }
+        },
+        "main.pricePoint": {
+            "description": "a past price of a car",
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number"
+                }
+            }
+        },
+        "main.priceTimeline": {
+            "description": "price timeline of a car",
+            "type": "object",
+            "properties": {
+                "car_id": {
+                    "type": "string"
+                },
+                "history": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.pricePoint"
+                    }
+                },
+                "previous_price": {
+                    "type": "number"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "scheduled": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.scheduledPrice"
+                    }
+                }
+            }
+        },
+        "main.rangeBucket": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "max": {
+                    "type": "number"
+                },
+                "min": {
+                    "type": "number"
+                }
+            }
+        },
+        "main.reservation": {
+            "description": "a hold on a car for a customer",
+            "type": "object",
+            "properties": {
+                "car_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "customer": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "held_by": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.reservationRequest": {
+            "description": "reservation request",
+            "type": "object",
+            "properties": {
+                "customer": {
+                    "type": "string"
+                },
+                "hours": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.scheduledPrice": {
+            "description": "a future price of a car",
+            "type": "object",
+            "properties": {
+                "car_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "effective_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "scheduled_by": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.searchResult": {
+            "description": "search hit with relevance score and highlighted fields",
+            "type": "object",
+            "properties": {
+                "car": {
+                    "$ref": "#/definitions/main.Car"
+                },
+                "highlights": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "score": {
+                    "type": "number"
+                }
+            }
+        },
+        "main.statsGroup": {
+            "description": "aggregates for one group of cars",
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "key": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "metrics": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/main.fieldStats"
+                    }
+                }
+            }
+        },
+        "main.statusTransition": {
+            "description": "status transition of a car",
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "incoming",
+                        "in_transit",
+                        "available",
+                        "reserved",
+                        "sold"
+                    ]
+                }
+            }
+        },
+        "main.termRate": {
+            "description": "the rate of a loan term",
+            "type": "object",
+            "properties": {
+                "apr": {
+                    "type": "number"
+                },
+                "term": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.valuation": {
+            "description": "suggested price of a car",
+            "type": "object",
+            "properties": {
+                "basis": {
+                    "type": "string",
+                    "enum": [
+                        "make and model",
+                        "make and category",
+                        "category"
+                    ]
+                },
+                "category": {
+                    "type": "string"
+                },
+                "comparables": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Car"
+                    }
+                },
+                "confidence": {
+                    "type": "number"
+                },
+                "estimate": {
+                    "type": "number"
+                },
+                "high": {
+                    "type": "number"
+                },
+                "low": {
+                    "type": "number"
+                },
+                "make": {
+                    "type": "string"
+                },
+                "method": {
+                    "type": "string"
+                },
+                "mileage": {
+                    "type": "number"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "year": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.vinInfo": {
+            "description": "decoded vehicle identification number",
+            "type": "object",
+            "properties": {
+                "country": {
+                    "type": "string"
+                },
+                "make": {
+                    "type": "string"
+                },
+                "serial_number": {
+                    "type": "string"
+                },
+                "vin": {
+                    "type": "string"
+                },
+                "wmi": {
+                    "type": "string"
+                },
+                "year": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.webhookDelivery": {
+            "description": "webhook delivery and its attempts",
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.deliveryAttempt"
+                    }
+                },
+                "event": {
+                    "$ref": "#/definitions/main.carEvent"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "pending",
+                        "succeeded",
+                        "dead"
+                    ]
+                },
+                "subscription_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.webhookSubscription": {
+            "description": "webhook subscription",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "dealership": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string",
+                        "enum": [
+                            "car.created",
+                            "car.updated",
+                            "car.deleted",
+                            "car.restored"
+                        ]
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
                 }
             }
         }
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Listings of the same car may differ in mileage by duplicateMileageSlack
+// miles, or by duplicateMileageShare of the larger reading when that is
+// more.
+const (
+	duplicateMileageSlack = 500
+	duplicateMileageShare = 0.02
+)
+
+// Why two cars are taken for the same vehicle.
+const (
+	duplicateSameVin = "same vin"
+	duplicateSimilar = "similar make, model, year, color and mileage"
+)
+
+// duplicateMatch is an existing car that looks like the same vehicle.
+// @Description a possible duplicate of a car
+type duplicateMatch struct {
+	Car    Car    `json:"car"`
+	Reason string `json:"reason"`
+}
+
+// duplicateGroup is a set of cars that look like the same vehicle.
+// @Description cars that look like the same vehicle
+type duplicateGroup struct {
+	Reason string `json:"reason"`
+	Cars   []Car  `json:"cars"`
+}
+
+// duplicateError rejects a car that looks like cars already listed. It is
+// written as the response body of the 409.
+// @Description a car rejected as a possible duplicate
+type duplicateError struct {
+	Message    string           `json:"error"`
+	Duplicates []duplicateMatch `json:"duplicates"`
+}
+
+func (e *duplicateError) Error() string {
+	return e.Message
+}
+
+// duplicateReason tells why a and b look like the same vehicle, or ""
+// when they do not. Cars that both have a VIN are compared on it alone.
+func duplicateReason(a, b Car) string {
+	if a.Vin != "" && b.Vin != "" {
+		if a.Vin == b.Vin {
+			return duplicateSameVin
+		}
+		return ""
+	}
+
+	if a.Year != b.Year || !similarText(a.Make, b.Make) || !similarText(a.Model, b.Model) || !similarText(a.Color, b.Color) {
+		return ""
+	}
+	slack := math.Max(duplicateMileageSlack, duplicateMileageShare*math.Max(a.Mileage, b.Mileage))
+	if math.Abs(a.Mileage-b.Mileage) > slack {
+		return ""
+	}
+	return duplicateSimilar
+}
+
+// similarText compares two names ignoring case, spaces and punctuation,
+// and allowing one typo in names of four or more characters.
+func similarText(a, b string) bool {
+	a, b = foldText(a), foldText(b)
+	if a == b {
+		return true
+	}
+	if len([]rune(a)) < 4 || len([]rune(b)) < 4 {
+		return false
+	}
+	return editDistance(a, b) <= 1
+}
+
+func foldText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
+// editDistance is the number of insertions, deletions, substitutions and
+// swaps of adjacent characters that turn a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = d[i-1][j-1] + cost
+			if d[i-1][j]+1 < d[i][j] {
+				d[i][j] = d[i-1][j] + 1
+			}
+			if d[i][j-1]+1 < d[i][j] {
+				d[i][j] = d[i][j-1] + 1
+			}
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] && d[i-2][j-2]+1 < d[i][j] {
+				d[i][j] = d[i-2][j-2] + 1
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+// findDuplicates lists the other cars that look like c.
+func (db *Db) findDuplicates(c Car) []duplicateMatch {
+	matches := []duplicateMatch{}
+	for _, v := range db.cars {
+		if v.Id == c.Id {
+			continue
+		}
+		if reason := duplicateReason(c, v); reason != "" {
+			matches = append(matches, duplicateMatch{Car: v, Reason: reason})
+		}
+	}
+	return matches
+}
+
+// checkDuplicates fails with a *duplicateError when c looks like a car
+// already listed. A car whose id exists is left for add to reject.
+func (db *Db) checkDuplicates(c Car) error {
+	if _, err := db.getById(c.Id); err == nil {
+		return nil
+	}
+
+	matches := db.findDuplicates(c)
+	if len(matches) == 0 {
+		return nil
+	}
+	ids := []string{}
+	for _, v := range matches {
+		ids = append(ids, v.Car.Id)
+	}
+	return &duplicateError{Message: fmt.Sprintf("possible duplicate of %s", strings.Join(ids, ", ")), Duplicates: matches}
+}
+
+// duplicateGroups groups the cars that look like the same vehicle,
+// directly or through another car of the group. A group is reported as
+// the same vin when any two of its cars share one.
+func (db *Db) duplicateGroups() []duplicateGroup {
+	group := make([]int, len(db.cars))
+	for i := range group {
+		group[i] = i
+	}
+	var root func(i int) int
+	root = func(i int) int {
+		if group[i] != i {
+			group[i] = root(group[i])
+		}
+		return group[i]
+	}
+
+	reasons := map[int]string{}
+	for i := range db.cars {
+		for j := i + 1; j < len(db.cars); j++ {
+			reason := duplicateReason(db.cars[i], db.cars[j])
+			if reason == "" {
+				continue
+			}
+			ri, rj := root(i), root(j)
+			if ri != rj {
+				group[rj] = ri
+				if reasons[rj] == duplicateSameVin {
+					reasons[ri] = duplicateSameVin
+				}
+			}
+			if reasons[ri] == "" || reason == duplicateSameVin {
+				reasons[ri] = reason
+			}
+		}
+	}
+
+	members := map[int][]Car{}
+	for i, v := range db.cars {
+		members[root(i)] = append(members[root(i)], v)
+	}
+	groups := []duplicateGroup{}
+	for r, cars := range members {
+		if len(cars) < 2 {
+			continue
+		}
+		sort.Slice(cars, func(i, j int) bool { return cars[i].Id < cars[j].Id })
+		groups = append(groups, duplicateGroup{Reason: reasons[r], Cars: cars})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Cars[0].Id < groups[j].Cars[0].Id })
+	return groups
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func duplicateTestCar(id string) *Car {
+	return &Car{ Id: id, Make: "Trabant", Model: "Kombi", Package: "601", Color: "Olive", Year: 1988, Category: "SUV", Mileage: 120000, Price: 450000, MileageUnit: "mi" }
+}
+
+func duplicatePost(path string, c *Car) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(c)
+	h := &carHandler{}
+	r := httptest.NewRequest("POST", path, strings.NewReader(string(body)))
+	r.Header.Set("content-type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestDuplicateReason_WhenComparing(t *testing.T){
+	a := *duplicateTestCar("a")
+
+	b := a
+	b.Make, b.Color, b.Mileage = "TRABANT ", "olive", 121500
+	assert.Equal(t, duplicateReason(a, b), duplicateSimilar)
+
+	b.Model = "Kmobi"
+	assert.Equal(t, duplicateReason(a, b), duplicateSimilar)
+
+	b.Mileage = 125000
+	assert.Equal(t, duplicateReason(a, b), "")
+
+	b = a
+	b.Year = 1989
+	assert.Equal(t, duplicateReason(a, b), "")
+
+	a.Vin, b.Vin = "1FTFW1ET9DFC10312", "1FTFW1ET9DFC10312"
+	assert.Equal(t, duplicateReason(a, b), duplicateSameVin)
+
+	b = a
+	b.Vin = "5YJ3E1EA2KF317000"
+	assert.Equal(t, duplicateReason(a, b), "")
+}
+
+func TestPost_WhenDuplicate(t *testing.T){
+	w := duplicatePost("/cars", duplicateTestCar("dupcar1"))
+	assert.Equal(t, w.Code, http.StatusCreated)
+
+	again := duplicateTestCar("dupcar2")
+	again.Mileage = 120300
+	w = duplicatePost("/cars", again)
+
+	var out duplicateError
+	json.Unmarshal(w.Body.Bytes(), &out)
+	assert.Equal(t, w.Code, http.StatusConflict)
+	assert.Equal(t, out.Message, "possible duplicate of dupcar1")
+	assert.Equal(t, out.Duplicates[0].Car.Id, "dupcar1")
+
+	w = duplicatePost("/cars?allow_duplicate=true", again)
+	assert.Equal(t, w.Code, http.StatusCreated)
+}
+
+func TestPost_WhenSameVin(t *testing.T){
+	car := duplicateTestCar("dupcar3")
+	car.Make, car.Year = "", 0
+	car.Vin = "JHMFA1F55AS000001"
+	w := duplicatePost("/cars", car)
+	assert.Equal(t, w.Code, http.StatusCreated)
+
+	other := duplicateTestCar("dupcar4")
+	other.Make, other.Model, other.Year = "", "Civic", 0
+	other.Vin = "jhmfa1f55as000001"
+	w = duplicatePost("/cars", other)
+
+	assert.Equal(t, w.Code, http.StatusConflict)
+	assert.Equal(t, strings.Contains(w.Body.String(), `"reason":"same vin"`), true)
+}
+
+func TestBatch_WhenCreatingDuplicate(t *testing.T){
+	duplicateTestCar("dupcar5").createCar()
+	car, _ := json.Marshal(duplicateTestCar("dupcar6"))
+
+	code, out := batchPost(t, `{"mode": "best_effort", "operations": [{"op": "create", "car": ` + string(car) + `}]}`)
+
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, out.Results[0].Status, http.StatusConflict)
+	assert.Equal(t, out.Results[0].Duplicates[0].Reason, duplicateSimilar)
+}
+
+func TestDuplicates_WhenReporting(t *testing.T){
+	var d Db
+	d.add(duplicateTestCar("a"))
+	d.add(duplicateTestCar("b"))
+	c := duplicateTestCar("c")
+	c.Mileage = 120400
+	d.add(c)
+	other := duplicateTestCar("d")
+	other.Color = "White"
+	d.add(other)
+
+	groups := d.duplicateGroups()
+
+	assert.Equal(t, len(groups), 1)
+	assert.Equal(t, groups[0].Reason, duplicateSimilar)
+	assert.Equal(t, []string{groups[0].Cars[0].Id, groups[0].Cars[1].Id, groups[0].Cars[2].Id}, []string{"a", "b", "c"})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Car lifecycle event types.
+const (
+	eventCreated  = "car.created"
+	eventUpdated  = "car.updated"
+	eventDeleted  = "car.deleted"
+	eventRestored = "car.restored"
+)
+
+// carEvent is published after a car is successfully created, updated,
+// deleted or restored. Id increases by one per event, from a start that
+// depends on when the process started.
+// @Description a car lifecycle event
+type carEvent struct {
+	Id   uint64    `json:"id"`
+	Type string    `json:"type" enums:"car.created,car.updated,car.deleted,car.restored"`
+	At   time.Time `json:"at"`
+	Car  Car       `json:"car"`
+}
+
+// eventBus fans car events out to in-process subscribers. Subscribers are
+// called synchronously and must not block.
+type eventBus struct {
+	sync.Mutex
+	seq         uint64
+	subscribers []func(carEvent)
+}
+
+var bus = eventBus{seq: eventEpoch(time.Now())}
+
+// eventEpoch is the id before the first event of a process started at t:
+// the start time in seconds shifted left 20 bits. Ids thus keep increasing
+// across restarts unless a run averages over a million events a second,
+// and an id from an earlier run is below every id of the current one. They
+// stay below 2^53, so JavaScript clients read them exactly.
+func eventEpoch(t time.Time) uint64 {
+	return uint64(t.Unix()) << 20
+}
+
+// last returns the id of the most recent event.
+func (b *eventBus) last() uint64 {
+	defer b.Unlock()
+	b.Lock()
+
+	return b.seq
+}
+
+func (b *eventBus) subscribe(f func(carEvent)) {
+	defer b.Unlock()
+	b.Lock()
+
+	b.subscribers = append(b.subscribers, f)
+}
+
+func (b *eventBus) publish(typ string, c Car) {
+	defer b.Unlock()
+	b.Lock()
+
+	b.seq++
+	e := carEvent{Id: b.seq, Type: typ, At: now(), Car: c}
+	for _, f := range b.subscribers {
+		f(e)
+	}
+}
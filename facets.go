@@ -0,0 +1,101 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Default bucket widths for the numeric facets.
+const (
+	defaultPriceInterval   = 1000000
+	defaultMileageInterval = 25000
+)
+
+// facetCount is the number of cars sharing a field value.
+type facetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// rangeBucket counts the cars whose value falls in [Min, Max).
+type rangeBucket struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count int     `json:"count"`
+}
+
+// facets is the response of GET /cars/facets.
+// @Description value counts and numeric histograms over the filtered inventory
+type facets struct {
+	Total    int           `json:"total"`
+	Make     []facetCount  `json:"make"`
+	Category []facetCount  `json:"category"`
+	Color    []facetCount  `json:"color"`
+	Year     []facetCount  `json:"year"`
+	Price    []rangeBucket `json:"price"`
+	Mileage  []rangeBucket `json:"mileage"`
+}
+
+// computeFacets builds the facets for cars. Mileage buckets are expressed
+// in units.
+func computeFacets(cars []Car, units string, priceInterval, mileageInterval float64) facets {
+	makes := map[string]int{}
+	categories := map[string]int{}
+	colors := map[string]int{}
+	years := map[string]int{}
+	prices := []float64{}
+	mileages := []float64{}
+
+	for _, v := range cars {
+		makes[v.Make]++
+		categories[v.Category]++
+		colors[v.Color]++
+		years[strconv.Itoa(v.Year)]++
+		prices = append(prices, v.Price)
+		mileages = append(mileages, fromMiles(v.Mileage, units))
+	}
+
+	return facets{
+		Total:    len(cars),
+		Make:     sortedCounts(makes),
+		Category: sortedCounts(categories),
+		Color:    sortedCounts(colors),
+		Year:     sortedCounts(years),
+		Price:    histogram(prices, priceInterval),
+		Mileage:  histogram(mileages, mileageInterval),
+	}
+}
+
+// sortedCounts orders counts by frequency, then by value.
+func sortedCounts(counts map[string]int) []facetCount {
+	out := []facetCount{}
+	for k, v := range counts {
+		out = append(out, facetCount{Value: k, Count: v})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Value < out[j].Value
+	})
+	return out
+}
+
+// histogram groups values into buckets of width interval. Empty buckets
+// are omitted.
+func histogram(values []float64, interval float64) []rangeBucket {
+	counts := map[float64]int{}
+	for _, v := range values {
+		counts[math.Floor(v/interval)*interval]++
+	}
+
+	out := []rangeBucket{}
+	for min, n := range counts {
+		out = append(out, rangeBucket{Min: min, Max: min + interval, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Min < out[j].Min
+	})
+	return out
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeFacets_WhenCountingValues(t *testing.T){
+	f := computeFacets(filterCars, "mi", 1500, 60)
+
+	assert.Equal(t, f.Total, 3)
+	assert.Equal(t, f.Make[0], facetCount{ Value: "Toyota", Count: 2 })
+	assert.Equal(t, f.Category[0], facetCount{ Value: "SUV", Count: 1 })
+	assert.Equal(t, f.Year[0].Value, "2010")
+}
+
+func TestComputeFacets_WhenBucketingNumbers(t *testing.T){
+	f := computeFacets(filterCars, "mi", 1500, 60)
+
+	assert.Equal(t, f.Price, []rangeBucket{ { Min: 0, Max: 1500, Count: 1 }, { Min: 1500, Max: 3000, Count: 1 }, { Min: 3000, Max: 4500, Count: 1 } })
+	assert.Equal(t, f.Mileage, []rangeBucket{ { Min: 0, Max: 60, Count: 2 }, { Min: 60, Max: 120, Count: 1 } })
+}
+
+func TestComputeFacets_WhenMileageInKm(t *testing.T){
+	f := computeFacets(filterCars, "km", 1500, 100)
+
+	assert.Equal(t, f.Mileage, []rangeBucket{ { Min: 0, Max: 100, Count: 2 }, { Min: 100, Max: 200, Count: 1 } })
+}
+
+func TestGetFacets_WhenIntervalLE_0(t *testing.T){
+	var car Car
+	_, err := car.getFacets(carQuery{ units: "mi" }, 0, 10)
+
+	assert.Equal(t, err.Error(), "price_interval must be gt 0")
+}
+
+func TestGetFacets_WhenIntervalNotFinite(t *testing.T){
+	var car Car
+	_, err := car.getFacets(carQuery{ units: "mi" }, math.NaN(), 10)
+	assert.Equal(t, err.Error(), "price_interval must be gt 0")
+
+	_, err = car.getFacets(carQuery{ units: "mi" }, 100, math.Inf(1))
+	assert.Equal(t, err.Error(), "mileage_interval must be gt 0")
+
+	h := &carHandler{}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/cars/facets?price_interval=NaN", nil))
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+}
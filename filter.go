@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// carQuery holds the filters and sort order accepted by GET /cars.
+// Mileage bounds are kept in miles, converted from the requested units.
+// Bounds are nil when not given, so that 0 filters like any other value.
+type carQuery struct {
+	units      string
+	make       string
+	model      string
+	category   string
+	color      string
+	statuses   []string
+	minYear    *int
+	maxYear    *int
+	minPrice   *float64
+	maxPrice   *float64
+	minMileage *float64
+	maxMileage *float64
+	sort       string
+	desc       bool
+}
+
+var sortableFields = map[string]bool{
+	"id": true, "make": true, "model": true, "year": true, "mileage": true, "price": true,
+}
+
+func parseCarQuery(v url.Values) (carQuery, error) {
+	var q carQuery
+	var err error
+
+	q.units, err = parseUnits(v.Get("units"))
+	if err != nil {
+		return carQuery{}, err
+	}
+
+	q.make = v.Get("make")
+	q.model = v.Get("model")
+	q.category = v.Get("category")
+	q.color = v.Get("color")
+
+	for _, s := range splitList(v.Get("status")) {
+		if !validStatus(s) {
+			return carQuery{}, fmt.Errorf("status must be one of incoming, in_transit, available, reserved, sold")
+		}
+		q.statuses = append(q.statuses, s)
+	}
+
+	if q.minYear, err = optionalIntParam(v, "min_year"); err != nil {
+		return carQuery{}, err
+	}
+	if q.maxYear, err = optionalIntParam(v, "max_year"); err != nil {
+		return carQuery{}, err
+	}
+	if q.minPrice, err = optionalFloatParam(v, "min_price"); err != nil {
+		return carQuery{}, err
+	}
+	if q.maxPrice, err = optionalFloatParam(v, "max_price"); err != nil {
+		return carQuery{}, err
+	}
+	if q.minMileage, err = optionalFloatParam(v, "min_mileage"); err != nil {
+		return carQuery{}, err
+	}
+	if q.maxMileage, err = optionalFloatParam(v, "max_mileage"); err != nil {
+		return carQuery{}, err
+	}
+	if q.minMileage != nil {
+		*q.minMileage = toMiles(*q.minMileage, q.units)
+	}
+	if q.maxMileage != nil {
+		*q.maxMileage = toMiles(*q.maxMileage, q.units)
+	}
+
+	s := v.Get("sort")
+	if strings.HasPrefix(s, "-") {
+		q.desc = true
+		s = s[1:]
+	}
+	if s != "" && !sortableFields[s] {
+		return carQuery{}, fmt.Errorf("sort must be one of id, make, model, year, mileage, price")
+	}
+	q.sort = s
+
+	return q, nil
+}
+
+func intParam(v url.Values, name string) (int, error) {
+	if v.Get(name) == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v.Get(name))
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+	return n, nil
+}
+
+// floatParam parses a finite number. NaN and infinities are rejected.
+func floatParam(v url.Values, name string) (float64, error) {
+	if v.Get(name) == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseFloat(v.Get(name), 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, fmt.Errorf("%s must be a number", name)
+	}
+	return n, nil
+}
+
+// optionalIntParam is intParam for filters, nil when name is not given.
+func optionalIntParam(v url.Values, name string) (*int, error) {
+	if v.Get(name) == "" {
+		return nil, nil
+	}
+	n, err := intParam(v, name)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// optionalFloatParam is floatParam for filters, nil when name is not given.
+func optionalFloatParam(v url.Values, name string) (*float64, error) {
+	if v.Get(name) == "" {
+		return nil, nil
+	}
+	n, err := floatParam(v, name)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func (q carQuery) match(c Car) bool {
+	if q.make != "" && !strings.EqualFold(c.Make, q.make) {
+		return false
+	}
+	if q.model != "" && !strings.EqualFold(c.Model, q.model) {
+		return false
+	}
+	if q.category != "" && !strings.EqualFold(c.Category, q.category) {
+		return false
+	}
+	if q.color != "" && !strings.EqualFold(c.Color, q.color) {
+		return false
+	}
+	if !q.wantsStatus(c.Status) {
+		return false
+	}
+	if q.minYear != nil && c.Year < *q.minYear {
+		return false
+	}
+	if q.maxYear != nil && c.Year > *q.maxYear {
+		return false
+	}
+	if q.minPrice != nil && c.Price < *q.minPrice {
+		return false
+	}
+	if q.maxPrice != nil && c.Price > *q.maxPrice {
+		return false
+	}
+	if q.minMileage != nil && c.Mileage < *q.minMileage {
+		return false
+	}
+	if q.maxMileage != nil && c.Mileage > *q.maxMileage {
+		return false
+	}
+	return true
+}
+
+// wantsStatus reports whether status is one of the filtered statuses, or
+// no status filter was given.
+func (q carQuery) wantsStatus(status string) bool {
+	if len(q.statuses) == 0 {
+		return true
+	}
+	for _, s := range q.statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns the cars matching q in the requested order. The input
+// slice is never modified.
+func (q carQuery) apply(cars []Car) []Car {
+	out := []Car{}
+	for _, v := range cars {
+		if q.match(v) {
+			out = append(out, v)
+		}
+	}
+
+	if q.sort != "" {
+		sort.SliceStable(out, func(i, j int) bool {
+			if q.desc {
+				return lessBy(q.sort, out[j], out[i])
+			}
+			return lessBy(q.sort, out[i], out[j])
+		})
+	}
+
+	return out
+}
+
+func lessBy(field string, a, b Car) bool {
+	switch field {
+	case "make":
+		return a.Make < b.Make
+	case "model":
+		return a.Model < b.Model
+	case "year":
+		return a.Year < b.Year
+	case "mileage":
+		return a.Mileage < b.Mileage
+	case "price":
+		return a.Price < b.Price
+	}
+	return a.Id < b.Id
+}
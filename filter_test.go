@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var filterCars = []Car{
+	{ Id: "a", Make: "Ford", Model: "F10", Year: 2010, Category: "Truck", Mileage: 100, Price: 1000 },
+	{ Id: "b", Make: "Toyota", Model: "Camry", Year: 2019, Category: "Sedan", Mileage: 10, Price: 3000 },
+	{ Id: "c", Make: "Toyota", Model: "Rav4", Year: 2018, Category: "SUV", Mileage: 50, Price: 2000 },
+}
+
+func TestParseCarQuery_WhenUnitsInvalid(t *testing.T){
+	_, err := parseCarQuery(url.Values{"units": {"ft"}})
+
+	assert.Equal(t, err.Error(), "units must be km or mi")
+}
+
+func TestParseCarQuery_WhenSortInvalid(t *testing.T){
+	_, err := parseCarQuery(url.Values{"sort": {"color"}})
+
+	assert.Equal(t, err.Error(), "sort must be one of id, make, model, year, mileage, price")
+}
+
+func TestApply_WhenFilteringMake(t *testing.T){
+	q, _ := parseCarQuery(url.Values{"make": {"toyota"}, "sort": {"-price"}})
+	cars := q.apply(filterCars)
+
+	assert.Equal(t, len(cars), 2)
+	assert.Equal(t, cars[0].Id, "b")
+}
+
+func TestApply_WhenMileageFilterInKm(t *testing.T){
+	q, _ := parseCarQuery(url.Values{"units": {"km"}, "max_mileage": {"90"}})
+	cars := q.apply(filterCars)
+
+	assert.Equal(t, len(cars), 2)
+	assert.Equal(t, cars[1].Id, "c")
+}
+
+func TestCarsInUnits_WhenKm(t *testing.T){
+	cars := carsInUnits(filterCars, "km")
+
+	assert.Equal(t, cars[0].Mileage, 160.93)
+	assert.Equal(t, cars[0].MileageUnit, "km")
+	assert.Equal(t, filterCars[0].Mileage, 100.0)
+}
+
+func TestApply_WhenBoundIsZero(t *testing.T){
+	cars := append([]Car{{ Id: "d", Make: "Ford", Year: 2020, Mileage: 0, Price: 0 }}, filterCars...)
+
+	q, _ := parseCarQuery(url.Values{"max_mileage": {"0"}})
+	assert.Equal(t, len(q.apply(cars)), 1)
+
+	q, _ = parseCarQuery(url.Values{"max_price": {"0"}})
+	assert.Equal(t, q.apply(cars)[0].Id, "d")
+}
+
+func TestParseCarQuery_WhenNumberNotFinite(t *testing.T){
+	_, err := parseCarQuery(url.Values{"min_price": {"NaN"}})
+	assert.Equal(t, err.Error(), "min_price must be a number")
+
+	_, err = parseCarQuery(url.Values{"max_mileage": {"+Inf"}})
+	assert.Equal(t, err.Error(), "max_mileage must be a number")
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// defaultFinancingTerm is the loan length in months when none is given.
+const defaultFinancingTerm = 60
+
+// termRate is the annual percentage rate a lender charges for loans of up
+// to Term months.
+// @Description the rate of a loan term
+type termRate struct {
+	Term int     `json:"term"`
+	Apr  float64 `json:"apr"`
+}
+
+// rateTable lists the rates of each credit tier, by ascending term.
+type rateTable map[string][]termRate
+
+var defaultLenderRates = rateTable{
+	"excellent": {{36, 5.49}, {48, 5.69}, {60, 5.89}, {72, 6.49}, {84, 7.29}},
+	"good":      {{36, 6.99}, {48, 7.19}, {60, 7.39}, {72, 7.99}, {84, 8.79}},
+	"fair":      {{36, 9.99}, {48, 10.29}, {60, 10.59}, {72, 11.29}, {84, 12.29}},
+	"poor":      {{36, 14.99}, {48, 15.49}, {60, 15.99}, {72, 16.99}, {84, 18.49}},
+}
+
+// lenderRates is the rate table used to price loans by credit tier. main
+// loads it from LENDER_RATES.
+var lenderRates = defaultLenderRates
+
+// loadRateTable reads a rate table from a JSON file mapping each credit
+// tier to its term rates, e.g. {"good": [{"term": 60, "apr": 7.39}]}.
+func loadRateTable(path string) (rateTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var t rateTable
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("rate table: %v", err)
+	}
+	if len(t) == 0 {
+		return nil, fmt.Errorf("rate table: no credit tiers")
+	}
+	for tier, rates := range t {
+		if len(rates) == 0 {
+			return nil, fmt.Errorf("rate table: tier %s has no rates", tier)
+		}
+		for _, r := range rates {
+			if r.Term <= 0 || r.Apr < 0 {
+				return nil, fmt.Errorf("rate table: tier %s has an invalid rate", tier)
+			}
+		}
+		sort.Slice(rates, func(i, j int) bool { return rates[i].Term < rates[j].Term })
+	}
+	return t, nil
+}
+
+func (t rateTable) tiers() []string {
+	tiers := []string{}
+	for tier := range t {
+		tiers = append(tiers, tier)
+	}
+	sort.Strings(tiers)
+	return tiers
+}
+
+// apr is the rate of tier for a loan of term months: that of the shortest
+// listed term that covers it.
+func (t rateTable) apr(tier string, term int) (float64, error) {
+	rates, ok := t[tier]
+	if !ok {
+		return 0, fmt.Errorf("tier must be one of %s", strings.Join(t.tiers(), ", "))
+	}
+	for _, r := range rates {
+		if term <= r.Term {
+			return r.Apr, nil
+		}
+	}
+	return 0, fmt.Errorf("term of %d months is not offered for tier %s", term, tier)
+}
+
+// financingQuery describes a loan. Either apr or tier gives the rate.
+type financingQuery struct {
+	down   float64
+	apr    float64
+	hasApr bool
+	tier   string
+	term   int
+}
+
+// installment is one monthly payment of a loan and the balance left after it.
+// @Description a monthly loan payment
+type installment struct {
+	Month     int     `json:"month"`
+	Payment   float64 `json:"payment"`
+	Principal float64 `json:"principal"`
+	Interest  float64 `json:"interest"`
+	Balance   float64 `json:"balance"`
+}
+
+// financing is the cost of buying a car with a fixed rate loan.
+// @Description the payments of a car loan
+type financing struct {
+	CarId          string        `json:"car_id"`
+	Price          float64       `json:"price"`
+	Down           float64       `json:"down"`
+	Principal      float64       `json:"principal"`
+	Tier           string        `json:"tier,omitempty"`
+	Apr            float64       `json:"apr"`
+	Term           int           `json:"term"`
+	MonthlyPayment float64       `json:"monthly_payment"`
+	TotalInterest  float64       `json:"total_interest"`
+	TotalCost      float64       `json:"total_cost"`
+	Schedule       []installment `json:"schedule"`
+}
+
+func parseFinancingQuery(v url.Values) (financingQuery, error) {
+	var q financingQuery
+	var err error
+
+	if q.down, err = floatParam(v, "down"); err != nil {
+		return financingQuery{}, err
+	}
+	if q.apr, err = floatParam(v, "apr"); err != nil {
+		return financingQuery{}, err
+	}
+	q.hasApr = v.Get("apr") != ""
+	q.tier = v.Get("tier")
+	if q.term, err = intParam(v, "term"); err != nil {
+		return financingQuery{}, err
+	}
+	if v.Get("term") == "" {
+		q.term = defaultFinancingTerm
+	}
+	return q, nil
+}
+
+// finance amortises the price of c less the down payment over q.term
+// equal monthly payments. The last payment absorbs the rounding to cents.
+func finance(c Car, q financingQuery) financing {
+	principal := roundPrice(c.Price - q.down)
+	rate := q.apr / 100 / 12
+
+	payment := principal / float64(q.term)
+	if rate > 0 {
+		payment = principal * rate / (1 - math.Pow(1+rate, -float64(q.term)))
+	}
+	payment = roundPrice(payment)
+
+	f := financing{
+		CarId:          c.Id,
+		Price:          c.Price,
+		Down:           q.down,
+		Principal:      principal,
+		Tier:           q.tier,
+		Apr:            q.apr,
+		Term:           q.term,
+		MonthlyPayment: payment,
+		Schedule:       []installment{},
+	}
+	balance := principal
+	for month := 1; month <= q.term; month++ {
+		interest := roundPrice(balance * rate)
+		p := payment
+		if month == q.term || p > balance+interest {
+			p = roundPrice(balance + interest)
+		}
+		balance = roundPrice(balance - (p - interest))
+		f.TotalInterest += interest
+		f.TotalCost += p
+		f.Schedule = append(f.Schedule, installment{Month: month, Payment: p, Principal: roundPrice(p - interest), Interest: interest, Balance: balance})
+	}
+	f.TotalInterest = roundPrice(f.TotalInterest)
+	f.TotalCost = roundPrice(f.TotalCost + q.down)
+	return f
+}
+
+// financingHandler exposes the lender rate table.
+type financingHandler struct{}
+
+// rates godoc
+// @Summary		List lender rates
+// @Description	Lists the annual percentage rates of each credit tier by loan term. A loan is charged the rate of the shortest listed term that covers it
+// @Tags		financing
+// @Accept		json
+// @Produce		json
+// @Success		200			{object}		map[string][]termRate	"OK"
+// @Router		/financing/rates	[get]
+func (h *financingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		respondWithError(w, http.StatusMethodNotAllowed, "invalid method")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, lenderRates)
+}
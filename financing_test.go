@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFinance_WhenAmortising(t *testing.T){
+	f := finance(Car{Id: "a", Price: 25000}, financingQuery{down: 5000, apr: 6, term: 60})
+
+	assert.Equal(t, f.Principal, 20000.0)
+	assert.Equal(t, f.MonthlyPayment, 386.66)
+	assert.Equal(t, len(f.Schedule), 60)
+	assert.Equal(t, f.Schedule[0], installment{Month: 1, Payment: 386.66, Principal: 286.66, Interest: 100, Balance: 19713.34})
+	assert.Equal(t, f.Schedule[59].Balance, 0.0)
+	assert.Equal(t, f.TotalInterest, roundPrice(f.TotalCost-25000))
+}
+
+func TestFinance_WhenInterestFree(t *testing.T){
+	f := finance(Car{Id: "a", Price: 1000}, financingQuery{term: 3})
+
+	assert.Equal(t, f.MonthlyPayment, 333.33)
+	assert.Equal(t, f.Schedule[2], installment{Month: 3, Payment: 333.34, Principal: 333.34, Interest: 0, Balance: 0})
+	assert.Equal(t, []float64{f.TotalInterest, f.TotalCost}, []float64{0, 1000})
+}
+
+func TestRateTable_WhenLookingUpTier(t *testing.T){
+	apr, err := defaultLenderRates.apr("good", 50)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, apr, 7.39)
+
+	_, err = defaultLenderRates.apr("good", 96)
+	assert.Equal(t, err.Error(), "term of 96 months is not offered for tier good")
+
+	_, err = defaultLenderRates.apr("subprime", 60)
+	assert.Equal(t, err.Error(), "tier must be one of excellent, fair, good, poor")
+}
+
+func TestLoadRateTable_WhenReadingFile(t *testing.T){
+	path := filepath.Join(t.TempDir(), "rates.json")
+	os.WriteFile(path, []byte(`{"prime": [{"term": 72, "apr": 4.5}, {"term": 36, "apr": 3.9}]}`), 0644)
+
+	rates, err := loadRateTable(path)
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rates, rateTable{"prime": {{36, 3.9}, {72, 4.5}}})
+
+	os.WriteFile(path, []byte(`{"prime": []}`), 0644)
+	_, err = loadRateTable(path)
+	assert.Equal(t, err.Error(), "rate table: tier prime has no rates")
+}
+
+func TestFinancing_WhenRequested(t *testing.T){
+	car := persistTestCar("fincar1")
+	car.Make, car.Model, car.Price = "Zastava", "Yugo", 12000
+	car.createCar()
+	h := &carHandler{}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/cars/fincar1/financing?down=2000&tier=excellent&term=36", nil))
+	var out financing
+	json.Unmarshal(w.Body.Bytes(), &out)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, []float64{out.Principal, out.Apr}, []float64{10000, 5.49})
+	assert.Equal(t, len(out.Schedule), 36)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/cars/fincar1/financing?down=12000&apr=5", nil))
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+	assert.Equal(t, w.Body.String(), `{"error":"down must be less than the price"}`)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/cars/fincar1/financing", nil))
+	assert.Equal(t, w.Body.String(), `{"error":"apr or tier must be given"}`)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/cars/nofincar/financing?apr=5", nil))
+	assert.Equal(t, w.Code, http.StatusNotFound)
+}
+
+func TestFinancing_WhenNotFinite(t *testing.T){
+	car := persistTestCar("fincar2")
+	car.Price = 12000
+	car.createCar()
+	h := &carHandler{}
+
+	for _, query := range []string{"apr=NaN", "apr=Inf", "apr=5&down=NaN", "apr=5&down=-Inf", "apr=5&term=NaN", "apr=5&term=Inf", "apr=5&term=1e400"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/cars/fincar2/financing?"+query, nil))
+		assert.Equal(t, w.Code, http.StatusBadRequest, query)
+	}
+
+	err := m.validate_financing(financingQuery{apr: math.NaN(), hasApr: true, term: 60}, 12000)
+	assert.Equal(t, err.Error(), "apr must be between 0 and 100")
+	err = m.validate_financing(financingQuery{apr: 5, hasApr: true, down: math.NaN(), term: 60}, 12000)
+	assert.Equal(t, err.Error(), "down must be ge 0")
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+var unitsEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name:        "Units",
+	Description: "Odometer units",
+	Values: graphql.EnumValueConfigMap{
+		"mi": &graphql.EnumValueConfig{Value: unitMiles},
+		"km": &graphql.EnumValueConfig{Value: unitKilometres},
+	},
+})
+
+var carStatusEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name:        "CarStatus",
+	Description: "Lifecycle status of a car",
+	Values: graphql.EnumValueConfigMap{
+		"INCOMING":   &graphql.EnumValueConfig{Value: statusIncoming},
+		"IN_TRANSIT": &graphql.EnumValueConfig{Value: statusInTransit},
+		"AVAILABLE":  &graphql.EnumValueConfig{Value: statusAvailable},
+		"RESERVED":   &graphql.EnumValueConfig{Value: statusReserved},
+		"SOLD":       &graphql.EnumValueConfig{Value: statusSold},
+	},
+})
+
+var carType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Car",
+	Description: "car information",
+	Fields: graphql.Fields{
+		"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"make":       &graphql.Field{Type: graphql.String},
+		"model":      &graphql.Field{Type: graphql.String},
+		"package":    &graphql.Field{Type: graphql.String},
+		"color":      &graphql.Field{Type: graphql.String},
+		"year":       &graphql.Field{Type: graphql.Int},
+		"category":   &graphql.Field{Type: graphql.String},
+		"mileage":    &graphql.Field{Type: graphql.Float},
+		"price":      &graphql.Field{Type: graphql.Float},
+		"dealership": &graphql.Field{Type: graphql.String},
+		"vin":        &graphql.Field{Type: graphql.String},
+		"country":    &graphql.Field{Type: graphql.String, Description: "Manufacturer country, decoded from the VIN when not given"},
+		"vinWarnings": &graphql.Field{
+			Type:        graphql.NewList(graphql.NewNonNull(graphql.String)),
+			Description: "Submitted values that disagree with the VIN",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(Car).VinWarnings, nil
+			},
+		},
+		"previousPrice": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "Price before the last price change",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if c := p.Source.(Car); c.PreviousPrice != 0 {
+					return c.PreviousPrice, nil
+				}
+				return nil, nil
+			},
+		},
+		"mileageUnit": &graphql.Field{
+			Type: unitsEnum,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(Car).MileageUnit, nil
+			},
+		},
+		"status": &graphql.Field{
+			Type: carStatusEnum,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(Car).Status, nil
+			},
+		},
+	},
+})
+
+var carEdgeType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "CarEdge",
+	Fields: graphql.Fields{
+		"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"node":   &graphql.Field{Type: graphql.NewNonNull(carType)},
+	},
+})
+
+var pageInfoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PageInfo",
+	Fields: graphql.Fields{
+		"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"endCursor":   &graphql.Field{Type: graphql.String},
+	},
+})
+
+var carConnectionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "CarConnection",
+	Fields: graphql.Fields{
+		"totalCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"edges":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(carEdgeType)))},
+		"pageInfo":   &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+	},
+})
+
+var carFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name:        "CarFilter",
+	Description: "Same filters as GET /cars. Mileage bounds are in the units of the query",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"make":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"model":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"category":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"color":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"minYear":    &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"maxYear":    &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"minPrice":   &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"maxPrice":   &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"minMileage": &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"maxMileage": &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"status":     &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(carStatusEnum))},
+	},
+})
+
+var carSortFieldEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "CarSortField",
+	Values: graphql.EnumValueConfigMap{
+		"ID":      &graphql.EnumValueConfig{Value: "id"},
+		"MAKE":    &graphql.EnumValueConfig{Value: "make"},
+		"MODEL":   &graphql.EnumValueConfig{Value: "model"},
+		"YEAR":    &graphql.EnumValueConfig{Value: "year"},
+		"MILEAGE": &graphql.EnumValueConfig{Value: "mileage"},
+		"PRICE":   &graphql.EnumValueConfig{Value: "price"},
+	},
+})
+
+var carSortType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CarSort",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"field": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(carSortFieldEnum)},
+		"desc":  &graphql.InputObjectFieldConfig{Type: graphql.Boolean, DefaultValue: false},
+	},
+})
+
+var carInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CarInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"id":          &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.ID)},
+		"make":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"model":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"package":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"color":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"year":        &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"category":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"mileage":     &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"price":       &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"mileageUnit": &graphql.InputObjectFieldConfig{Type: unitsEnum},
+		"vin":         &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Fills empty make, year and country"},
+		"country":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"status":      &graphql.InputObjectFieldConfig{Type: carStatusEnum, Description: "Status of a new car: INCOMING, IN_TRANSIT or AVAILABLE, the default. Ignored by updateCar"},
+	},
+})
+
+var unitsArg = &graphql.ArgumentConfig{Type: unitsEnum, DefaultValue: unitMiles, Description: "Odometer units of the returned mileage"}
+
+// filterParams maps CarFilter fields to the GET /cars query parameters so
+// both APIs share parseCarQuery.
+var filterParams = map[string]string{
+	"make": "make", "model": "model", "category": "category", "color": "color",
+	"minYear": "min_year", "maxYear": "max_year", "minPrice": "min_price", "maxPrice": "max_price",
+	"minMileage": "min_mileage", "maxMileage": "max_mileage", "status": "status",
+}
+
+func queryFromArgs(args map[string]interface{}) (carQuery, error) {
+	v := url.Values{}
+	v.Set("units", args["units"].(string))
+
+	if filter, ok := args["filter"].(map[string]interface{}); ok {
+		for k, val := range filter {
+			if list, ok := val.([]interface{}); ok {
+				items := []string{}
+				for _, item := range list {
+					items = append(items, fmt.Sprint(item))
+				}
+				v.Set(filterParams[k], strings.Join(items, ","))
+				continue
+			}
+			v.Set(filterParams[k], fmt.Sprint(val))
+		}
+	}
+	if sort, ok := args["sort"].(map[string]interface{}); ok {
+		field := sort["field"].(string)
+		if desc, _ := sort["desc"].(bool); desc {
+			field = "-" + field
+		}
+		v.Set("sort", field)
+	}
+
+	return parseCarQuery(v)
+}
+
+func carFromInput(input map[string]interface{}) Car {
+	c := Car{Id: input["id"].(string)}
+	c.Make, _ = input["make"].(string)
+	c.Model, _ = input["model"].(string)
+	c.Package, _ = input["package"].(string)
+	c.Color, _ = input["color"].(string)
+	c.Year, _ = input["year"].(int)
+	c.Category, _ = input["category"].(string)
+	c.Mileage, _ = input["mileage"].(float64)
+	c.Price, _ = input["price"].(float64)
+	c.MileageUnit, _ = input["mileageUnit"].(string)
+	c.Vin, _ = input["vin"].(string)
+	c.Country, _ = input["country"].(string)
+	c.Status, _ = input["status"].(string)
+	return c
+}
+
+func encodeCursor(id string) string {
+	return base64.StdEncoding.EncodeToString([]byte("car:" + id))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(b), "car:") {
+		return "", fmt.Errorf("invalid cursor")
+	}
+	return strings.TrimPrefix(string(b), "car:"), nil
+}
+
+// paginate returns the page of cars following the after cursor, as a
+// CarConnection.
+func paginate(cars []Car, first int, after string) (map[string]interface{}, error) {
+	start := 0
+	if after != "" {
+		id, err := decodeCursor(after)
+		if err != nil {
+			return nil, err
+		}
+		start = -1
+		for i, c := range cars {
+			if c.Id == id {
+				start = i + 1
+			}
+		}
+		if start == -1 {
+			return nil, fmt.Errorf("invalid cursor")
+		}
+	}
+
+	end := len(cars)
+	if first >= 0 && start+first < end {
+		end = start + first
+	}
+
+	edges := []map[string]interface{}{}
+	var endCursor interface{}
+	for _, c := range cars[start:end] {
+		endCursor = encodeCursor(c.Id)
+		edges = append(edges, map[string]interface{}{"cursor": endCursor, "node": c})
+	}
+
+	return map[string]interface{}{
+		"totalCount": len(cars),
+		"edges":      edges,
+		"pageInfo":   map[string]interface{}{"hasNextPage": end < len(cars), "endCursor": endCursor},
+	}, nil
+}
+
+func newGraphQLSchema() (graphql.Schema, error) {
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"car": &graphql.Field{
+				Type:        carType,
+				Description: "Gets a single car",
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"units": unitsArg,
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					car := Car{Id: p.Args["id"].(string), Dealership: dealershipOf(p.Context)}
+					q, err := car.getCarById()
+					if err != nil {
+						return nil, err
+					}
+					return q.inUnits(p.Args["units"].(string)), nil
+				},
+			},
+			"cars": &graphql.Field{
+				Type:        graphql.NewNonNull(carConnectionType),
+				Description: "Lists cars with the same filters and sort order as GET /cars, paginated by cursor",
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: carFilterType},
+					"sort":   &graphql.ArgumentConfig{Type: carSortType},
+					"first":  &graphql.ArgumentConfig{Type: graphql.Int},
+					"after":  &graphql.ArgumentConfig{Type: graphql.String},
+					"units":  unitsArg,
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					filter, err := queryFromArgs(p.Args)
+					if err != nil {
+						return nil, err
+					}
+
+					car := Car{Dealership: dealershipOf(p.Context)}
+					cars, err := car.getAllCars()
+					if err != nil {
+						return nil, err
+					}
+
+					first := -1
+					if v, ok := p.Args["first"].(int); ok {
+						if v < 0 {
+							return nil, fmt.Errorf("first must be ge 0")
+						}
+						first = v
+					}
+					after, _ := p.Args["after"].(string)
+
+					return paginate(carsInUnits(filter.apply(cars), filter.units), first, after)
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createCar": &graphql.Field{
+				Type: carType,
+				Args: graphql.FieldConfigArgument{
+					"input":          &graphql.ArgumentConfig{Type: graphql.NewNonNull(carInputType)},
+					"allowDuplicate": &graphql.ArgumentConfig{Type: graphql.Boolean, Description: "Creates the car even if it looks like one already listed"},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					car := carFromInput(p.Args["input"].(map[string]interface{}))
+					car.Dealership = dealershipOf(p.Context)
+					allow, _ := p.Args["allowDuplicate"].(bool)
+					return car.createListing(allow)
+				},
+			},
+			"updateCar": &graphql.Field{
+				Type: carType,
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(carInputType)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					car := carFromInput(p.Args["input"].(map[string]interface{}))
+					car.Dealership = dealershipOf(p.Context)
+					actor, _ := p.Context.Value(actorKey).(string)
+					if err := car.checkHold(actor); err != nil {
+						return nil, err
+					}
+					return car.updateCar()
+				},
+			},
+			"deleteCar": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					car := Car{Id: p.Args["id"].(string), Dealership: dealershipOf(p.Context)}
+					actor, _ := p.Context.Value(actorKey).(string)
+					if err := car.checkHold(actor); err != nil {
+						return false, err
+					}
+					if _, err := car.deleteCar(actor); err != nil {
+						return false, err
+					}
+					return true, nil
+				},
+			},
+			"transitionCar": &graphql.Field{
+				Type: carType,
+				Args: graphql.FieldConfigArgument{
+					"id":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"status": &graphql.ArgumentConfig{Type: graphql.NewNonNull(carStatusEnum)},
+					"reason": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					car := Car{Id: p.Args["id"].(string), Dealership: dealershipOf(p.Context)}
+					actor, _ := p.Context.Value(actorKey).(string)
+					if err := car.checkHold(actor); err != nil {
+						return nil, err
+					}
+					t := statusTransition{Status: p.Args["status"].(string)}
+					t.Reason, _ = p.Args["reason"].(string)
+					return car.transitionCar(t, actor)
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+type contextKey string
+
+// actorKey carries the requesting actor to GraphQL resolvers.
+const actorKey contextKey = "actor"
+
+// graphqlHandler serves /graphql over the same store as the REST handlers,
+// holding the cars handler lock while a request executes.
+type graphqlHandler struct {
+	cars     *carHandler
+	schema   graphql.Schema
+	graphiql bool
+}
+
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// ServeHTTP godoc
+// @Summary		Execute a GraphQL request
+// @Description	Runs a GraphQL query or mutation over the car inventory. The schema exposes car(id), cars(filter, sort, first, after) and the createCar, updateCar and deleteCar mutations, and can be introspected. Queries may also be sent with GET, in the query, variables and operationName parameters; mutations must be POSTed. In development mode a GraphiQL page is served to browsers on GET
+// @Tags		graphql
+// @Accept		json
+// @Produce		json
+// @Param		request		body		graphqlRequest		true		"GraphQL request"
+// @Success		200			{object}	object				"GraphQL result with data and errors"
+// @Failure		400			{string}	string				"BadRequest"
+// @Router		/graphql	[post]
+func (h *graphqlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req graphqlRequest
+
+	switch r.Method {
+	case "GET":
+		if h.graphiql && r.URL.Query().Get("query") == "" && strings.Contains(r.Header.Get("accept"), "text/html") {
+			w.Header().Set("content-type", "text/html")
+			w.Write([]byte(graphiqlPage))
+			return
+		}
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if v := r.URL.Query().Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				respondWithError(w, http.StatusBadRequest, "variables must be a JSON object")
+				return
+			}
+		}
+	case "POST":
+		defer r.Body.Close()
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		ct := r.Header.Get("content-type")
+		if ct != "application/json" {
+			respondWithError(w, http.StatusUnsupportedMediaType, "content type 'application/json' required")
+			return
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "invalid method")
+		return
+	}
+
+	if req.Query == "" {
+		respondWithError(w, http.StatusBadRequest, "query field empty")
+		return
+	}
+
+	// Browsers send GET across origins without a preflight, so GET may
+	// only read.
+	if r.Method == "GET" {
+		op, err := operationOf(req.Query, req.OperationName)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if op != ast.OperationTypeQuery {
+			w.Header().Set("allow", "POST")
+			respondWithError(w, http.StatusMethodNotAllowed, op+" must be sent with POST")
+			return
+		}
+	}
+
+	dealership, err := dealershipFromRequest(r)
+	if err != nil {
+		respondWithError(w, dealershipStatus(err), err.Error())
+		return
+	}
+	ctx := context.WithValue(r.Context(), dealershipKey, dealership)
+
+	defer h.cars.Unlock()
+	h.cars.Lock()
+
+	result := graphql.Do(graphql.Params{
+		Schema:         h.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        context.WithValue(ctx, actorKey, actorFromRequest(r)),
+	})
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// operationOf returns the type of the operation a request runs: the one
+// named name, or the only one in the document.
+func operationOf(query, name string) (string, error) {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return "", fmt.Errorf("query is not valid GraphQL")
+	}
+	var found *ast.OperationDefinition
+	for _, d := range doc.Definitions {
+		op, ok := d.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if name == "" && found != nil {
+			return "", fmt.Errorf("operationName required when the query has several operations")
+		}
+		if name == "" || (op.Name != nil && op.Name.Value == name) {
+			found = op
+		}
+	}
+	if found == nil {
+		return "", fmt.Errorf("operation %s not found", name)
+	}
+	return found.Operation, nil
+}
+
+func newGraphQLHandler(cars *carHandler, graphiql bool) *graphqlHandler {
+	schema, err := newGraphQLSchema()
+	if err != nil {
+		panic(err)
+	}
+	return &graphqlHandler{cars: cars, schema: schema, graphiql: graphiql}
+}
+
+// graphiqlPage is the in-browser IDE served at /graphql in development mode.
+const graphiqlPage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Cars GraphiQL</title>
+  <link rel="stylesheet" href="https://unpkg.com/graphiql@3/graphiql.min.css" />
+</head>
+<body style="margin: 0;">
+  <div id="graphiql" style="height: 100vh;"></div>
+  <script crossorigin src="https://unpkg.com/react@18/umd/react.production.min.js"></script>
+  <script crossorigin src="https://unpkg.com/react-dom@18/umd/react-dom.production.min.js"></script>
+  <script crossorigin src="https://unpkg.com/graphiql@3/graphiql.min.js"></script>
+  <script>
+    const fetcher = GraphiQL.createFetcher({ url: '/graphql' });
+    ReactDOM.createRoot(document.getElementById('graphiql')).render(React.createElement(GraphiQL, { fetcher }));
+  </script>
+</body>
+</html>`
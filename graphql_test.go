@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func graphqlDo(t *testing.T, query string) map[string]interface{} {
+	h := newGraphQLHandler(&carHandler{}, false)
+	body, _ := json.Marshal(graphqlRequest{ Query: query })
+	r := httptest.NewRequest("POST", "/graphql", strings.NewReader(string(body)))
+	r.Header.Set("content-type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, w.Code, http.StatusOK)
+	var out map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &out)
+	return out
+}
+
+func TestGraphQL_WhenCreatingAndQueryingCar(t *testing.T){
+	out := graphqlDo(t, `mutation { createCar(input: {id: "gqlcar01", make: "Mazda", model: "CX5", package: "GT", color: "Red", year: 2021, category: "SUV", mileage: 100, price: 3000000}) { id make } }`)
+	assert.Equal(t, out["errors"], nil)
+
+	out = graphqlDo(t, `{ car(id: "gqlcar01", units: km) { model mileage mileageUnit } }`)
+	car := out["data"].(map[string]interface{})["car"].(map[string]interface{})
+	assert.Equal(t, car["model"], "CX5")
+	assert.Equal(t, car["mileage"], 160.93)
+	assert.Equal(t, car["mileageUnit"], "km")
+}
+
+func TestGraphQL_WhenValidationFails(t *testing.T){
+	out := graphqlDo(t, `mutation { createCar(input: {id: "gqlcar02", make: "Mazda"}) { id } }`)
+	errors := out["errors"].([]interface{})
+
+	assert.Equal(t, errors[0].(map[string]interface{})["message"], "model field empty")
+}
+
+func TestGraphQL_WhenPaginatingCars(t *testing.T){
+	graphqlDo(t, `mutation { a: createCar(input: {id: "gqlpage1", make: "Lada", model: "Niva", package: "XX", color: "Red", year: 1990, category: "SUV", mileage: 1, price: 1}) { id }
+		b: createCar(input: {id: "gqlpage2", make: "Lada", model: "Niva", package: "XX", color: "Red", year: 1991, category: "SUV", mileage: 1, price: 2}) { id } }`)
+
+	out := graphqlDo(t, `{ cars(filter: {make: "Lada"}, sort: {field: PRICE, desc: true}, first: 1) { totalCount edges { node { id } } pageInfo { hasNextPage endCursor } } }`)
+	cars := out["data"].(map[string]interface{})["cars"].(map[string]interface{})
+	assert.Equal(t, cars["totalCount"], 2.0)
+	assert.Equal(t, cars["edges"].([]interface{})[0].(map[string]interface{})["node"].(map[string]interface{})["id"], "gqlpage2")
+	cursor := cars["pageInfo"].(map[string]interface{})["endCursor"].(string)
+
+	out = graphqlDo(t, `{ cars(filter: {make: "Lada"}, sort: {field: PRICE, desc: true}, first: 1, after: "` + cursor + `") { edges { node { id } } pageInfo { hasNextPage } } }`)
+	cars = out["data"].(map[string]interface{})["cars"].(map[string]interface{})
+	assert.Equal(t, cars["edges"].([]interface{})[0].(map[string]interface{})["node"].(map[string]interface{})["id"], "gqlpage1")
+	assert.Equal(t, cars["pageInfo"].(map[string]interface{})["hasNextPage"], false)
+}
+
+func TestGraphQL_WhenIntrospecting(t *testing.T){
+	out := graphqlDo(t, `{ __type(name: "Car") { fields { name } } }`)
+
+	assert.Equal(t, out["errors"], nil)
+	assert.Equal(t, len(out["data"].(map[string]interface{})["__type"].(map[string]interface{})["fields"].([]interface{})), 16)
+}
+
+func TestGraphQL_WhenCreatingDuplicate(t *testing.T){
+	create := `mutation { createCar(input: {id: "%s", make: "Moskvitch", model: "412", package: "IE", color: "Blue", year: 1984, category: "Sedan", mileage: 50000, price: 300000}%s) { id } }`
+	graphqlDo(t, fmt.Sprintf(create, "gqldup01", ""))
+
+	out := graphqlDo(t, fmt.Sprintf(create, "gqldup02", ""))
+	errors := out["errors"].([]interface{})
+	assert.Contains(t, errors[0].(map[string]interface{})["message"], "possible duplicate")
+
+	out = graphqlDo(t, fmt.Sprintf(create, "gqldup03", ", allowDuplicate: true"))
+	assert.Equal(t, out["errors"], nil)
+}
+
+func TestGraphQL_WhenMutationSentWithGet(t *testing.T){
+	h := newGraphQLHandler(&carHandler{}, false)
+	mutation := url.QueryEscape(`mutation { deleteCar(id: "gqlcar01") }`)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/graphql?query="+mutation, nil))
+
+	assert.Equal(t, w.Code, http.StatusMethodNotAllowed)
+	assert.Equal(t, w.Header().Get("allow"), "POST")
+
+	query := url.QueryEscape(`query Named { car(id: "gqlcar01") { id } } mutation Other { deleteCar(id: "gqlcar01") }`)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/graphql?operationName=Named&query="+query, nil))
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/graphql?operationName=Other&query="+query, nil))
+	assert.Equal(t, w.Code, http.StatusMethodNotAllowed)
+}
+
+func TestGraphQL_WhenUsingVariablesAndFragments(t *testing.T){
+	h := newGraphQLHandler(&carHandler{}, false)
+	body, _ := json.Marshal(graphqlRequest{
+		Query: `mutation Create($input: CarInput!) { created: createCar(input: $input) { ...Fields } }
+			query Get($id: ID!, $units: Units = km, $withColor: Boolean!) { car(id: $id, units: $units) { ...Fields color @include(if: $withColor) } }
+			fragment Fields on Car { id __typename mileage mileageUnit }`,
+		OperationName: "Create",
+		Variables: map[string]interface{}{"input": map[string]interface{}{"id": "gqlvar01", "make": "Tatra", "model": "603", "package": "2", "color": "Black", "year": 1962, "category": "Sedan", "mileage": 100, "price": 900000}},
+	})
+	r := httptest.NewRequest("POST", "/graphql", strings.NewReader(string(body)))
+	r.Header.Set("content-type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, w.Body.String(), `{"data":{"created":{"__typename":"Car","id":"gqlvar01","mileage":100,"mileageUnit":"mi"}}}`)
+
+	variables := url.QueryEscape(`{"id": "gqlvar01", "withColor": false}`)
+	query := url.QueryEscape(`query Get($id: ID!, $units: Units = km, $withColor: Boolean!) { car(id: $id, units: $units) { id mileage color @include(if: $withColor) } }`)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/graphql?query="+query+"&variables="+variables, nil))
+	assert.Equal(t, w.Body.String(), `{"data":{"car":{"id":"gqlvar01","mileage":160.93}}}`)
+}
+
+func TestGraphQL_WhenDocumentIsInvalid(t *testing.T){
+	out := graphqlDo(t, `mutation { a: createCar(input: {id: "gqlinv01", make: "Tatra", model: "603", package: "2", color: "Black", year: 1962, category: "Sedan", mileage: 1, price: 1}) { id }
+		b: deleteCar(id: "gqlinv01") { id } }`)
+
+	assert.Equal(t, out["data"], nil)
+	errors := out["errors"].([]interface{})
+	assert.Equal(t, errors[0].(map[string]interface{})["message"], `Field "deleteCar" of type "Boolean" must not have a sub selection.`)
+	_, err := db.getById("gqlinv01")
+	assert.Equal(t, err, errIdNotFound)
+
+	out = graphqlDo(t, `{ car(id: "gqlinv01", units: furlongs) { id } }`)
+	errors = out["errors"].([]interface{})
+	assert.Equal(t, errors[0].(map[string]interface{})["message"], "Argument \"units\" has invalid value furlongs.\nExpected type \"Units\", found furlongs.")
+
+	out = graphqlDo(t, `{ a: car(id: "gqlinv01") { id } a: cars { id } }`)
+	errors = out["errors"].([]interface{})
+	assert.Equal(t, errors[0].(map[string]interface{})["message"], `Fields "a" conflict because car and cars are different fields. Use different aliases on the fields to fetch both if this was intentional.`)
+}
+
+func TestGraphQL_WhenIntrospectingSchema(t *testing.T){
+	out := graphqlDo(t, `{ __schema { queryType { name } mutationType { name } types { name kind } directives { name } }
+		__type(name: "CarSort") { inputFields { name defaultValue type { kind ofType { name } } } } }`)
+
+	assert.Equal(t, out["errors"], nil)
+	data := out["data"].(map[string]interface{})
+	schema := data["__schema"].(map[string]interface{})
+	assert.Equal(t, schema["queryType"], map[string]interface{}{"name": "Query"})
+	assert.Equal(t, schema["mutationType"], map[string]interface{}{"name": "Mutation"})
+	assert.Contains(t, schema["types"], map[string]interface{}{"name": "CarStatus", "kind": "ENUM"})
+	assert.Equal(t, len(schema["directives"].([]interface{})), 3)
+
+	fields := data["__type"].(map[string]interface{})["inputFields"].([]interface{})
+	assert.Contains(t, fields, map[string]interface{}{"name": "field", "defaultValue": nil, "type": map[string]interface{}{"kind": "NON_NULL", "ofType": map[string]interface{}{"name": "CarSortField"}}})
+	assert.Contains(t, fields, map[string]interface{}{"name": "desc", "defaultValue": "false", "type": map[string]interface{}{"kind": "SCALAR", "ofType": nil}})
+}
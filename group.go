@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+)
+
+// groupHandler serves the cross-dealership read view of group admins.
+type groupHandler struct {
+	cars *carHandler
+}
+
+func (h *groupHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, err := userFromRequest(r)
+	if err != nil {
+		respondWithError(w, dealershipStatus(err), err.Error())
+		return
+	}
+	if user == "" {
+		respondWithError(w, http.StatusUnauthorized, errAuthRequired.Error())
+		return
+	}
+	if !dealerships.isAdmin(user) {
+		respondWithError(w, http.StatusForbidden, "group admin required")
+		return
+	}
+
+	if r.Method != "GET" {
+		respondWithError(w, http.StatusMethodNotAllowed, "invalid method")
+		return
+	}
+
+	switch r.URL.Path {
+	case "/group/cars":
+		h.getCars(w, r)
+	case "/group/dealerships":
+		h.getDealerships(w, r)
+	default:
+		respondWithError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// getCars godoc
+// @Summary		Get the cars of every dealership
+// @Description Lists the cars of all dealerships, or only of the one in the dealership filter. Requires a group admin
+// @Tags		group
+// @Accept		json
+// @Produce		json
+// @Security	BasicAuth
+// @Param		dealership	query			string			false			"Filter by dealership"
+// @Param		units		query			string			false			"Odometer units of the response and mileage filters"	Enums(mi, km)
+// @Param		make		query			string			false			"Filter by make"
+// @Param		model		query			string			false			"Filter by model"
+// @Param		category	query			string			false			"Filter by category"
+// @Param		color		query			string			false			"Filter by color"
+// @Param		status		query			string			false			"Filter by status, comma separated: incoming, in_transit, available, reserved, sold"
+// @Param		min_year	query			int				false			"Minimum year"
+// @Param		max_year	query			int				false			"Maximum year"
+// @Param		min_price	query			number			false			"Minimum price"
+// @Param		max_price	query			number			false			"Maximum price"
+// @Param		min_mileage	query			number			false			"Minimum mileage, in units"
+// @Param		max_mileage	query			number			false			"Maximum mileage, in units"
+// @Param		sort		query			string			false			"Sort field, prefix with - for descending"
+// @Success		200 		{array} 		Car				"OK"
+// @Failure		400			{string}		string			"BadRequest"
+// @Failure		403			{string}		string			"Forbidden"
+// @Failure		404			{string}		string			"NotFound"
+// @Router		/group/cars	[get]
+func (h *groupHandler) getCars(w http.ResponseWriter, r *http.Request) {
+	defer h.cars.Unlock()
+	h.cars.Lock()
+
+	filter, err := parseCarQuery(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	names := dealerships.names()
+	if d := r.URL.Query().Get("dealership"); d != "" {
+		if _, err := dealerships.get(d); err != nil {
+			respondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		names = []string{d}
+	}
+
+	all := []Car{}
+	for _, name := range names {
+		car := Car{Dealership: name}
+		q, err := car.getAllCars()
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		for _, v := range q {
+			v.Dealership = name
+			all = append(all, v)
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, carsInUnits(filter.apply(all), filter.units))
+}
+
+// getDealerships godoc
+// @Summary		List dealerships
+// @Description Lists the names of the dealerships. Requires a group admin
+// @Tags		group
+// @Accept		json
+// @Produce		json
+// @Security	BasicAuth
+// @Success		200 				{array} 		string			"OK"
+// @Failure		403					{string}		string			"Forbidden"
+// @Router		/group/dealerships	[get]
+func (h *groupHandler) getDealerships(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, dealerships.names())
+}
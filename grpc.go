@@ -0,0 +1,343 @@
+//go:generate protoc -I proto --go_out=carpb --go_opt=paths=source_relative --go-grpc_out=carpb --go-grpc_opt=paths=source_relative cars.proto
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"example/cars/carpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// carServer implements carpb.CarServiceServer over the same store as the
+// REST handlers, holding the cars handler lock while a call executes.
+type carServer struct {
+	carpb.UnimplementedCarServiceServer
+	cars *carHandler
+}
+
+func newGRPCServer(cars *carHandler) *grpc.Server {
+	s := grpc.NewServer()
+	carpb.RegisterCarServiceServer(s, &carServer{cars: cars})
+	return s
+}
+
+func (s *carServer) Get(ctx context.Context, req *carpb.GetCarRequest) (*carpb.Car, error) {
+	units, err := parseUnits(req.Units)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	dealership, err := dealershipFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.cars.Unlock()
+	s.cars.Lock()
+
+	car := Car{Id: req.Id, Dealership: dealership}
+	c, err := car.getCarById()
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return carToProto(c.inUnits(units)), nil
+}
+
+func (s *carServer) List(req *carpb.ListCarsRequest, stream carpb.CarService_ListServer) error {
+	filter, err := parseCarQuery(listValues(req))
+	if err != nil {
+		return grpcError(err)
+	}
+	dealership, err := dealershipFromContext(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	s.cars.Lock()
+	car := Car{Dealership: dealership}
+	cars, err := car.getAllCars()
+	s.cars.Unlock()
+	if err != nil {
+		return grpcError(err)
+	}
+
+	for _, c := range carsInUnits(filter.apply(cars), filter.units) {
+		if err := stream.Send(carToProto(c)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *carServer) Create(ctx context.Context, req *carpb.CreateCarRequest) (*carpb.Car, error) {
+	dealership, err := dealershipFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.cars.Unlock()
+	s.cars.Lock()
+
+	car := carFromProto(req.Car)
+	car.Dealership = dealership
+	c, err := car.createListing(false)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return carToProto(c), nil
+}
+
+func (s *carServer) Update(ctx context.Context, req *carpb.UpdateCarRequest) (*carpb.Car, error) {
+	dealership, err := dealershipFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.cars.Unlock()
+	s.cars.Lock()
+
+	car := carFromProto(req.Car)
+	car.Dealership = dealership
+	if err := car.checkHold(actorFromContext(ctx)); err != nil {
+		return nil, grpcError(err)
+	}
+	c, err := car.updateCar()
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return carToProto(c), nil
+}
+
+func (s *carServer) Delete(ctx context.Context, req *carpb.DeleteCarRequest) (*carpb.DeleteCarResponse, error) {
+	dealership, err := dealershipFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.cars.Unlock()
+	s.cars.Lock()
+
+	car := Car{Id: req.Id, Dealership: dealership}
+	if err := car.checkHold(actorFromContext(ctx)); err != nil {
+		return nil, grpcError(err)
+	}
+	if _, err := car.deleteCar(actorFromContext(ctx)); err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &carpb.DeleteCarResponse{}, nil
+}
+
+func (s *carServer) Transition(ctx context.Context, req *carpb.TransitionCarRequest) (*carpb.Car, error) {
+	dealership, err := dealershipFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.cars.Unlock()
+	s.cars.Lock()
+
+	car := Car{Id: req.Id, Dealership: dealership}
+	if err := car.checkHold(actorFromContext(ctx)); err != nil {
+		return nil, grpcError(err)
+	}
+	q, err := car.transitionCar(statusTransition{Status: req.Status, Reason: req.Reason}, actorFromContext(ctx))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return carToProto(q), nil
+}
+
+// Watch streams car events like GET /cars/events, resuming after
+// last_event_id. A "reset" event is sent first when events after
+// last_event_id were already evicted from the replay buffer, or when
+// last_event_id is from before the server restarted.
+func (s *carServer) Watch(req *carpb.WatchRequest, stream carpb.CarService_WatchServer) error {
+	dealership, err := dealershipFromContext(stream.Context())
+	if err != nil {
+		return err
+	}
+	filter := eventFilter{dealership: dealership, make: req.Make, category: req.Category}
+
+	ch, backlog, complete := s.cars.stream.subscribe(req.LastEventId)
+	defer s.cars.stream.unsubscribe(ch)
+
+	if req.LastEventId != 0 && !complete {
+		if err := stream.Send(&carpb.CarEvent{Type: "reset"}); err != nil {
+			return err
+		}
+	}
+	for _, e := range backlog {
+		if filter.match(e) {
+			if err := stream.Send(eventToProto(e)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "client too slow")
+			}
+			if filter.match(e) {
+				if err := stream.Send(eventToProto(e)); err != nil {
+					return err
+				}
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// grpcError maps the errors shared with the REST handlers to gRPC status
+// codes, the way the controllers map them to HTTP statuses.
+func grpcError(err error) error {
+	msg := err.Error()
+	var duplicate *duplicateError
+	switch {
+	case errors.Is(err, errIdNotFound):
+		return status.Error(codes.NotFound, msg)
+	case errors.Is(err, errIdExists), errors.As(err, &duplicate):
+		return status.Error(codes.AlreadyExists, msg)
+	case errors.Is(err, errTransition), errors.Is(err, errHeld):
+		return status.Error(codes.FailedPrecondition, msg)
+	case errors.Is(err, errWal):
+		return status.Error(codes.Internal, msg)
+	default:
+		return status.Error(codes.InvalidArgument, msg)
+	}
+}
+
+// dealershipFromContext resolves the dealership of a call from the
+// x-dealership and authorization metadata.
+func dealershipFromContext(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	r := &http.Request{Header: http.Header{}}
+	for _, v := range md.Get("authorization") {
+		r.Header.Set("Authorization", v)
+	}
+	for _, v := range md.Get("x-dealership") {
+		r.Header.Set("X-Dealership", v)
+	}
+
+	dealership, err := dealershipFromRequest(r)
+	if err != nil {
+		switch dealershipStatus(err) {
+		case http.StatusNotFound:
+			return "", status.Error(codes.NotFound, err.Error())
+		case http.StatusUnauthorized:
+			return "", status.Error(codes.Unauthenticated, err.Error())
+		default:
+			return "", status.Error(codes.PermissionDenied, err.Error())
+		}
+	}
+	return dealership, nil
+}
+
+// actorFromContext identifies the user authenticated by the authorization
+// metadata, as actorFromRequest does over HTTP.
+func actorFromContext(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	r := &http.Request{Header: http.Header{}}
+	for _, v := range md.Get("authorization") {
+		r.Header.Set("Authorization", v)
+	}
+	return actorFromRequest(r)
+}
+
+// listValues maps ListCarsRequest to the GET /cars query parameters so
+// both APIs share parseCarQuery. Zero values mean no filter.
+func listValues(req *carpb.ListCarsRequest) url.Values {
+	v := url.Values{}
+	set := func(key, val string) {
+		if val != "" {
+			v.Set(key, val)
+		}
+	}
+	setInt := func(key string, val int32) {
+		if val != 0 {
+			v.Set(key, strconv.Itoa(int(val)))
+		}
+	}
+	setFloat := func(key string, val float64) {
+		if val != 0 {
+			v.Set(key, strconv.FormatFloat(val, 'f', -1, 64))
+		}
+	}
+
+	set("make", req.Make)
+	set("model", req.Model)
+	set("category", req.Category)
+	set("color", req.Color)
+	set("status", req.Status)
+	set("sort", req.Sort)
+	set("units", req.Units)
+	setInt("min_year", req.MinYear)
+	setInt("max_year", req.MaxYear)
+	setFloat("min_price", req.MinPrice)
+	setFloat("max_price", req.MaxPrice)
+	setFloat("min_mileage", req.MinMileage)
+	setFloat("max_mileage", req.MaxMileage)
+	return v
+}
+
+func carToProto(c Car) *carpb.Car {
+	return &carpb.Car{
+		Id:            c.Id,
+		Make:          c.Make,
+		Model:         c.Model,
+		Package:       c.Package,
+		Color:         c.Color,
+		Year:          int32(c.Year),
+		Category:      c.Category,
+		Mileage:       c.Mileage,
+		Price:         c.Price,
+		MileageUnit:   c.MileageUnit,
+		Dealership:    c.Dealership,
+		Status:        c.Status,
+		PreviousPrice: c.PreviousPrice,
+		Vin:           c.Vin,
+		Country:       c.Country,
+		VinWarnings:   c.VinWarnings,
+	}
+}
+
+func carFromProto(c *carpb.Car) Car {
+	if c == nil {
+		return Car{}
+	}
+	return Car{
+		Id:          c.Id,
+		Make:        c.Make,
+		Model:       c.Model,
+		Package:     c.Package,
+		Color:       c.Color,
+		Year:        int(c.Year),
+		Category:    c.Category,
+		Mileage:     c.Mileage,
+		Price:       c.Price,
+		MileageUnit: c.MileageUnit,
+		Status:      c.Status,
+		Vin:         c.Vin,
+		Country:     c.Country,
+	}
+}
+
+func eventToProto(e carEvent) *carpb.CarEvent {
+	return &carpb.CarEvent{Id: e.Id, Type: e.Type, AtUnixNano: e.At.UnixNano(), Car: carToProto(e.Car)}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"example/cars/carpb"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func grpcClient(t *testing.T, h *carHandler) carpb.CarServiceClient {
+	lis := bufconn.Listen(1 << 20)
+	s := newGRPCServer(h)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Equal(t, err, nil)
+	t.Cleanup(func() { conn.Close() })
+
+	return carpb.NewCarServiceClient(conn)
+}
+
+func TestGRPC_WhenCreatingAndGettingCar(t *testing.T){
+	client := grpcClient(t, &carHandler{ stream: newEventStream(10, 0) })
+	ctx := context.Background()
+
+	_, err := client.Create(ctx, &carpb.CreateCarRequest{ Car: &carpb.Car{ Id: "grpccar1", Make: "Kia", Model: "Rio", Package: "LX", Color: "Blue", Year: 2020, Category: "Sedan", Mileage: 100, Price: 1500000 } })
+	assert.Equal(t, err, nil)
+
+	car, err := client.Get(ctx, &carpb.GetCarRequest{ Id: "grpccar1", Units: "km" })
+	assert.Equal(t, err, nil)
+	assert.Equal(t, car.Model, "Rio")
+	assert.Equal(t, car.Mileage, 160.93)
+	assert.Equal(t, car.MileageUnit, "km")
+}
+
+func TestGRPC_WhenErrorsAreMappedToStatusCodes(t *testing.T){
+	client := grpcClient(t, &carHandler{ stream: newEventStream(10, 0) })
+	ctx := context.Background()
+
+	_, err := client.Get(ctx, &carpb.GetCarRequest{ Id: "grpcmissing" })
+	assert.Equal(t, status.Code(err), codes.NotFound)
+
+	_, err = client.Create(ctx, &carpb.CreateCarRequest{ Car: &carpb.Car{ Id: "grpccar2", Make: "Kia" } })
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+	assert.Equal(t, status.Convert(err).Message(), "model field empty")
+
+	car := &carpb.Car{ Id: "grpccar3", Make: "Kia", Model: "Soul", Package: "EX", Color: "Green", Year: 2021, Category: "SUV", Mileage: 10, Price: 2000000 }
+	client.Create(ctx, &carpb.CreateCarRequest{ Car: car })
+	_, err = client.Create(ctx, &carpb.CreateCarRequest{ Car: car })
+	assert.Equal(t, status.Code(err), codes.AlreadyExists)
+	car.Id = "grpccar4"
+	_, err = client.Create(ctx, &carpb.CreateCarRequest{ Car: car })
+	assert.Equal(t, status.Code(err), codes.AlreadyExists)
+	assert.Equal(t, status.Convert(err).Message(), "possible duplicate of grpccar3")
+
+	_, err = client.Delete(ctx, &carpb.DeleteCarRequest{ Id: "grpcmissing" })
+	assert.Equal(t, status.Code(err), codes.NotFound)
+}
+
+func TestGRPC_WhenNumbersAreNotFinite(t *testing.T){
+	client := grpcClient(t, &carHandler{ stream: newEventStream(10, 0) })
+	ctx := context.Background()
+
+	car := &carpb.Car{ Id: "grpcnan1", Make: "Kia", Model: "Ceed", Package: "GT", Color: "Red", Year: 2022, Category: "Hatchback", Mileage: math.Inf(1), Price: math.NaN() }
+	_, err := client.Create(ctx, &carpb.CreateCarRequest{ Car: car })
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+	assert.Equal(t, status.Convert(err).Message(), "mileage field must be ge 0")
+
+	car.Mileage = 10
+	_, err = client.Create(ctx, &carpb.CreateCarRequest{ Car: car })
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+	assert.Equal(t, status.Convert(err).Message(), "price field must be gt 0")
+
+	car.Price = 1800000
+	_, err = client.Create(ctx, &carpb.CreateCarRequest{ Car: car })
+	assert.Equal(t, err, nil)
+
+	car.Price = math.Inf(-1)
+	_, err = client.Update(ctx, &carpb.UpdateCarRequest{ Car: car })
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+	car.Price = 1800000
+	car.Mileage = math.NaN()
+	_, err = client.Update(ctx, &carpb.UpdateCarRequest{ Car: car })
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+}
+
+func TestGRPC_WhenListingFilteredCars(t *testing.T){
+	client := grpcClient(t, &carHandler{ stream: newEventStream(10, 0) })
+	ctx := context.Background()
+
+	for _, c := range []*carpb.Car{
+		{ Id: "grpclist1", Make: "Dacia", Model: "Duster", Package: "SE", Color: "Grey", Year: 2019, Category: "SUV", Mileage: 10, Price: 900000 },
+		{ Id: "grpclist2", Make: "Dacia", Model: "Sandero", Package: "SE", Color: "Grey", Year: 2020, Category: "Hatchback", Mileage: 10, Price: 700000 },
+	} {
+		client.Create(ctx, &carpb.CreateCarRequest{ Car: c })
+	}
+
+	stream, err := client.List(ctx, &carpb.ListCarsRequest{ Make: "Dacia", Sort: "price" })
+	assert.Equal(t, err, nil)
+
+	ids := []string{}
+	for {
+		c, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		ids = append(ids, c.Id)
+	}
+	assert.Equal(t, ids, []string{"grpclist2", "grpclist1"})
+
+	stream, _ = client.List(ctx, &carpb.ListCarsRequest{ Sort: "colour" })
+	_, err = stream.Recv()
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+}
+
+func TestGRPC_WhenWatchingEvents(t *testing.T){
+	h := &carHandler{ stream: newEventStream(10, 0) }
+	client := grpcClient(t, h)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	h.stream.publish(carEvent{ Id: 1, Type: eventCreated, Car: Car{ Id: "a", Make: "Ford" } })
+	h.stream.publish(carEvent{ Id: 2, Type: eventCreated, Car: Car{ Id: "b", Make: "Kia" } })
+
+	stream, err := client.Watch(ctx, &carpb.WatchRequest{ Make: "kia" })
+	assert.Equal(t, err, nil)
+
+	e, err := stream.Recv()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, e.Id, uint64(2))
+	assert.Equal(t, e.Car.Id, "b")
+}
+
+func TestGRPC_WhenDeadlineExpires(t *testing.T){
+	h := &carHandler{ stream: newEventStream(10, 0) }
+	client := grpcClient(t, h)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	stream, err := client.Watch(ctx, &carpb.WatchRequest{})
+	assert.Equal(t, err, nil)
+	_, err = stream.Recv()
+	assert.Equal(t, status.Code(err), codes.DeadlineExceeded)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// now is the clock used to timestamp mutations. Tests replace it.
+var now = time.Now
+
+// Actions recorded in a car's history.
+const (
+	actionCreated      = "created"
+	actionUpdated      = "updated"
+	actionDeleted      = "deleted"
+	actionRestored     = "restored"
+	actionTransitioned = "transitioned"
+)
+
+// fieldChange is the before and after value of a single field.
+type fieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// carVersion is an immutable snapshot of a car taken after a mutation.
+// @Description a recorded version of a car
+type carVersion struct {
+	Version int                    `json:"version"`
+	Action  string                 `json:"action" enums:"created,updated,deleted,restored,transitioned"`
+	At      time.Time              `json:"at"`
+	Car     Car                    `json:"car"`
+	Changes map[string]fieldChange `json:"changes"`
+	Actor   string                 `json:"actor,omitempty"`
+	Reason  string                 `json:"reason,omitempty"`
+}
+
+// carHistory is the append-only list of versions of every car stored and
+// not yet purged, keyed by id.
+type carHistory struct {
+	versions map[string][]carVersion
+}
+
+func (h *carHistory) record(action string, c Car) {
+	if h.versions == nil {
+		h.versions = map[string][]carVersion{}
+	}
+
+	prev := Car{}
+	if n := len(h.versions[c.Id]); n > 0 {
+		prev = h.versions[c.Id][n-1].Car
+	}
+
+	changes := map[string]fieldChange{}
+	if action != actionDeleted {
+		changes = diffCars(prev, c)
+	}
+
+	h.versions[c.Id] = append(h.versions[c.Id], carVersion{
+		Version: len(h.versions[c.Id]) + 1,
+		Action:  action,
+		At:      now(),
+		Car:     c,
+		Changes: changes,
+	})
+}
+
+// recordBy records a version made by actor for the given reason.
+func (h *carHistory) recordBy(action string, c Car, actor, reason string) {
+	h.record(action, c)
+	v := h.versions[c.Id]
+	v[len(v)-1].Actor = actor
+	v[len(v)-1].Reason = reason
+}
+
+// drop forgets every version of the car with the given id.
+func (h *carHistory) drop(id string) {
+	delete(h.versions, id)
+}
+
+func (h *carHistory) list(id string) ([]carVersion, error) {
+	versions, ok := h.versions[id]
+	if !ok {
+		return []carVersion{}, errIdNotFound
+	}
+	return versions, nil
+}
+
+// asOf returns the car as it was at t.
+func (h *carHistory) asOf(id string, t time.Time) (Car, error) {
+	found := false
+	car := Car{}
+	for _, v := range h.versions[id] {
+		if v.At.After(t) {
+			break
+		}
+		found = v.Action != actionDeleted
+		car = v.Car
+	}
+	if !found {
+		return Car{}, errIdNotFound
+	}
+	return car, nil
+}
+
+// diffCars lists the fields, by json name, whose value differs between a
+// and b.
+func diffCars(a, b Car) map[string]fieldChange {
+	changes := map[string]fieldChange{}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	for i := 0; i < va.NumField(); i++ {
+		name := strings.Split(va.Type().Field(i).Tag.Get("json"), ",")[0]
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			changes[name] = fieldChange{From: va.Field(i).Interface(), To: vb.Field(i).Interface()}
+		}
+	}
+	return changes
+}
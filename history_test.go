@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory_WhenCarMutated(t *testing.T){
+	start := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	clock := start
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	var h carHistory
+	car := Car{ Id: "hist", Make: "Nissan", Price: 1000 }
+	h.record(actionCreated, car)
+	clock = start.Add(time.Hour)
+	car.Price = 900
+	h.record(actionUpdated, car)
+	clock = start.Add(2 * time.Hour)
+	h.record(actionDeleted, car)
+
+	versions, err := h.list("hist")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(versions), 3)
+	assert.Equal(t, versions[1].Changes, map[string]fieldChange{ "Price": { From: 1000.0, To: 900.0 } })
+
+	old, err := h.asOf("hist", start.Add(30 * time.Minute))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, old.Price, 1000.0)
+
+	_, err = h.asOf("hist", start.Add(3 * time.Hour))
+	assert.Equal(t, err.Error(), "id not found")
+
+	_, err = h.asOf("hist", start.Add(-time.Hour))
+	assert.Equal(t, err.Error(), "id not found")
+}
+
+func TestGetCarHistory_WhenIdNotFound(t *testing.T){
+	car := Car{ Id: "zzzzzzzzz" }
+	_, err := car.getCarHistory()
+
+	assert.Equal(t, err.Error(), "id not found")
+}
+
+func TestGetCarHistory_WhenSuccessful(t *testing.T){
+	car := Car{ Id: "histhist", Make: "Nissan", Model: "Sentra", Package: "XX", Color: "Gray", Year: 2013, Category: "Sedan", Mileage: 799, Price: 2499000 }
+	car.createCar()
+	car.Color = "Blue"
+	car.updateCar()
+	versions, err := car.getCarHistory()
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(versions), 2)
+	assert.Equal(t, versions[1].Changes["Color"], fieldChange{ From: "Gray", To: "Blue" })
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultIdempotencyTTL is how long a response is replayed for its
+// Idempotency-Key.
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// multipartOverhead is the room left for the multipart framing and form
+// fields of a media upload on top of the file itself.
+const multipartOverhead = 1 << 20
+
+// maxIdempotentBodyBytes is the largest body read to fingerprint a
+// request: that of a media upload of the largest allowed size.
+func maxIdempotentBodyBytes() int64 {
+	return maxMediaBytes + multipartOverhead
+}
+
+// storedResponse is the response to the first request made with an
+// Idempotency-Key. done is closed once the response is complete; until
+// then repeated requests wait for it.
+type storedResponse struct {
+	fingerprint string
+	done        chan struct{}
+	stored      bool
+	status      int
+	header      http.Header
+	body        []byte
+	expires     time.Time
+}
+
+// idempotencyHandler replays the stored response of unsafe requests
+// repeated with the same Idempotency-Key. Keys are scoped to the
+// authenticated user and the dealership it names; anonymous requests share
+// a scope, as they can only act on the default dealership.
+// Server errors and requests that panic are not stored, so the request can
+// be retried.
+type idempotencyHandler struct {
+	sync.Mutex
+	next      http.Handler
+	ttl       time.Duration
+	responses map[string]*storedResponse
+	nextSweep time.Time
+}
+
+func newIdempotencyHandler(next http.Handler, ttl time.Duration) *idempotencyHandler {
+	return &idempotencyHandler{next: next, ttl: ttl, responses: map[string]*storedResponse{}}
+}
+
+func unsafeMethod(method string) bool {
+	return method == "POST" || method == "PUT" || method == "PATCH" || method == "DELETE"
+}
+
+// fingerprint identifies the request a key was first used for.
+func fingerprint(r *http.Request, body []byte) string {
+	sum := sha256.New()
+	sum.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	sum.Write(body)
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+func (h *idempotencyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" || !unsafeMethod(r.Method) {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodyBytes()))
+	r.Body.Close()
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	user, err := userFromRequest(r)
+	if err != nil {
+		respondWithError(w, dealershipStatus(err), err.Error())
+		return
+	}
+	fp := fingerprint(r, body)
+	scoped := user + "\x00" + r.Header.Get("X-Dealership") + "\x00" + key
+
+	for {
+		h.Lock()
+		h.sweep()
+		res, ok := h.responses[scoped]
+		if ok && res.stored && now().After(res.expires) {
+			delete(h.responses, scoped)
+			ok = false
+		}
+		if !ok {
+			res = &storedResponse{fingerprint: fp, done: make(chan struct{})}
+			h.responses[scoped] = res
+			h.Unlock()
+
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			h.record(w, r, scoped, res)
+			return
+		}
+		h.Unlock()
+
+		if res.fingerprint != fp {
+			respondWithError(w, http.StatusUnprocessableEntity, "Idempotency-Key already used for a different request")
+			return
+		}
+
+		select {
+		case <-res.done:
+		case <-r.Context().Done():
+			return
+		}
+		if res.stored {
+			replay(w, res)
+			return
+		}
+		// The first attempt failed and was forgotten; run this one instead.
+	}
+}
+
+// record serves the request and stores its response under key once it is
+// complete. A panic drops the key and is passed on.
+func (h *idempotencyHandler) record(w http.ResponseWriter, r *http.Request, key string, res *storedResponse) {
+	rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+	defer func() {
+		p := recover()
+		h.Lock()
+		if p == nil && rec.status < 500 {
+			res.stored = true
+			res.status = rec.status
+			res.header = rec.header
+			res.body = rec.body.Bytes()
+			res.expires = now().Add(h.ttl)
+		} else {
+			delete(h.responses, key)
+		}
+		h.Unlock()
+		close(res.done)
+		if p != nil {
+			panic(p)
+		}
+	}()
+
+	h.next.ServeHTTP(rec, r)
+}
+
+func replay(w http.ResponseWriter, res *storedResponse) {
+	for k, v := range res.header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(res.status)
+	w.Write(res.body)
+}
+
+// sweep forgets expired responses, at most once a minute. h must be
+// locked.
+func (h *idempotencyHandler) sweep() {
+	t := now()
+	if t.Before(h.nextSweep) {
+		return
+	}
+	for k, res := range h.responses {
+		if res.stored && t.After(res.expires) {
+			delete(h.responses, k)
+		}
+	}
+	h.nextSweep = t.Add(time.Minute)
+}
+
+// responseRecorder writes the response through to the client while
+// keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	header      http.Header
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.wroteHeader = true
+	rec.status = status
+	rec.header = rec.ResponseWriter.Header().Clone()
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
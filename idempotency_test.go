@@ -0,0 +1,154 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func idempotentPost(h http.Handler, key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", "/cars", strings.NewReader(body))
+	r.Header.Set("content-type", "application/json")
+	r.Header.Set("Idempotency-Key", key)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestIdempotency_WhenPostIsRetried(t *testing.T){
+	h := newIdempotencyHandler(&carHandler{}, time.Hour)
+	body := `{"id":"idemcar1","make":"Fiat","model":"500","package":"Pop","color":"White","year":2020,"category":"Hatchback","mileage":10,"price":1200000}`
+
+	first := idempotentPost(h, "key-1", body)
+	second := idempotentPost(h, "key-1", body)
+
+	assert.Equal(t, first.Code, http.StatusCreated)
+	assert.Equal(t, second.Code, http.StatusCreated)
+	assert.Equal(t, second.Body.String(), first.Body.String())
+	assert.Equal(t, second.Header().Get("Idempotent-Replayed"), "true")
+
+	third := idempotentPost(h, "key-2", body)
+	assert.Equal(t, third.Code, http.StatusBadRequest)
+}
+
+func TestIdempotency_WhenKeyReusedWithDifferentBody(t *testing.T){
+	h := newIdempotencyHandler(&carHandler{}, time.Hour)
+	idempotentPost(h, "key-3", `{"id":"idemcar2"}`)
+
+	w := idempotentPost(h, "key-3", `{"id":"idemcar3"}`)
+
+	assert.Equal(t, w.Code, http.StatusUnprocessableEntity)
+}
+
+func TestIdempotency_WhenBodyTooLarge(t *testing.T){
+	defer func(n int64) { maxMediaBytes = n }(maxMediaBytes)
+	maxMediaBytes = 16
+	var calls int32
+	h := newIdempotencyHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}), time.Hour)
+
+	w := idempotentPost(h, "key-large", strings.Repeat("x", int(maxIdempotentBodyBytes())+1))
+
+	assert.Equal(t, w.Code, http.StatusRequestEntityTooLarge)
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(0))
+}
+
+func TestIdempotency_WhenDuplicatesAreConcurrent(t *testing.T){
+	var calls int32
+	h := newIdempotencyHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(20 * time.Millisecond)
+		respondWithJSON(w, http.StatusCreated, map[string]string{"ok": "yes"})
+	}), time.Hour)
+
+	var wg sync.WaitGroup
+	codes := make([]int, 5)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = idempotentPost(h, "key-4", `{}`).Code
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(1))
+	assert.Equal(t, codes, []int{201, 201, 201, 201, 201})
+}
+
+func TestIdempotency_WhenResponseExpiredOrFailed(t *testing.T){
+	var calls int32
+	status := http.StatusInternalServerError
+	h := newIdempotencyHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(status)
+	}), time.Hour)
+
+	idempotentPost(h, "key-5", `{}`)
+	status = http.StatusOK
+	idempotentPost(h, "key-5", `{}`)
+	idempotentPost(h, "key-5", `{}`)
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(2))
+
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	idempotentPost(h, "key-5", `{}`)
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(3))
+}
+
+func TestIdempotency_WhenHandlerPanics(t *testing.T){
+	var calls int32
+	h := newIdempotencyHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			panic("boom")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}), time.Hour)
+
+	assert.Panics(t, func() { idempotentPost(h, "key-6", `{}`) })
+	w := idempotentPost(h, "key-6", `{}`)
+
+	assert.Equal(t, w.Code, http.StatusCreated)
+	assert.Equal(t, w.Header().Get("Idempotent-Replayed"), "")
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(2))
+}
+
+func TestIdempotency_WhenKeyReusedByAnotherUser(t *testing.T){
+	var calls int32
+	h := newIdempotencyHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusCreated)
+	}), time.Hour)
+	post := func(user, actor string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/cars", strings.NewReader(`{}`))
+		r.Header.Set("Idempotency-Key", "key-7")
+		r.Header.Set("X-Actor", actor)
+		authAs(r, user)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	post("ivy", "ivy")
+	w := post("jon", "ivy")
+
+	assert.Equal(t, w.Header().Get("Idempotent-Replayed"), "")
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(2))
+
+	w = post("ivy", "jon")
+	assert.Equal(t, w.Header().Get("Idempotent-Replayed"), "true")
+
+	r := httptest.NewRequest("POST", "/cars", strings.NewReader(`{}`))
+	r.Header.Set("Idempotency-Key", "key-7")
+	r.SetBasicAuth("ivy", "guess")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+}
@@ -21,7 +21,14 @@ package main
 import (
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
 
 	_ "example/cars/docs"
 
@@ -32,9 +39,57 @@ import (
 func main() {
 	port := ":8080"
 
+	users, err := parseDealershipUsers(os.Getenv("DEALERSHIP_USERS"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	dealerships.users = users
+	dealerships.admins = map[string]bool{}
+	for _, u := range splitList(os.Getenv("GROUP_ADMINS")) {
+		if _, ok := users[u]; !ok {
+			log.Fatalf("GROUP_ADMINS user %s is not in DEALERSHIP_USERS", u)
+		}
+		dealerships.admins[u] = true
+	}
+	if err := dealerships.open(envOr("DATA_DIR", "data"), splitList(os.Getenv("DEALERSHIPS"))); err != nil {
+		log.Fatal(err)
+	}
+
+	blobs = &diskBlobStore{dir: filepath.Join(envOr("DATA_DIR", "data"), "media")}
+	maxMediaBytes = mediaMaxBytes()
+	if v := os.Getenv("LENDER_RATES"); v != "" {
+		if lenderRates, err = loadRateTable(v); err != nil {
+			log.Fatalf("invalid LENDER_RATES: %v", err)
+		}
+	}
+
 	carhandler := newCarHandler()
-	http.Handle("/cars", carhandler)
-	http.Handle("/cars/", carhandler)
+	go carhandler.purgeTrash(trashRetention(), time.Hour)
+	go carhandler.releaseExpiredHolds(time.Minute)
+	go carhandler.applyScheduledPrices(time.Minute)
+	go carhandler.snapshotEvery(snapshotInterval())
+	go closeOnSignal(carhandler)
+	ttl := idempotencyTTL()
+	cars := newIdempotencyHandler(carhandler, ttl)
+	http.Handle("/cars", cars)
+	http.Handle("/cars/", cars)
+	http.Handle("/cars:batch", cars)
+	http.Handle("/cars:compare", cars)
+
+	go serveGRPC(envOr("GRPC_ADDR", ":9090"), carhandler)
+
+	group := &groupHandler{cars: carhandler}
+	http.Handle("/group/", group)
+
+	http.Handle("/vin/", &vinHandler{})
+	http.Handle("/financing/rates", &financingHandler{})
+
+	http.Handle("/graphql", newGraphQLHandler(carhandler, os.Getenv("APP_ENV") == "development"))
+
+	webhookhandler := newWebhookHandler(carhandler)
+	webhooks := newIdempotencyHandler(webhookhandler, ttl)
+	http.Handle("/webhooks", webhooks)
+	http.Handle("/webhooks/", webhooks)
 
 	http.Handle("/metrics", promhttp.Handler())
 
@@ -48,4 +103,89 @@ func main() {
 
 	fmt.Println("Starting server on port", port)
 	log.Fatal(http.ListenAndServe(port, nil))
-}
\ No newline at end of file
+}
+
+// serveGRPC serves CarService on addr alongside the HTTP server.
+func serveGRPC(addr string, cars *carHandler) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Starting gRPC server on", addr)
+	log.Fatal(newGRPCServer(cars).Serve(lis))
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// snapshotInterval reads how often the store is compacted from
+// SNAPSHOT_INTERVAL, e.g. "5m". It falls back to five minutes.
+func snapshotInterval() time.Duration {
+	d, err := time.ParseDuration(envOr("SNAPSHOT_INTERVAL", "5m"))
+	if err != nil {
+		log.Fatalf("invalid SNAPSHOT_INTERVAL: %v", err)
+	}
+	return d
+}
+
+// closeOnSignal writes a final snapshot when the server is interrupted.
+func closeOnSignal(h *carHandler) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+
+	h.Lock()
+	dealerships.each(func(name string, d *Db) {
+		if err := d.close(); err != nil {
+			log.Printf("closing store of %s: %v", name, err)
+		}
+	})
+	os.Exit(0)
+}
+
+// trashRetention reads how long deleted cars are kept from TRASH_RETENTION,
+// e.g. "72h". It falls back to defaultTrashRetention.
+func trashRetention() time.Duration {
+	v := os.Getenv("TRASH_RETENTION")
+	if v == "" {
+		return defaultTrashRetention
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("invalid TRASH_RETENTION %q: %v", v, err)
+	}
+	return d
+}
+
+// idempotencyTTL reads how long responses are replayed for their
+// Idempotency-Key from IDEMPOTENCY_TTL, e.g. "1h". It falls back to
+// defaultIdempotencyTTL.
+func idempotencyTTL() time.Duration {
+	v := os.Getenv("IDEMPOTENCY_TTL")
+	if v == "" {
+		return defaultIdempotencyTTL
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("invalid IDEMPOTENCY_TTL %q: %v", v, err)
+	}
+	return d
+}
+
+// mediaMaxBytes reads the largest accepted upload from MEDIA_MAX_BYTES. It
+// falls back to defaultMaxMediaBytes.
+func mediaMaxBytes() int64 {
+	v := os.Getenv("MEDIA_MAX_BYTES")
+	if v == "" {
+		return defaultMaxMediaBytes
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n <= 0 {
+		log.Fatalf("invalid MEDIA_MAX_BYTES %q", v)
+	}
+	return n
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"time"
+)
+
+// Errors uploading and managing photos.
+var (
+	errMediaNotFound    = errors.New("media not found")
+	errMediaTooLarge    = errors.New("media too large")
+	errUnsupportedMedia = errors.New("unsupported media type")
+)
+
+// defaultMaxMediaBytes is the largest upload accepted unless
+// MEDIA_MAX_BYTES says otherwise.
+const defaultMaxMediaBytes = 10 << 20
+
+var maxMediaBytes int64 = defaultMaxMediaBytes
+
+// maxMediaPixels is the largest image accepted, in pixels. Small files can
+// declare huge images, so the size is checked before decoding them.
+var maxMediaPixels = 40_000_000
+
+// mediaTypes are the sniffed content types that can be uploaded.
+var mediaTypes = []string{"image/jpeg", "image/png", "image/gif"}
+
+// thumbnailSizes are the thumbnails generated for every upload, by name and
+// the length of their longest side in pixels.
+var thumbnailSizes = []struct {
+	name  string
+	bound int
+}{
+	{"small", 160},
+	{"medium", 480},
+	{"large", 1024},
+}
+
+// media is a photo of a car. The bytes live in the blob store under
+// "<Id>/original" and "<Id>/<thumbnail>".
+// @Description a photo of a car
+type media struct {
+	Id          string            `json:"id"`
+	CarId       string            `json:"car_id"`
+	ContentType string            `json:"content_type"`
+	Size        int               `json:"size"`
+	Width       int               `json:"width"`
+	Height      int               `json:"height"`
+	Position    int               `json:"position"`
+	Primary     bool              `json:"primary"`
+	Url         string            `json:"url"`
+	Thumbnails  map[string]string `json:"thumbnails"`
+	UploadedBy  string            `json:"uploaded_by"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// mediaUpdate moves a photo to Position and, when Primary is set, makes it
+// the primary photo of the car.
+// @Description media ordering request
+type mediaUpdate struct {
+	Position *int `json:"position,omitempty"`
+	Primary  bool `json:"primary"`
+}
+
+// carMedia holds the photos of every car, keyed by car id and in display
+// order. A car with photos always has exactly one primary photo.
+type carMedia struct {
+	byCar map[string][]media
+}
+
+func (s *carMedia) add(md media) media {
+	if s.byCar == nil {
+		s.byCar = map[string][]media{}
+	}
+	list := append(s.byCar[md.CarId], md)
+	list[len(list)-1].Primary = len(list) == 1
+	s.set(md.CarId, list)
+	return list[len(list)-1]
+}
+
+func (s *carMedia) get(carId, id string) (media, bool) {
+	for _, v := range s.byCar[carId] {
+		if v.Id == id {
+			return v, true
+		}
+	}
+	return media{}, false
+}
+
+// move puts the photo at position, clamped to the photos of the car.
+func (s *carMedia) move(carId, id string, position int) {
+	list := s.byCar[carId]
+	for i, v := range list {
+		if v.Id != id {
+			continue
+		}
+		list = append(list[:i], list[i+1:]...)
+		if position > len(list) {
+			position = len(list)
+		}
+		if position < 0 {
+			position = 0
+		}
+		list = append(list[:position], append([]media{v}, list[position:]...)...)
+		s.set(carId, list)
+		return
+	}
+}
+
+func (s *carMedia) setPrimary(carId, id string) {
+	for i, v := range s.byCar[carId] {
+		s.byCar[carId][i].Primary = v.Id == id
+	}
+}
+
+// remove drops the photo, promoting the first remaining one when it was
+// the primary photo.
+func (s *carMedia) remove(carId, id string) {
+	list := s.byCar[carId]
+	for i, v := range list {
+		if v.Id != id {
+			continue
+		}
+		list = append(list[:i], list[i+1:]...)
+		if v.Primary && len(list) > 0 {
+			list[0].Primary = true
+		}
+		s.set(carId, list)
+		return
+	}
+}
+
+// set stores list as the photos of the car, numbering their positions.
+func (s *carMedia) set(carId string, list []media) {
+	if len(list) == 0 {
+		delete(s.byCar, carId)
+		return
+	}
+	for i := range list {
+		list[i].Position = i
+	}
+	s.byCar[carId] = list
+}
+
+func (s *carMedia) forCar(carId string) []media {
+	return append([]media{}, s.byCar[carId]...)
+}
+
+func (s *carMedia) list() []media {
+	out := []media{}
+	for _, ms := range s.byCar {
+		out = append(out, ms...)
+	}
+	return out
+}
+
+func (db *Db) addMedia(md media) (media, error) {
+	if _, err := db.getById(md.CarId); err != nil {
+		return media{}, err
+	}
+
+	if err := db.wal.append(walRecord{Op: opAddMedia, Media: &md}); err != nil {
+		return media{}, err
+	}
+
+	return db.media.add(md), nil
+}
+
+// updateMedia moves the photo to position and, if primary is set, makes it
+// the primary photo.
+func (db *Db) updateMedia(carId, id string, position int, primary bool) (media, error) {
+	if _, err := db.getById(carId); err != nil {
+		return media{}, err
+	}
+	if _, ok := db.media.get(carId, id); !ok {
+		return media{}, errMediaNotFound
+	}
+
+	if err := db.wal.append(walRecord{Op: opUpdateMedia, Id: carId, Media: &media{Id: id, Position: position, Primary: primary}}); err != nil {
+		return media{}, err
+	}
+
+	db.media.move(carId, id, position)
+	if primary {
+		db.media.setPrimary(carId, id)
+	}
+	md, _ := db.media.get(carId, id)
+	return md, nil
+}
+
+func (db *Db) deleteMedia(carId, id string) (media, error) {
+	if _, err := db.getById(carId); err != nil {
+		return media{}, err
+	}
+	md, ok := db.media.get(carId, id)
+	if !ok {
+		return media{}, errMediaNotFound
+	}
+
+	if err := db.wal.append(walRecord{Op: opDeleteMedia, Id: carId, Media: &media{Id: id}}); err != nil {
+		return media{}, err
+	}
+
+	db.media.remove(carId, id)
+	return md, nil
+}
+
+func (db *Db) getMedia(carId string) ([]media, error) {
+	if _, err := db.getById(carId); err != nil {
+		return []media{}, err
+	}
+	return db.media.forCar(carId), nil
+}
+
+// withMedia returns a copy of the car carrying its photos.
+func (db *Db) withMedia(c Car) Car {
+	if ms := db.media.forCar(c.Id); len(ms) > 0 {
+		c.Media = ms
+	}
+	return c
+}
+
+// dropMedia forgets the photos of a car that is gone for good and removes
+// their blobs.
+func (db *Db) dropMedia(carId string) {
+	for _, md := range db.media.forCar(carId) {
+		blobs.removeAll(md.Id)
+	}
+	delete(db.media.byCar, carId)
+}
+
+func mediaUrl(carId, id string) string {
+	return "/cars/" + carId + "/media/" + id
+}
+
+// thumbnailType is the content type of the thumbnails of an upload. JPEG
+// photos keep their format and the others become PNG, keeping any
+// transparency.
+func thumbnailType(contentType string) string {
+	if contentType == "image/jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+// decodedMedia is an upload decoded into its thumbnails, ready to store.
+type decodedMedia struct {
+	data        []byte
+	contentType string
+	config      image.Config
+	thumbs      map[string][]byte
+}
+
+// renderThumbnails decodes the upload and encodes one thumbnail per size.
+// Images of more than maxMediaPixels are rejected from their header,
+// before they are decoded.
+func renderThumbnails(data []byte, contentType string) (image.Config, map[string][]byte, error) {
+	decodeConfig, decode := jpeg.DecodeConfig, jpeg.Decode
+	switch contentType {
+	case "image/jpeg":
+	case "image/png":
+		decodeConfig, decode = png.DecodeConfig, png.Decode
+	case "image/gif":
+		decodeConfig, decode = gif.DecodeConfig, gif.Decode
+	default:
+		return image.Config{}, nil, fmt.Errorf("%w %s: unknown format", errUnsupportedMedia, contentType)
+	}
+
+	cfg, err := decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return image.Config{}, nil, fmt.Errorf("%w %s: %v", errUnsupportedMedia, contentType, err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > int64(maxMediaPixels) {
+		return image.Config{}, nil, fmt.Errorf("%w: %dx%d exceeds the limit of %d pixels", errMediaTooLarge, cfg.Width, cfg.Height, maxMediaPixels)
+	}
+	img, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return image.Config{}, nil, fmt.Errorf("%w %s: %v", errUnsupportedMedia, contentType, err)
+	}
+
+	b := img.Bounds()
+	thumbs := map[string][]byte{}
+	for _, size := range thumbnailSizes {
+		var buf bytes.Buffer
+		thumb := thumbnail(img, size.bound)
+		if thumbnailType(contentType) == "image/jpeg" {
+			err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+		} else {
+			err = png.Encode(&buf, thumb)
+		}
+		if err != nil {
+			return image.Config{}, nil, err
+		}
+		thumbs[size.name] = buf.Bytes()
+	}
+	return image.Config{Width: b.Dx(), Height: b.Dy()}, thumbs, nil
+}
+
+// thumbnail scales src down so its longest side is at most bound pixels,
+// averaging the source pixels that fall into each thumbnail pixel. Images
+// that already fit are copied as they are.
+func thumbnail(src image.Image, bound int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > bound || h > bound {
+		if w >= h {
+			w, h = bound, h*bound/w
+		} else {
+			w, h = w*bound/h, bound
+		}
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+
+	dst := image.NewRGBA64(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := b.Min.Y+y*b.Dy()/h, b.Min.Y+(y+1)*b.Dy()/h
+		for x := 0; x < w; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/w, b.Min.X+(x+1)*b.Dx()/w
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
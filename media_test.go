@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mediaSetup(t *testing.T) {
+	store := blobs
+	blobs = &diskBlobStore{dir: t.TempDir()}
+	t.Cleanup(func() { blobs = store })
+}
+
+func testPng(w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 200, 255})
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func mediaDo(method, path, contentType string, body []byte) *httptest.ResponseRecorder {
+	h := &carHandler{}
+	r := httptest.NewRequest(method, path, bytes.NewReader(body))
+	r.Header.Set("content-type", contentType)
+	authAs(r, "pat")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func mediaUpload(id string, data []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile("file", "photo")
+	part.Write(data)
+	mw.Close()
+	return mediaDo("POST", "/cars/"+id+"/media", mw.FormDataContentType(), body.Bytes())
+}
+
+func uploadTestMedia(t *testing.T, id string) media {
+	w := mediaUpload(id, testPng(640, 320))
+	assert.Equal(t, w.Code, http.StatusCreated)
+
+	var md media
+	json.Unmarshal(w.Body.Bytes(), &md)
+	return md
+}
+
+func TestUploadMedia_WhenPng(t *testing.T){
+	mediaSetup(t)
+	persistTestCar("mediacar1").createCar()
+
+	md := uploadTestMedia(t, "mediacar1")
+
+	assert.Equal(t, md.ContentType, "image/png")
+	assert.Equal(t, []int{md.Width, md.Height}, []int{640, 320})
+	assert.Equal(t, md.Primary, true)
+	assert.Equal(t, md.Url, "/cars/mediacar1/media/"+md.Id)
+	assert.Equal(t, md.Thumbnails["small"], md.Url+"/small")
+
+	w := mediaDo("GET", md.Thumbnails["small"], "", nil)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Header().Get("content-type"), "image/png")
+	thumb, _ := png.DecodeConfig(w.Body)
+	assert.Equal(t, []int{thumb.Width, thumb.Height}, []int{160, 80})
+
+	w = mediaDo("GET", md.Thumbnails["large"], "", nil)
+	thumb, _ = png.DecodeConfig(w.Body)
+	assert.Equal(t, []int{thumb.Width, thumb.Height}, []int{640, 320})
+
+	var car Car
+	w = mediaDo("GET", "/cars/mediacar1", "", nil)
+	json.Unmarshal(w.Body.Bytes(), &car)
+	assert.Equal(t, len(car.Media), 1)
+	assert.Equal(t, car.Media[0].Id, md.Id)
+}
+
+func TestUploadMedia_WhenNotAnImage(t *testing.T){
+	mediaSetup(t)
+	persistTestCar("mediacar2").createCar()
+
+	w := mediaUpload("mediacar2", []byte("just some text"))
+
+	assert.Equal(t, w.Code, http.StatusUnsupportedMediaType)
+	assert.Equal(t, strings.Contains(w.Body.String(), "allowed are image/jpeg, image/png, image/gif"), true)
+}
+
+func TestUploadMedia_WhenTooLarge(t *testing.T){
+	mediaSetup(t)
+	persistTestCar("mediacar3").createCar()
+	limit := maxMediaBytes
+	maxMediaBytes = 64
+	defer func() { maxMediaBytes = limit }()
+
+	w := mediaUpload("mediacar3", testPng(64, 64))
+
+	assert.Equal(t, w.Code, http.StatusRequestEntityTooLarge)
+}
+
+func TestUploadMedia_WhenTooManyPixels(t *testing.T){
+	mediaSetup(t)
+	persistTestCar("mediacar6").createCar()
+
+	// A 1x1 PNG whose header claims 50000x50000 pixels.
+	data := testPng(1, 1)
+	binary.BigEndian.PutUint32(data[16:], 50000)
+	binary.BigEndian.PutUint32(data[20:], 50000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	w := mediaUpload("mediacar6", data)
+
+	assert.Equal(t, w.Code, http.StatusRequestEntityTooLarge)
+	assert.Equal(t, strings.Contains(w.Body.String(), "50000x50000 exceeds the limit"), true)
+	car, _ := db.getById("mediacar6")
+	assert.Equal(t, len(db.withMedia(car).Media), 0)
+}
+
+func TestUpdateMedia_WhenReordering(t *testing.T){
+	mediaSetup(t)
+	persistTestCar("mediacar4").createCar()
+	first := uploadTestMedia(t, "mediacar4")
+	second := uploadTestMedia(t, "mediacar4")
+	assert.Equal(t, second.Position, 1)
+	assert.Equal(t, second.Primary, false)
+
+	w := mediaDo("PATCH", second.Url, "application/json", []byte(`{"position": 0, "primary": true}`))
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	var ms []media
+	w = mediaDo("GET", "/cars/mediacar4/media", "", nil)
+	json.Unmarshal(w.Body.Bytes(), &ms)
+	assert.Equal(t, []string{ms[0].Id, ms[1].Id}, []string{second.Id, first.Id})
+	assert.Equal(t, []bool{ms[0].Primary, ms[1].Primary}, []bool{true, false})
+}
+
+func TestDeleteMedia_WhenPrimary(t *testing.T){
+	mediaSetup(t)
+	persistTestCar("mediacar5").createCar()
+	first := uploadTestMedia(t, "mediacar5")
+	second := uploadTestMedia(t, "mediacar5")
+
+	w := mediaDo("DELETE", first.Url, "", nil)
+	assert.Equal(t, w.Code, http.StatusNoContent)
+
+	w = mediaDo("GET", first.Url, "", nil)
+	assert.Equal(t, w.Code, http.StatusNotFound)
+
+	var ms []media
+	w = mediaDo("GET", "/cars/mediacar5/media", "", nil)
+	json.Unmarshal(w.Body.Bytes(), &ms)
+	assert.Equal(t, len(ms), 1)
+	assert.Equal(t, ms[0].Id, second.Id)
+	assert.Equal(t, ms[0].Primary, true)
+}
+
+func TestOpen_WhenReplayingMedia(t *testing.T){
+	dir := t.TempDir()
+	var d Db
+	d.open(dir)
+	d.add(persistTestCar("a"))
+	d.addMedia(media{Id: "m1", CarId: "a"})
+	d.addMedia(media{Id: "m2", CarId: "a"})
+	d.addMedia(media{Id: "m3", CarId: "a"})
+	d.updateMedia("a", "m3", 0, true)
+	d.deleteMedia("a", "m1")
+	d.wal.f.Close()
+
+	var replayed Db
+	assert.Equal(t, replayed.open(dir), nil)
+	ms := replayed.media.forCar("a")
+	assert.Equal(t, []string{ms[0].Id, ms[1].Id}, []string{"m3", "m2"})
+	assert.Equal(t, ms[0].Primary, true)
+
+	assert.Equal(t, replayed.snapshot(), nil)
+	replayed.wal.f.Close()
+	var compacted Db
+	compacted.open(dir)
+	ms = compacted.media.forCar("a")
+	assert.Equal(t, []string{ms[0].Id, ms[1].Id}, []string{"m3", "m2"})
+	assert.Equal(t, ms[0].Primary, true)
+}
@@ -2,6 +2,10 @@ package main
 
 import (
 	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
 )
 
 type carMiddleware struct {}
@@ -14,6 +18,100 @@ func (m *carMiddleware) validate_getById(c *Car) error {
 	return nil
 }
 
+func (m *carMiddleware) validate_search(q string) error {
+	if len(tokenize(q)) == 0 {
+		return fmt.Errorf("q field empty")
+	}
+
+	return nil
+}
+
+func (m *carMiddleware) validate_facets(priceInterval, mileageInterval float64) error {
+	if !isFinite(priceInterval) || priceInterval <= 0 {
+		return fmt.Errorf("price_interval must be gt 0")
+	}
+	if !isFinite(mileageInterval) || mileageInterval <= 0 {
+		return fmt.Errorf("mileage_interval must be gt 0")
+	}
+
+	return nil
+}
+
+func (m *carMiddleware) validate_stats(s statsQuery) error {
+	for _, f := range s.groupBy {
+		if groupableFields[f] == nil {
+			return fmt.Errorf("group_by must be one of make, model, package, color, category, year")
+		}
+	}
+	for _, f := range s.fields {
+		if numericFields[f] == nil {
+			return fmt.Errorf("fields must be one of price, mileage, year")
+		}
+	}
+	for _, p := range s.percentiles {
+		if math.IsNaN(p) || p < 0 || p > 100 {
+			return fmt.Errorf("percentiles must be between 0 and 100")
+		}
+	}
+
+	return nil
+}
+
+func (m *carMiddleware) validate_valuation(q valuationQuery) error {
+	if q.make == "" {
+		return fmt.Errorf("make field empty")
+	}
+	if q.model == "" {
+		return fmt.Errorf("model field empty")
+	}
+	if q.year <= 0 {
+		return fmt.Errorf("year field must be gt 0")
+	}
+	if q.mileage < 0 {
+		return fmt.Errorf("mileage field must be ge 0")
+	}
+
+	return nil
+}
+
+func (m *carMiddleware) validate_financing(q financingQuery, price float64) error {
+	if q.term < 1 || q.term > 120 {
+		return fmt.Errorf("term must be between 1 and 120 months")
+	}
+	if !q.hasApr && q.tier == "" {
+		return fmt.Errorf("apr or tier must be given")
+	}
+	if q.hasApr && q.tier != "" {
+		return fmt.Errorf("apr and tier must not both be given")
+	}
+	if !isFinite(q.apr) || q.apr < 0 || q.apr > 100 {
+		return fmt.Errorf("apr must be between 0 and 100")
+	}
+	if !isFinite(q.down) || q.down < 0 {
+		return fmt.Errorf("down must be ge 0")
+	}
+	if q.down >= price {
+		return fmt.Errorf("down must be less than the price")
+	}
+
+	return nil
+}
+
+func (m *carMiddleware) validate_compare(ids []string) error {
+	if len(ids) < minCompared || len(ids) > maxCompared {
+		return fmt.Errorf("ids must list %d or %d cars", minCompared, maxCompared)
+	}
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			return fmt.Errorf("ids must not repeat %s", id)
+		}
+		seen[id] = true
+	}
+
+	return nil
+}
+
 func (m *carMiddleware)validate_create(c *Car) error {
 	if c.Id == "" {
 		return fmt.Errorf("id field empty")
@@ -36,13 +134,18 @@ func (m *carMiddleware)validate_create(c *Car) error {
 	if c.Category == "" {
 		return fmt.Errorf("category field empty")
 	}
-	if c.Mileage < 0 {
+	if !isFinite(c.Mileage) || c.Mileage < 0 {
 		return fmt.Errorf("mileage field must be ge 0")
 	}
-	if c.Price <= 0 {
+	if !isFinite(c.Price) || c.Price <= 0 {
 		return fmt.Errorf("price field must be gt 0")
 	}
-
+	if c.MileageUnit != "" && !validUnit(c.MileageUnit) {
+		return fmt.Errorf("mileage_unit field must be km or mi")
+	}
+	if c.Status != "" && !validEntryStatus(c.Status) {
+		return fmt.Errorf("status field must be %s", strings.Join(entryStatuses, ", "))
+	}
 	return nil
 }
 
@@ -68,12 +171,15 @@ func (m *carMiddleware) validate_update(c *Car) error {
 	if c.Category == "" {
 		return fmt.Errorf("category field empty")
 	}
-	if c.Mileage < 0 {
+	if !isFinite(c.Mileage) || c.Mileage < 0 {
 		return fmt.Errorf("mileage field must be ge 0")
 	}
-	if c.Price <= 0 {
+	if !isFinite(c.Price) || c.Price <= 0 {
 		return fmt.Errorf("price field must be gt 0")
 	}
+	if c.MileageUnit != "" && !validUnit(c.MileageUnit) {
+		return fmt.Errorf("mileage_unit field must be km or mi")
+	}
 
 	return nil
 }
@@ -84,4 +190,130 @@ func (m *carMiddleware) validate_delete(c *Car) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+func (m *carMiddleware) validate_transition(c *Car, t statusTransition) error {
+	if c.Id == "" {
+		return fmt.Errorf("id field empty")
+	}
+	if t.Status == "" {
+		return fmt.Errorf("status field empty")
+	}
+	if !validStatus(t.Status) {
+		return fmt.Errorf("status field must be one of incoming, in_transit, available, reserved, sold")
+	}
+	if t.Status == statusReserved {
+		return fmt.Errorf("%w to reserved: hold the car with a reservation instead", errTransition)
+	}
+
+	return nil
+}
+
+func (m *carMiddleware) validate_reservation(c *Car, r reservationRequest) error {
+	if c.Id == "" {
+		return fmt.Errorf("id field empty")
+	}
+	if r.Customer == "" {
+		return fmt.Errorf("customer field empty")
+	}
+	if r.Hours < 1 || r.Hours > maxHoldHours {
+		return fmt.Errorf("hours must be between 1 and %d", maxHoldHours)
+	}
+
+	return nil
+}
+
+func (m *carMiddleware) validate_priceChange(c *Car, p priceChange) error {
+	if c.Id == "" {
+		return fmt.Errorf("id field empty")
+	}
+	if p.Price <= 0 {
+		return fmt.Errorf("price field must be gt 0")
+	}
+	if p.EffectiveAt.IsZero() {
+		return fmt.Errorf("effective_at field empty")
+	}
+	if !p.EffectiveAt.After(now()) {
+		return fmt.Errorf("effective_at must be in the future")
+	}
+
+	return nil
+}
+
+func (m *carMiddleware) validate_media(c *Car, data []byte) error {
+	if c.Id == "" {
+		return fmt.Errorf("id field empty")
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("file field empty")
+	}
+	if int64(len(data)) > maxMediaBytes {
+		return fmt.Errorf("%w: limit is %d bytes", errMediaTooLarge, maxMediaBytes)
+	}
+	contentType := http.DetectContentType(data)
+	for _, v := range mediaTypes {
+		if v == contentType {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w %s: allowed are %s", errUnsupportedMedia, contentType, strings.Join(mediaTypes, ", "))
+}
+
+func (m *carMiddleware) validate_mediaUpdate(c *Car, u mediaUpdate) error {
+	if c.Id == "" {
+		return fmt.Errorf("id field empty")
+	}
+	if u.Position != nil && *u.Position < 0 {
+		return fmt.Errorf("position field must be gte 0")
+	}
+
+	return nil
+}
+
+func (m *carMiddleware) validate_webhook(s *webhookSubscription) error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url field must be an absolute http(s) url")
+	}
+	if internalHost(u.Hostname()) {
+		return fmt.Errorf("url must not point to an internal address")
+	}
+	if len(s.Events) == 0 {
+		return fmt.Errorf("events field empty")
+	}
+	for _, e := range s.Events {
+		if e != eventCreated && e != eventUpdated && e != eventDeleted && e != eventRestored {
+			return fmt.Errorf("unknown event type %s", e)
+		}
+	}
+	if s.Secret == "" {
+		return fmt.Errorf("secret field empty")
+	}
+
+	return nil
+}
+func (m *carMiddleware) validate_batch(b *batchRequest) error {
+	if b.Mode != batchAtomic && b.Mode != batchBestEffort {
+		return fmt.Errorf("mode must be atomic or best_effort")
+	}
+	if len(b.Operations) == 0 {
+		return fmt.Errorf("operations field empty")
+	}
+	if len(b.Operations) > maxBatchSize {
+		return fmt.Errorf("operations must be at most %d", maxBatchSize)
+	}
+	for _, op := range b.Operations {
+		if op.Op != batchCreate && op.Op != batchUpdate && op.Op != batchDelete {
+			return fmt.Errorf("op must be one of create, update, delete")
+		}
+	}
+
+	return nil
+}
+
+// isFinite reports whether v is neither NaN nor infinite. Comparisons
+// with NaN are always false, so range checks alone let it through.
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
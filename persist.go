@@ -0,0 +1,400 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// errWal wraps the errors writing the write-ahead log.
+var errWal = errors.New("write-ahead log")
+
+// Files kept in the data directory.
+const (
+	walFile      = "wal.log"
+	snapshotFile = "snapshot"
+)
+
+// Operations recorded in the write-ahead log.
+const (
+	opAdd           = "add"
+	opUpdate        = "update"
+	opDelete        = "delete"
+	opRestore       = "restore"
+	opPurge         = "purge"
+	opBatch         = "batch"
+	opTransition    = "transition"
+	opHold          = "hold"
+	opRelease       = "release"
+	opSchedulePrice = "schedule_price"
+	opCancelPrice   = "cancel_price"
+	opApplyPrice    = "apply_price"
+	opAddMedia      = "add_media"
+	opUpdateMedia   = "update_media"
+	opDeleteMedia   = "delete_media"
+	opSubscribe     = "subscribe"
+	opUnsubscribe   = "unsubscribe"
+)
+
+// headerSize is the length and CRC-32 prefix of every framed record.
+const headerSize = 8
+
+// walRecord is one mutation of the store. Seq increases by one per record
+// and is carried over by snapshots, so replay can skip what a snapshot
+// already covers.
+type walRecord struct {
+	Seq    uint64      `json:"seq"`
+	Op     string      `json:"op"`
+	At     time.Time   `json:"at"`
+	Car    Car         `json:"car"`
+	Id     string      `json:"id,omitempty"`
+	Actor  string      `json:"actor,omitempty"`
+	Status string      `json:"status,omitempty"`
+	Reason string      `json:"reason,omitempty"`
+	Cutoff time.Time   `json:"cutoff,omitempty"`
+	Batch  []walRecord `json:"batch,omitempty"`
+
+	Reservation *reservation    `json:"reservation,omitempty"`
+	Price       *scheduledPrice `json:"price,omitempty"`
+	Media       *media          `json:"media,omitempty"`
+
+	Subscription *webhookSubscription `json:"subscription,omitempty"`
+}
+
+// snapshot is the compacted state of the store up to Seq.
+type snapshot struct {
+	Seq     uint64                  `json:"seq"`
+	Cars    []Car                   `json:"cars"`
+	Trash   []trashedCar            `json:"trash"`
+	History map[string][]carVersion `json:"history"`
+	Holds   []reservation           `json:"holds"`
+	Prices  []scheduledPrice        `json:"prices"`
+	Media   []media                 `json:"media"`
+
+	Webhooks []webhookSubscription `json:"webhooks"`
+}
+
+// wal is an append-only file of framed records, fsynced after each write.
+// A nil *wal discards records, which is how the store runs without
+// persistence.
+type wal struct {
+	dir  string
+	f    *os.File
+	seq  uint64
+	size int64
+}
+
+// frame prefixes payload with its length and CRC-32.
+func frame(payload []byte) []byte {
+	buf := make([]byte, headerSize+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	copy(buf[headerSize:], payload)
+	return buf
+}
+
+// unframe reads the record at the start of data. ok is false when the
+// record is incomplete or its checksum does not match.
+func unframe(data []byte) (payload []byte, n int, ok bool) {
+	if len(data) < headerSize {
+		return nil, 0, false
+	}
+	size := int(binary.LittleEndian.Uint32(data[0:4]))
+	if len(data)-headerSize < size {
+		return nil, 0, false
+	}
+	payload = data[headerSize : headerSize+size]
+	if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(data[4:8]) {
+		return nil, 0, false
+	}
+	return payload, headerSize + size, true
+}
+
+func (w *wal) append(rec walRecord) error {
+	if w == nil {
+		return nil
+	}
+
+	rec.Seq = w.seq + 1
+	rec.At = now()
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	buf := frame(payload)
+	if _, err := w.f.Write(buf); err != nil {
+		w.f.Truncate(w.size)
+		return fmt.Errorf("%w: %v", errWal, err)
+	}
+	if err := w.f.Sync(); err != nil {
+		w.f.Truncate(w.size)
+		return fmt.Errorf("%w: %v", errWal, err)
+	}
+
+	w.seq = rec.Seq
+	w.size += int64(len(buf))
+	return nil
+}
+
+// readWal decodes the records of the log at path. It stops at the first
+// torn or corrupt record and returns the size of the valid prefix.
+func readWal(path string) ([]walRecord, int64, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	records := []walRecord{}
+	offset := 0
+	for offset < len(data) {
+		payload, n, ok := unframe(data[offset:])
+		if !ok {
+			break
+		}
+		var rec walRecord
+		if err := json.Unmarshal(payload, &rec); err != nil {
+			break
+		}
+		records = append(records, rec)
+		offset += n
+	}
+
+	if offset < len(data) {
+		log.Printf("write-ahead log: dropping %d bytes of torn or corrupt records", len(data)-offset)
+	}
+	return records, int64(offset), nil
+}
+
+func readSnapshot(path string) (snapshot, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return snapshot{}, nil
+	}
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	payload, n, ok := unframe(data)
+	if !ok || n != len(data) {
+		return snapshot{}, fmt.Errorf("snapshot %s is corrupt", path)
+	}
+
+	var s snapshot
+	if err := json.Unmarshal(payload, &s); err != nil {
+		return snapshot{}, fmt.Errorf("snapshot %s is corrupt: %v", path, err)
+	}
+	return s, nil
+}
+
+// open loads the store from dir: the latest snapshot, then every logged
+// mutation made after it. Torn records at the tail of the log are
+// truncated. Subsequent mutations are appended to the log.
+func (db *Db) open(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	s, err := readSnapshot(filepath.Join(dir, snapshotFile))
+	if err != nil {
+		return err
+	}
+	db.cars = s.Cars
+	db.trash.cars = s.Trash
+	db.history.versions = s.History
+	db.holds = carHolds{}
+	for _, r := range s.Holds {
+		db.holds.put(r)
+	}
+	db.prices = priceSchedule{}
+	for _, p := range s.Prices {
+		db.prices.put(p)
+	}
+	db.media = carMedia{byCar: map[string][]media{}}
+	for _, md := range s.Media {
+		db.media.byCar[md.CarId] = append(db.media.byCar[md.CarId], md)
+	}
+	db.webhooks = s.Webhooks
+	db.index = searchIndex{}
+	for i, v := range db.cars {
+		// Cars stored before statuses existed are available.
+		if v.Status == "" {
+			db.cars[i].Status = statusAvailable
+		}
+		db.index.add(v)
+	}
+
+	path := filepath.Join(dir, walFile)
+	records, size, err := readWal(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Truncate(path, size); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	seq := s.Seq
+	for _, rec := range records {
+		if rec.Seq <= s.Seq {
+			continue
+		}
+		db.replay(rec)
+		seq = rec.Seq
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	db.wal = &wal{dir: dir, f: f, seq: seq, size: size}
+	return nil
+}
+
+// replay applies a logged mutation, timestamped as when it first happened.
+func (db *Db) replay(rec walRecord) {
+	clock := now
+	now = func() time.Time { return rec.At }
+	defer func() { now = clock }()
+
+	var err error
+	switch rec.Op {
+	case opAdd:
+		_, err = db.add(&rec.Car)
+	case opUpdate:
+		_, err = db.update(&rec.Car)
+	case opDelete:
+		_, err = db.delete(rec.Id, rec.Actor)
+	case opRestore:
+		_, err = db.restore(rec.Id)
+	case opPurge:
+		_, err = db.purge(rec.Cutoff)
+	case opTransition:
+		_, err = db.transition(rec.Id, rec.Status, rec.Actor, rec.Reason)
+	case opHold:
+		_, err = db.hold(*rec.Reservation)
+	case opRelease:
+		_, err = db.release(rec.Id, rec.Actor, rec.Reason)
+	case opSchedulePrice:
+		_, err = db.schedulePrice(*rec.Price)
+	case opCancelPrice:
+		_, err = db.cancelPrice(rec.Id, rec.Price.Id)
+	case opApplyPrice:
+		_, err = db.applyPrice(rec.Id, rec.Price.Id)
+	case opAddMedia:
+		_, err = db.addMedia(*rec.Media)
+	case opUpdateMedia:
+		_, err = db.updateMedia(rec.Id, rec.Media.Id, rec.Media.Position, rec.Media.Primary)
+	case opDeleteMedia:
+		_, err = db.deleteMedia(rec.Id, rec.Media.Id)
+	case opSubscribe:
+		err = db.subscribe(*rec.Subscription)
+	case opUnsubscribe:
+		err = db.unsubscribe(rec.Id)
+	case opBatch:
+		for _, sub := range rec.Batch {
+			sub.Seq, sub.At = rec.Seq, rec.At
+			if err := db.checkBatchHold(sub); err != nil {
+				log.Printf("write-ahead log: skipping batch operation of record %d: %v", rec.Seq, err)
+				continue
+			}
+			db.replay(sub)
+		}
+	default:
+		err = fmt.Errorf("unknown operation %q", rec.Op)
+	}
+	if err != nil {
+		log.Printf("write-ahead log: skipping record %d: %v", rec.Seq, err)
+	}
+}
+
+// checkBatchHold makes the hold check apply made before a logged batch
+// operation. Batches logged before updates recorded their actor only
+// check deletes.
+func (db *Db) checkBatchHold(rec walRecord) error {
+	switch {
+	case rec.Op == opUpdate && rec.Actor != "":
+		return db.checkHold(rec.Car.Id, rec.Actor)
+	case rec.Op == opDelete:
+		return db.checkHold(rec.Id, rec.Actor)
+	}
+	return nil
+}
+
+// snapshot writes the whole store to a new snapshot file and empties the
+// log. The snapshot is fsynced and renamed into place so a crash leaves
+// either the old or the new one.
+func (db *Db) snapshot() error {
+	if db.wal == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(snapshot{Seq: db.wal.seq, Cars: db.cars, Trash: db.trash.cars, History: db.history.versions, Holds: db.holds.list(), Prices: db.prices.list(), Media: db.media.list(), Webhooks: db.webhooks})
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(db.wal.dir, snapshotFile)
+	tmp, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(frame(payload)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+	if dir, err := os.Open(db.wal.dir); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+
+	if err := db.wal.f.Truncate(0); err != nil {
+		return err
+	}
+	db.wal.size = 0
+	return nil
+}
+
+// close flushes a final snapshot and closes the log.
+func (db *Db) close() error {
+	if db.wal == nil {
+		return nil
+	}
+	err := db.snapshot()
+	if cerr := db.wal.f.Close(); err == nil {
+		err = cerr
+	}
+	db.wal = nil
+	return err
+}
+
+// snapshotEvery compacts the log every interval until the process exits.
+func (h *carHandler) snapshotEvery(interval time.Duration) {
+	for range time.Tick(interval) {
+		h.Lock()
+		dealerships.each(func(name string, d *Db) {
+			if err := d.snapshot(); err != nil {
+				log.Printf("snapshot of %s failed: %v", name, err)
+			}
+		})
+		h.Unlock()
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func persistTestCar(id string) *Car {
+	return &Car{ Id: id, Make: "Nissan", Model: "March", Package: "XX", Color: "Gray", Year: 2013, Category: "SUV", Mileage: 799, Price: 2499000, MileageUnit: "mi" }
+}
+
+func TestOpen_WhenReplayingWal(t *testing.T){
+	dir := t.TempDir()
+	var d Db
+	assert.Equal(t, d.open(dir), nil)
+	d.add(persistTestCar("a"))
+	d.add(persistTestCar("b"))
+	updated := persistTestCar("a")
+	updated.Color = "Blue"
+	d.update(updated)
+	d.delete("b", "alice")
+	d.wal.f.Close()
+
+	var replayed Db
+	assert.Equal(t, replayed.open(dir), nil)
+	car, err := replayed.getById("a")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, car.Color, "Blue")
+	assert.Equal(t, len(replayed.cars), 1)
+	assert.Equal(t, replayed.trash.cars[0].DeletedBy, "alice")
+	versions, _ := replayed.getHistory("a")
+	assert.Equal(t, len(versions), 2)
+}
+
+func TestOpen_WhenTailRecordTorn(t *testing.T){
+	dir := t.TempDir()
+	var d Db
+	d.open(dir)
+	d.add(persistTestCar("a"))
+	size := d.wal.size
+	d.add(persistTestCar("b"))
+	d.wal.f.Close()
+
+	path := filepath.Join(dir, walFile)
+	os.Truncate(path, size+5)
+
+	var replayed Db
+	assert.Equal(t, replayed.open(dir), nil)
+	assert.Equal(t, len(replayed.cars), 1)
+	info, _ := os.Stat(path)
+	assert.Equal(t, info.Size(), size)
+
+	replayed.add(persistTestCar("c"))
+	replayed.wal.f.Close()
+
+	var again Db
+	again.open(dir)
+	assert.Equal(t, len(again.cars), 2)
+}
+
+func TestOpen_WhenRecordCorrupt(t *testing.T){
+	dir := t.TempDir()
+	var d Db
+	d.open(dir)
+	d.add(persistTestCar("a"))
+	size := d.wal.size
+	d.add(persistTestCar("b"))
+	d.wal.f.Close()
+
+	path := filepath.Join(dir, walFile)
+	data, _ := os.ReadFile(path)
+	data[size+headerSize+2] ^= 0xff
+	os.WriteFile(path, data, 0644)
+
+	var replayed Db
+	assert.Equal(t, replayed.open(dir), nil)
+	assert.Equal(t, len(replayed.cars), 1)
+}
+
+func TestOpen_WhenSnapshotAndWal(t *testing.T){
+	dir := t.TempDir()
+	var d Db
+	d.open(dir)
+	d.add(persistTestCar("a"))
+	assert.Equal(t, d.snapshot(), nil)
+	assert.Equal(t, d.wal.size, int64(0))
+	d.add(persistTestCar("b"))
+	d.wal.f.Close()
+
+	var replayed Db
+	assert.Equal(t, replayed.open(dir), nil)
+	assert.Equal(t, len(replayed.cars), 2)
+	assert.Equal(t, replayed.wal.seq, uint64(2))
+	results, _ := replayed.search("nissan")
+	assert.Equal(t, len(results), 2)
+}
+
+func TestOpen_WhenSnapshotCorrupt(t *testing.T){
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, snapshotFile), []byte("garbage!!"), 0644)
+
+	var d Db
+	err := d.open(dir)
+
+	assert.NotEqual(t, err, nil)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"time"
+)
+
+var errScheduledPriceNotFound = errors.New("scheduled price not found")
+
+// scheduledPrice changes the price of a car when EffectiveAt is reached.
+// @Description a future price of a car
+type scheduledPrice struct {
+	Id          string    `json:"id"`
+	CarId       string    `json:"car_id"`
+	Price       float64   `json:"price"`
+	EffectiveAt time.Time `json:"effective_at"`
+	ScheduledBy string    `json:"scheduled_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// priceChange asks for the price of a car to change at EffectiveAt.
+// @Description scheduled price change request
+type priceChange struct {
+	Price       float64   `json:"price"`
+	EffectiveAt time.Time `json:"effective_at"`
+}
+
+// pricePoint is a price a car had from At on.
+// @Description a past price of a car
+type pricePoint struct {
+	Price float64   `json:"price"`
+	At    time.Time `json:"at"`
+}
+
+// priceTimeline is the past and scheduled prices of a car.
+// @Description price timeline of a car
+type priceTimeline struct {
+	CarId         string           `json:"car_id"`
+	Price         float64          `json:"price"`
+	PreviousPrice float64          `json:"previous_price,omitempty"`
+	History       []pricePoint     `json:"history"`
+	Scheduled     []scheduledPrice `json:"scheduled"`
+}
+
+// priceSchedule holds the pending price changes of every car, keyed by car
+// id and ordered by EffectiveAt.
+type priceSchedule struct {
+	byCar map[string][]scheduledPrice
+}
+
+func (s *priceSchedule) put(p scheduledPrice) {
+	if s.byCar == nil {
+		s.byCar = map[string][]scheduledPrice{}
+	}
+	list := append(s.byCar[p.CarId], p)
+	sort.SliceStable(list, func(i, j int) bool { return list[i].EffectiveAt.Before(list[j].EffectiveAt) })
+	s.byCar[p.CarId] = list
+}
+
+func (s *priceSchedule) take(carId, id string) (scheduledPrice, error) {
+	for i, p := range s.byCar[carId] {
+		if p.Id == id {
+			s.byCar[carId] = append(s.byCar[carId][:i], s.byCar[carId][i+1:]...)
+			if len(s.byCar[carId]) == 0 {
+				delete(s.byCar, carId)
+			}
+			return p, nil
+		}
+	}
+	return scheduledPrice{}, errScheduledPriceNotFound
+}
+
+func (s *priceSchedule) forCar(carId string) []scheduledPrice {
+	return append([]scheduledPrice{}, s.byCar[carId]...)
+}
+
+func (s *priceSchedule) list() []scheduledPrice {
+	out := []scheduledPrice{}
+	for _, ps := range s.byCar {
+		out = append(out, ps...)
+	}
+	return out
+}
+
+// due lists the scheduled prices whose time has come, oldest first.
+func (s *priceSchedule) due(at time.Time) []scheduledPrice {
+	out := []scheduledPrice{}
+	for _, ps := range s.byCar {
+		for _, p := range ps {
+			if !p.EffectiveAt.After(at) {
+				out = append(out, p)
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].EffectiveAt.Before(out[j].EffectiveAt) })
+	return out
+}
+
+func (db *Db) schedulePrice(p scheduledPrice) (scheduledPrice, error) {
+	if _, err := db.getById(p.CarId); err != nil {
+		return scheduledPrice{}, err
+	}
+
+	if err := db.wal.append(walRecord{Op: opSchedulePrice, Price: &p}); err != nil {
+		return scheduledPrice{}, err
+	}
+
+	db.prices.put(p)
+	return p, nil
+}
+
+func (db *Db) cancelPrice(carId, id string) (scheduledPrice, error) {
+	if _, err := db.getById(carId); err != nil {
+		return scheduledPrice{}, err
+	}
+	for _, p := range db.prices.forCar(carId) {
+		if p.Id == id {
+			if err := db.wal.append(walRecord{Op: opCancelPrice, Id: carId, Price: &p}); err != nil {
+				return scheduledPrice{}, err
+			}
+			return db.prices.take(carId, id)
+		}
+	}
+	return scheduledPrice{}, errScheduledPriceNotFound
+}
+
+// applyPrice makes a scheduled price the current price of its car.
+func (db *Db) applyPrice(carId, id string) (Car, error) {
+	for i, v := range db.cars {
+		if v.Id != carId {
+			continue
+		}
+		for _, p := range db.prices.forCar(carId) {
+			if p.Id != id {
+				continue
+			}
+			if err := db.wal.append(walRecord{Op: opApplyPrice, Id: carId, Price: &p}); err != nil {
+				return Car{}, err
+			}
+			db.prices.take(carId, id)
+			db.setPrice(i, p.Price)
+			db.history.recordBy(actionUpdated, db.cars[i], p.ScheduledBy, "scheduled price")
+			return db.cars[i], nil
+		}
+		return Car{}, errScheduledPriceNotFound
+	}
+	return Car{}, errIdNotFound
+}
+
+// setPrice changes the price of the car at index i, keeping the old one as
+// its previous price.
+func (db *Db) setPrice(i int, price float64) {
+	if db.cars[i].Price != price {
+		db.cars[i].PreviousPrice = db.cars[i].Price
+		db.cars[i].Price = price
+	}
+}
+
+// getPrices builds the timeline of the car from its history and pending
+// schedule.
+func (db *Db) getPrices(id string) (priceTimeline, error) {
+	car, err := db.getById(id)
+	if err != nil {
+		return priceTimeline{}, err
+	}
+
+	versions, _ := db.history.list(id)
+	points := []pricePoint{}
+	for _, v := range versions {
+		if v.Action == actionDeleted {
+			continue
+		}
+		if n := len(points); n == 0 || points[n-1].Price != v.Car.Price {
+			points = append(points, pricePoint{Price: v.Car.Price, At: v.At})
+		}
+	}
+
+	return priceTimeline{CarId: id, Price: car.Price, PreviousPrice: car.PreviousPrice, History: points, Scheduled: db.prices.forCar(id)}, nil
+}
+
+// applyScheduledPrices applies the scheduled prices that are due every
+// interval until the process exits.
+func (h *carHandler) applyScheduledPrices(interval time.Duration) {
+	for range time.Tick(interval) {
+		h.Lock()
+		dealerships.each(func(name string, d *Db) {
+			for _, p := range d.prices.due(now()) {
+				car, err := d.applyPrice(p.CarId, p.Id)
+				if err != nil {
+					log.Printf("applying price %s of %s in %s: %v", p.Id, p.CarId, name, err)
+					continue
+				}
+				bus.publish(eventUpdated, car)
+			}
+		})
+		h.Unlock()
+	}
+}
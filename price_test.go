@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func priceDo(method, path, body string) *httptest.ResponseRecorder {
+	h := &carHandler{}
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("content-type", "application/json")
+	authAs(r, "pat")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func priceChangeJSON(price float64, at time.Time) string {
+	b, _ := json.Marshal(priceChange{Price: price, EffectiveAt: at})
+	return string(b)
+}
+
+func TestUpdateCar_WhenPriceChanges(t *testing.T){
+	persistTestCar("pricecar1").createCar()
+	updated := persistTestCar("pricecar1")
+	updated.Price = 2349000
+	updated.PreviousPrice = 1
+
+	car, err := updated.updateCar()
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, car.Price, 2349000.0)
+	assert.Equal(t, car.PreviousPrice, 2499000.0)
+}
+
+func TestSchedulePrice_WhenEffectiveAtInPast(t *testing.T){
+	persistTestCar("pricecar2").createCar()
+
+	w := priceDo("POST", "/cars/pricecar2/prices", priceChangeJSON(1999000, now().Add(-time.Hour)))
+
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+	assert.Equal(t, strings.Contains(w.Body.String(), "effective_at must be in the future"), true)
+}
+
+func TestSchedulePrice_WhenDue(t *testing.T){
+	persistTestCar("pricecar3").createCar()
+	w := priceDo("POST", "/cars/pricecar3/prices", priceChangeJSON(1999000, now().Add(time.Hour)))
+	assert.Equal(t, w.Code, http.StatusCreated)
+
+	var timeline priceTimeline
+	w = priceDo("GET", "/cars/pricecar3/prices", "")
+	json.Unmarshal(w.Body.Bytes(), &timeline)
+	assert.Equal(t, len(timeline.Scheduled), 1)
+	assert.Equal(t, timeline.Scheduled[0].ScheduledBy, "pat")
+
+	clock := now
+	now = func() time.Time { return clock().Add(2 * time.Hour) }
+	defer func() { now = clock }()
+	for _, p := range db.prices.due(now()) {
+		db.applyPrice(p.CarId, p.Id)
+	}
+
+	timeline = priceTimeline{}
+	w = priceDo("GET", "/cars/pricecar3/prices", "")
+	json.Unmarshal(w.Body.Bytes(), &timeline)
+	assert.Equal(t, timeline.Price, 1999000.0)
+	assert.Equal(t, timeline.PreviousPrice, 2499000.0)
+	assert.Equal(t, len(timeline.Scheduled), 0)
+	assert.Equal(t, []float64{timeline.History[0].Price, timeline.History[1].Price}, []float64{2499000, 1999000})
+}
+
+func TestCancelPrice_WhenScheduled(t *testing.T){
+	persistTestCar("pricecar4").createCar()
+	var p scheduledPrice
+	w := priceDo("POST", "/cars/pricecar4/prices", priceChangeJSON(1999000, now().Add(time.Hour)))
+	json.Unmarshal(w.Body.Bytes(), &p)
+
+	w = priceDo("DELETE", "/cars/pricecar4/prices/"+p.Id, "")
+	assert.Equal(t, w.Code, http.StatusNoContent)
+
+	w = priceDo("DELETE", "/cars/pricecar4/prices/"+p.Id, "")
+	assert.Equal(t, w.Code, http.StatusNotFound)
+}
+
+func TestOpen_WhenReplayingScheduledPrices(t *testing.T){
+	dir := t.TempDir()
+	var d Db
+	d.open(dir)
+	d.add(persistTestCar("a"))
+	at := now()
+	d.schedulePrice(scheduledPrice{Id: "p1", CarId: "a", Price: 1999000, EffectiveAt: at})
+	d.schedulePrice(scheduledPrice{Id: "p2", CarId: "a", Price: 1899000, EffectiveAt: at.Add(time.Hour)})
+	d.applyPrice("a", "p1")
+	d.wal.f.Close()
+
+	var replayed Db
+	assert.Equal(t, replayed.open(dir), nil)
+	car, _ := replayed.getById("a")
+	assert.Equal(t, car.Price, 1999000.0)
+	assert.Equal(t, car.PreviousPrice, 2499000.0)
+	assert.Equal(t, len(replayed.prices.forCar("a")), 1)
+}
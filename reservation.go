@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// A reservation holds a car for defaultHoldHours unless the request asks
+// for another duration, up to maxHoldHours.
+const (
+	defaultHoldHours = 48
+	maxHoldHours     = 7 * 24
+)
+
+// Errors holding and releasing cars.
+var (
+	errReservationNotFound = errors.New("reservation not found")
+	errAlreadyHeld         = errors.New("car already held")
+	errHeld                = errors.New("car is held")
+	errReservationHeld     = errors.New("reservation held")
+)
+
+// reservation holds a car for a customer until it expires. While it is
+// active only HeldBy may update, delete or transition the car.
+// @Description a hold on a car for a customer
+type reservation struct {
+	Id        string    `json:"id"`
+	CarId     string    `json:"car_id"`
+	Customer  string    `json:"customer"`
+	HeldBy    string    `json:"held_by"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// reservationRequest asks for a car to be held for Hours, 48 by default.
+// @Description reservation request
+type reservationRequest struct {
+	Customer string `json:"customer"`
+	Hours    int    `json:"hours"`
+}
+
+func (r reservation) active() bool {
+	return r.ExpiresAt.After(now())
+}
+
+// carHolds holds the reservation of every held car, keyed by car id.
+type carHolds struct {
+	byCar map[string]reservation
+}
+
+func (h *carHolds) get(id string) (reservation, bool) {
+	r, ok := h.byCar[id]
+	return r, ok
+}
+
+func (h *carHolds) put(r reservation) {
+	if h.byCar == nil {
+		h.byCar = map[string]reservation{}
+	}
+	h.byCar[r.CarId] = r
+}
+
+func (h *carHolds) remove(id string) {
+	delete(h.byCar, id)
+}
+
+func (h *carHolds) list() []reservation {
+	out := []reservation{}
+	for _, r := range h.byCar {
+		out = append(out, r)
+	}
+	return out
+}
+
+// expired lists the ids of the cars whose hold has run out.
+func (h *carHolds) expired() []string {
+	ids := []string{}
+	for id, r := range h.byCar {
+		if !r.active() {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// hold reserves an available car, moving it to reserved. A hold that has
+// run out is released first.
+func (db *Db) hold(r reservation) (reservation, error) {
+	index := -1
+	for i, v := range db.cars {
+		if v.Id == r.CarId {
+			index = i
+		}
+	}
+	if index == -1 {
+		return reservation{}, errIdNotFound
+	}
+
+	if held, ok := db.holds.get(r.CarId); ok {
+		if held.active() {
+			return reservation{}, fmt.Errorf("%w until %s", errAlreadyHeld, held.ExpiresAt.Format(time.RFC3339))
+		}
+		if _, err := db.release(r.CarId, "system", "reservation expired"); err != nil {
+			return reservation{}, err
+		}
+	}
+
+	if err := canTransition(db.cars[index].Status, statusReserved); err != nil {
+		return reservation{}, err
+	}
+	if err := db.wal.append(walRecord{Op: opHold, Reservation: &r}); err != nil {
+		return reservation{}, err
+	}
+
+	db.holds.put(r)
+	db.cars[index].Status = statusReserved
+	db.history.recordBy(actionTransitioned, db.cars[index], r.HeldBy, "held for "+r.Customer)
+	return r, nil
+}
+
+// release ends the hold on the car, moving it back to available if it is
+// still reserved.
+func (db *Db) release(id, actor, reason string) (Car, error) {
+	if _, ok := db.holds.get(id); !ok {
+		return Car{}, errReservationNotFound
+	}
+
+	if err := db.wal.append(walRecord{Op: opRelease, Id: id, Actor: actor, Reason: reason}); err != nil {
+		return Car{}, err
+	}
+
+	db.holds.remove(id)
+	for i, v := range db.cars {
+		if v.Id == id {
+			if v.Status == statusReserved {
+				db.cars[i].Status = statusAvailable
+				db.history.recordBy(actionTransitioned, db.cars[i], actor, reason)
+			}
+			return db.cars[i], nil
+		}
+	}
+	return Car{}, nil
+}
+
+// checkHold fails when the car is held by someone other than actor.
+func (db *Db) checkHold(id, actor string) error {
+	if r, ok := db.holds.get(id); ok && r.active() && r.HeldBy != actor {
+		return fmt.Errorf("%w by %s until %s", errHeld, r.HeldBy, r.ExpiresAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (db *Db) getHolds(id string) ([]reservation, error) {
+	if _, err := db.getById(id); err != nil {
+		return []reservation{}, err
+	}
+	if r, ok := db.holds.get(id); ok && r.active() {
+		return []reservation{r}, nil
+	}
+	return []reservation{}, nil
+}
+
+// releaseExpiredHolds releases the holds that have run out every interval
+// until the process exits.
+func (h *carHandler) releaseExpiredHolds(interval time.Duration) {
+	for range time.Tick(interval) {
+		h.Lock()
+		dealerships.each(func(name string, d *Db) {
+			for _, id := range d.holds.expired() {
+				car, err := d.release(id, "system", "reservation expired")
+				if err != nil {
+					log.Printf("releasing hold on %s in %s: %v", id, name, err)
+					continue
+				}
+				bus.publish(eventUpdated, car)
+			}
+		})
+		h.Unlock()
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func reservationDo(method, path, actor, body string) *httptest.ResponseRecorder {
+	h := &carHandler{}
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("content-type", "application/json")
+	if actor != "" {
+		authAs(r, actor)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func reserveTestCar(t *testing.T, id, actor string) reservation {
+	persistTestCar(id).createCar()
+	w := reservationDo("POST", "/cars/"+id+"/reservations", actor, `{"customer": "CUST-42"}`)
+	assert.Equal(t, w.Code, http.StatusCreated)
+
+	var r reservation
+	json.Unmarshal(w.Body.Bytes(), &r)
+	return r
+}
+
+func TestReserve_WhenCarAvailable(t *testing.T){
+	r := reserveTestCar(t, "holdcar1", "rita")
+
+	assert.Equal(t, r.HeldBy, "rita")
+	assert.Equal(t, r.Customer, "CUST-42")
+	assert.Equal(t, r.ExpiresAt.Sub(r.CreatedAt), 48*time.Hour)
+	car, _ := db.getById("holdcar1")
+	assert.Equal(t, car.Status, statusReserved)
+
+	var rs []reservation
+	w := reservationDo("GET", "/cars/holdcar1/reservations", "bob", "")
+	json.Unmarshal(w.Body.Bytes(), &rs)
+	assert.Equal(t, len(rs), 1)
+	assert.Equal(t, rs[0].Id, r.Id)
+}
+
+func TestReserve_WhenAlreadyHeld(t *testing.T){
+	reserveTestCar(t, "holdcar2", "rita")
+
+	w := reservationDo("POST", "/cars/holdcar2/reservations", "bob", `{"customer": "CUST-7"}`)
+
+	assert.Equal(t, w.Code, http.StatusConflict)
+	assert.Equal(t, strings.Contains(w.Body.String(), "car already held until"), true)
+}
+
+func TestReserve_WhenCustomerEmpty(t *testing.T){
+	persistTestCar("holdcar3").createCar()
+
+	w := reservationDo("POST", "/cars/holdcar3/reservations", "rita", `{"hours": 2}`)
+
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+	assert.Equal(t, strings.Contains(w.Body.String(), "customer field empty"), true)
+}
+
+func TestReserve_WhenAnonymous(t *testing.T){
+	persistTestCar("holdcar9").createCar()
+
+	w := reservationDo("POST", "/cars/holdcar9/reservations", "", `{"customer": "CUST-42"}`)
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+
+	h := &carHandler{}
+	r := httptest.NewRequest("POST", "/cars/holdcar9/reservations", strings.NewReader(`{"customer": "CUST-42"}`))
+	r.Header.Set("content-type", "application/json")
+	r.Header.Set("X-Actor", "rita")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+}
+
+func TestRelease_WhenActorHeaderSpoofed(t *testing.T){
+	res := reserveTestCar(t, "holdcar10", "rita")
+
+	h := &carHandler{}
+	r := httptest.NewRequest("DELETE", "/cars/holdcar10/reservations/"+res.Id, nil)
+	r.Header.Set("X-Actor", "rita")
+	authAs(r, "bob")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, w.Code, http.StatusForbidden)
+	assert.Equal(t, strings.Contains(w.Body.String(), "reservation held by rita"), true)
+}
+
+func TestUpdateCar_WhenHeldBySomeoneElse(t *testing.T){
+	reserveTestCar(t, "holdcar4", "rita")
+	body, _ := json.Marshal(persistTestCar("holdcar4"))
+
+	w := reservationDo("PUT", "/cars", "bob", string(body))
+	assert.Equal(t, w.Code, http.StatusConflict)
+	assert.Equal(t, strings.Contains(w.Body.String(), "car is held by rita"), true)
+
+	w = reservationDo("DELETE", "/cars/holdcar4", "bob", "")
+	assert.Equal(t, w.Code, http.StatusConflict)
+
+	w = reservationDo("POST", "/cars/holdcar4:transition", "bob", `{"status": "sold"}`)
+	assert.Equal(t, w.Code, http.StatusConflict)
+
+	w = reservationDo("PUT", "/cars", "rita", string(body))
+	assert.Equal(t, w.Code, http.StatusOK)
+}
+
+func TestPricesAndMedia_WhenHeldBySomeoneElse(t *testing.T){
+	reserveTestCar(t, "holdcar11", "rita")
+	change := `{"price": 1999000, "effective_at": "` + now().Add(time.Hour).Format(time.RFC3339) + `"}`
+
+	for _, r := range []struct{ method, path, body string }{
+		{ "POST", "/cars/holdcar11/prices", change },
+		{ "DELETE", "/cars/holdcar11/prices/p1", "" },
+		{ "PATCH", "/cars/holdcar11/media/m1", `{"position": 0}` },
+		{ "DELETE", "/cars/holdcar11/media/m1", "" },
+	} {
+		w := reservationDo(r.method, r.path, "bob", r.body)
+		assert.Equal(t, w.Code, http.StatusConflict)
+	}
+
+	w := reservationDo("POST", "/cars/holdcar11/prices", "rita", change)
+	assert.Equal(t, w.Code, http.StatusCreated)
+}
+
+func TestReserve_WhenHoldExpired(t *testing.T){
+	reserveTestCar(t, "holdcar5", "rita")
+	clock := now
+	now = func() time.Time { return clock().Add(49 * time.Hour) }
+	defer func() { now = clock }()
+
+	body, _ := json.Marshal(persistTestCar("holdcar5"))
+	w := reservationDo("PUT", "/cars", "bob", string(body))
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	w = reservationDo("POST", "/cars/holdcar5/reservations", "bob", `{"customer": "CUST-7"}`)
+	assert.Equal(t, w.Code, http.StatusCreated)
+	versions, _ := db.getHistory("holdcar5")
+	assert.Equal(t, versions[len(versions)-2].Reason, "reservation expired")
+}
+
+func TestRelease_WhenHolder(t *testing.T){
+	r := reserveTestCar(t, "holdcar6", "rita")
+
+	w := reservationDo("DELETE", "/cars/holdcar6/reservations/"+r.Id, "bob", "")
+	assert.Equal(t, w.Code, http.StatusForbidden)
+
+	w = reservationDo("DELETE", "/cars/holdcar6/reservations/"+r.Id, "rita", "")
+	var car Car
+	json.Unmarshal(w.Body.Bytes(), &car)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, car.Status, statusAvailable)
+
+	w = reservationDo("DELETE", "/cars/holdcar6/reservations/"+r.Id, "rita", "")
+	assert.Equal(t, w.Code, http.StatusNotFound)
+}
+
+func TestTransition_WhenHolderSellsCar(t *testing.T){
+	reserveTestCar(t, "holdcar7", "rita")
+
+	w := reservationDo("POST", "/cars/holdcar7:transition", "rita", `{"status": "sold"}`)
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	_, held := db.holds.get("holdcar7")
+	assert.Equal(t, held, false)
+}
+
+func TestOpen_WhenReplayingHolds(t *testing.T){
+	dir := t.TempDir()
+	var d Db
+	d.open(dir)
+	d.add(persistTestCar("a"))
+	d.add(persistTestCar("b"))
+	at := now()
+	d.hold(reservation{Id: "r1", CarId: "a", Customer: "CUST-1", HeldBy: "rita", CreatedAt: at, ExpiresAt: at.Add(time.Hour)})
+	d.hold(reservation{Id: "r2", CarId: "b", Customer: "CUST-2", HeldBy: "rita", CreatedAt: at, ExpiresAt: at.Add(time.Hour)})
+	d.release("b", "rita", "reservation released")
+	d.wal.f.Close()
+
+	var replayed Db
+	assert.Equal(t, replayed.open(dir), nil)
+	r, held := replayed.holds.get("a")
+	assert.Equal(t, held, true)
+	assert.Equal(t, r.Customer, "CUST-1")
+	_, held = replayed.holds.get("b")
+	assert.Equal(t, held, false)
+	car, _ := replayed.getById("b")
+	assert.Equal(t, car.Status, statusAvailable)
+}
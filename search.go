@@ -0,0 +1,248 @@
+package main
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// searchResult is a single hit returned by GET /cars/search.
+// @Description search hit with relevance score and highlighted fields
+type searchResult struct {
+	Car        Car               `json:"car"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// Scores given to a query token depending on how it matched an indexed term.
+const (
+	scoreExact  = 3.0
+	scorePrefix = 2.0
+	scoreFuzzy  = 1.0
+)
+
+// searchIndex is an inverted index from lowercase tokens to the cars and
+// fields that contain them. Db keeps it in sync on add, update and delete.
+// The vocabulary is also kept sorted, for prefix lookups, and bucketed by
+// length, so fuzzy lookups only compare terms of a reachable length.
+type searchIndex struct {
+	postings map[string]map[string]map[string]bool
+	terms    []string
+	byLength map[int]map[string]bool
+}
+
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func searchableFields(c Car) map[string]string {
+	return map[string]string{
+		"make":     c.Make,
+		"model":    c.Model,
+		"package":  c.Package,
+		"color":    c.Color,
+		"category": c.Category,
+	}
+}
+
+func (idx *searchIndex) add(c Car) {
+	if idx.postings == nil {
+		idx.postings = map[string]map[string]map[string]bool{}
+	}
+	for field, value := range searchableFields(c) {
+		for _, t := range tokenize(value) {
+			if idx.postings[t] == nil {
+				idx.postings[t] = map[string]map[string]bool{}
+				idx.addTerm(t)
+			}
+			if idx.postings[t][c.Id] == nil {
+				idx.postings[t][c.Id] = map[string]bool{}
+			}
+			idx.postings[t][c.Id][field] = true
+		}
+	}
+}
+
+func (idx *searchIndex) remove(id string) {
+	for t, cars := range idx.postings {
+		delete(cars, id)
+		if len(cars) == 0 {
+			delete(idx.postings, t)
+			idx.removeTerm(t)
+		}
+	}
+}
+
+func (idx *searchIndex) addTerm(t string) {
+	i := sort.SearchStrings(idx.terms, t)
+	idx.terms = append(idx.terms, "")
+	copy(idx.terms[i+1:], idx.terms[i:])
+	idx.terms[i] = t
+
+	if idx.byLength == nil {
+		idx.byLength = map[int]map[string]bool{}
+	}
+	n := len([]rune(t))
+	if idx.byLength[n] == nil {
+		idx.byLength[n] = map[string]bool{}
+	}
+	idx.byLength[n][t] = true
+}
+
+func (idx *searchIndex) removeTerm(t string) {
+	if i := sort.SearchStrings(idx.terms, t); i < len(idx.terms) && idx.terms[i] == t {
+		idx.terms = append(idx.terms[:i], idx.terms[i+1:]...)
+	}
+	delete(idx.byLength[len([]rune(t))], t)
+}
+
+// maxTypos is the edit distance tolerated for a query token of the given
+// length. Short tokens must match exactly or by prefix.
+func maxTypos(token string) int {
+	switch n := len([]rune(token)); {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	}
+	return 0
+}
+
+// lookup returns, for every car matching token, its best score and the
+// indexed terms that matched.
+func (idx *searchIndex) lookup(token string) (map[string]float64, map[string][]string) {
+	scores := map[string]float64{}
+	terms := map[string][]string{}
+
+	matches := map[string]float64{}
+	if idx.postings[token] != nil {
+		matches[token] = scoreExact
+	}
+	for i := sort.SearchStrings(idx.terms, token); i < len(idx.terms) && strings.HasPrefix(idx.terms[i], token); i++ {
+		if idx.terms[i] != token {
+			matches[idx.terms[i]] = scorePrefix
+		}
+	}
+	if typos := maxTypos(token); typos > 0 {
+		n := len([]rune(token))
+		for l := n - typos; l <= n+typos; l++ {
+			for term := range idx.byLength[l] {
+				if _, ok := matches[term]; !ok && levenshtein(term, token) <= typos {
+					matches[term] = scoreFuzzy
+				}
+			}
+		}
+	}
+
+	for term, score := range matches {
+		for id := range idx.postings[term] {
+			if score > scores[id] {
+				scores[id] = score
+			}
+			terms[id] = append(terms[id], term)
+		}
+	}
+
+	return scores, terms
+}
+
+// search returns the cars matching every token of q, best match first.
+func (idx *searchIndex) search(q string, cars []Car) []searchResult {
+	tokens := tokenize(q)
+	if len(tokens) == 0 {
+		return []searchResult{}
+	}
+
+	var total map[string]float64
+	var matched map[string][]string
+	for i, t := range tokens {
+		scores, terms := idx.lookup(t)
+		if i == 0 {
+			total, matched = scores, terms
+			continue
+		}
+		for id := range total {
+			s, ok := scores[id]
+			if !ok {
+				delete(total, id)
+				continue
+			}
+			total[id] += s
+			matched[id] = append(matched[id], terms[id]...)
+		}
+	}
+
+	results := []searchResult{}
+	for _, c := range cars {
+		if score, ok := total[c.Id]; ok {
+			results = append(results, searchResult{Car: c, Score: score / float64(len(tokens)), Highlights: highlight(c, matched[c.Id])})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return results
+}
+
+// highlight wraps every word of the car's searchable fields that matched
+// one of terms in <em> tags. Words are HTML escaped, so the fields can be
+// rendered as they are. Fields without matches are left out.
+func highlight(c Car, terms []string) map[string]string {
+	hit := map[string]bool{}
+	for _, t := range terms {
+		hit[t] = true
+	}
+
+	out := map[string]string{}
+	for field, value := range searchableFields(c) {
+		words := strings.Fields(value)
+		found := false
+		for i, w := range words {
+			words[i] = html.EscapeString(w)
+			for _, t := range tokenize(w) {
+				if hit[t] {
+					words[i] = "<em>" + words[i] + "</em>"
+					found = true
+					break
+				}
+			}
+		}
+		if found {
+			out[field] = strings.Join(words, " ")
+		}
+	}
+	return out
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestIndex(cars []Car) searchIndex {
+	var idx searchIndex
+	for _, c := range cars {
+		idx.add(c)
+	}
+	return idx
+}
+
+var searchCars = []Car{
+	{ Id: "rav", Make: "Toyota", Model: "Rav4", Package: "XSE", Color: "Red", Category: "SUV" },
+	{ Id: "cam", Make: "Toyota", Model: "Camry", Package: "SE", Color: "White", Category: "Sedan" },
+	{ Id: "bro", Make: "Ford", Model: "Bronco", Package: "Badlands", Color: "Burnt Orange", Category: "SUV" },
+}
+
+func TestSearch_WhenAllTermsMatch(t *testing.T){
+	idx := newTestIndex(searchCars)
+	results := idx.search("toyota rav4 xse red", searchCars)
+
+	assert.Equal(t, len(results), 1)
+	assert.Equal(t, results[0].Car.Id, "rav")
+	assert.Equal(t, results[0].Highlights["model"], "<em>Rav4</em>")
+}
+
+func TestSearch_WhenQueryHasTypo(t *testing.T){
+	idx := newTestIndex(searchCars)
+	results := idx.search("toyta", searchCars)
+
+	assert.Equal(t, len(results), 2)
+}
+
+func TestSearch_WhenRankingByRelevance(t *testing.T){
+	idx := newTestIndex(searchCars)
+	results := idx.search("se", searchCars)
+
+	assert.Equal(t, results[0].Car.Id, "cam")
+	assert.Equal(t, results[0].Score, scoreExact)
+}
+
+func TestSearch_WhenCarRemoved(t *testing.T){
+	idx := newTestIndex(searchCars)
+	idx.remove("bro")
+	results := idx.search("bronco", searchCars)
+
+	assert.Equal(t, len(results), 0)
+}
+
+func TestSearchCars_WhenQueryEmpty(t *testing.T){
+	var car Car
+	_, err := car.searchCars(" ")
+
+	assert.Equal(t, err.Error(), "q field empty")
+}
+
+func TestSearch_WhenFieldHasMarkup(t *testing.T){
+	cars := []Car{{ Id: "xss", Make: "<script>alert(1)</script>", Model: "Ford & Sons" }}
+	idx := newTestIndex(cars)
+	results := idx.search("script sons", cars)
+
+	assert.Equal(t, results[0].Highlights["make"], "<em>&lt;script&gt;alert(1)&lt;/script&gt;</em>")
+	assert.Equal(t, results[0].Highlights["model"], "Ford &amp; <em>Sons</em>")
+}
+
+func TestLookup_WhenTermsRemoved(t *testing.T){
+	idx := newTestIndex(searchCars)
+	idx.remove("rav")
+	idx.remove("cam")
+
+	assert.Equal(t, idx.terms, []string{"badlands", "bronco", "burnt", "ford", "orange", "suv"})
+	scores, _ := idx.lookup("fird")
+	assert.Equal(t, scores, map[string]float64{"bro": scoreFuzzy})
+}
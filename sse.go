@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// replayBufferSize is how many recent events GET /cars/events can replay
+// to a client resuming with Last-Event-ID.
+const replayBufferSize = 1000
+
+// clientBufferSize is how many events may queue up for a slow client
+// before it is disconnected.
+const clientBufferSize = 64
+
+// eventStream keeps the most recent car events and fans new ones out to
+// the connected Server-Sent Events clients.
+type eventStream struct {
+	sync.Mutex
+	size    int
+	buffer  []carEvent
+	clients map[chan carEvent]bool
+	// start is the id of the last event before the stream was created, and
+	// last the id of the last event published to it.
+	start, last uint64
+}
+
+func newEventStream(size int, start uint64) *eventStream {
+	return &eventStream{size: size, clients: map[chan carEvent]bool{}, start: start, last: start}
+}
+
+func (s *eventStream) publish(e carEvent) {
+	defer s.Unlock()
+	s.Lock()
+
+	s.last = e.Id
+	s.buffer = append(s.buffer, e)
+	if len(s.buffer) > s.size {
+		s.buffer = s.buffer[len(s.buffer)-s.size:]
+	}
+
+	for ch := range s.clients {
+		select {
+		case ch <- e:
+		default:
+			delete(s.clients, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe registers a client and returns the buffered events after
+// lastId. complete is false when events after lastId were already evicted
+// from the buffer, or when lastId is not from this stream, e.g. it was
+// received before the server restarted.
+func (s *eventStream) subscribe(lastId uint64) (ch chan carEvent, backlog []carEvent, complete bool) {
+	defer s.Unlock()
+	s.Lock()
+
+	ch = make(chan carEvent, clientBufferSize)
+	s.clients[ch] = true
+
+	complete = lastId >= s.start && lastId <= s.last && (len(s.buffer) == 0 || s.buffer[0].Id <= lastId+1)
+	for _, e := range s.buffer {
+		if e.Id > lastId {
+			backlog = append(backlog, e)
+		}
+	}
+	return ch, backlog, complete
+}
+
+func (s *eventStream) unsubscribe(ch chan carEvent) {
+	defer s.Unlock()
+	s.Lock()
+
+	if s.clients[ch] {
+		delete(s.clients, ch)
+		close(ch)
+	}
+}
+
+// eventFilter restricts a stream to the cars of a dealership, and
+// optionally of a make and/or category.
+type eventFilter struct {
+	dealership string
+	make       string
+	category   string
+}
+
+func (f eventFilter) match(e carEvent) bool {
+	if f.dealership != "" && orDefault(e.Car.Dealership) != f.dealership {
+		return false
+	}
+	if f.make != "" && !strings.EqualFold(e.Car.Make, f.make) {
+		return false
+	}
+	if f.category != "" && !strings.EqualFold(e.Car.Category, f.category) {
+		return false
+	}
+	return true
+}
+
+// writeEvent writes e in the text/event-stream format.
+func writeEvent(w io.Writer, e carEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Id, e.Type, data)
+	return err
+}
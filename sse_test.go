@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribe_WhenResumingFromLastEventId(t *testing.T){
+	s := newEventStream(3, 0)
+	for i := uint64(1); i <= 5; i++ {
+		s.publish(carEvent{ Id: i })
+	}
+
+	_, backlog, complete := s.subscribe(3)
+	assert.Equal(t, len(backlog), 2)
+	assert.Equal(t, backlog[0].Id, uint64(4))
+	assert.Equal(t, complete, true)
+
+	_, backlog, complete = s.subscribe(1)
+	assert.Equal(t, len(backlog), 3)
+	assert.Equal(t, complete, false)
+}
+
+func TestPublish_WhenClientTooSlow(t *testing.T){
+	s := newEventStream(10, 0)
+	ch, _, _ := s.subscribe(0)
+	for i := uint64(1); i <= clientBufferSize+1; i++ {
+		s.publish(carEvent{ Id: i })
+	}
+
+	assert.Equal(t, len(s.clients), 0)
+	s.unsubscribe(ch)
+}
+
+func TestEvents_WhenStreamingFilteredEvents(t *testing.T){
+	h := &carHandler{ stream: newEventStream(10, 0) }
+	h.stream.publish(carEvent{ Id: 1, Type: eventCreated, Car: Car{ Id: "a", Make: "Ford" } })
+	h.stream.publish(carEvent{ Id: 2, Type: eventCreated, Car: Car{ Id: "b", Make: "Toyota" } })
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	req, _ := http.NewRequest("GET", srv.URL+"/cars/events?make=toyota", nil)
+	req.Header.Set("Last-Event-ID", "0")
+	res, err := http.DefaultClient.Do(req)
+	assert.Equal(t, err, nil)
+	defer res.Body.Close()
+	assert.Equal(t, res.Header.Get("content-type"), "text/event-stream")
+
+	h.stream.publish(carEvent{ Id: 3, Type: eventDeleted, Car: Car{ Id: "c", Make: "Ford" } })
+	h.stream.publish(carEvent{ Id: 4, Type: eventUpdated, Car: Car{ Id: "b", Make: "Toyota" } })
+
+	reader := bufio.NewReader(res.Body)
+	ids := []string{}
+	for len(ids) < 2 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		if strings.HasPrefix(line, "id: ") {
+			ids = append(ids, strings.TrimSpace(strings.TrimPrefix(line, "id: ")))
+		}
+	}
+
+	assert.Equal(t, ids, []string{ "2", "4" })
+}
+
+func TestSubscribe_WhenLastEventIdFromAnotherRun(t *testing.T){
+	start := eventEpoch(time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC))
+	s := newEventStream(3, start)
+
+	_, backlog, complete := s.subscribe(start - 5)
+	assert.Equal(t, len(backlog), 0)
+	assert.Equal(t, complete, false)
+
+	s.publish(carEvent{ Id: start + 1 })
+
+	_, _, complete = s.subscribe(start + 1)
+	assert.Equal(t, complete, true)
+	_, _, complete = s.subscribe(start + 9)
+	assert.Equal(t, complete, false)
+	assert.Equal(t, eventEpoch(time.Date(2023, 10, 1, 12, 0, 1, 0, time.UTC)) > start + 1, true)
+}
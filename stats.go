@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var defaultPercentiles = []float64{25, 50, 75, 90}
+
+// fieldStats summarises one numeric field over a group of cars.
+type fieldStats struct {
+	Sum         float64            `json:"sum"`
+	Min         float64            `json:"min"`
+	Max         float64            `json:"max"`
+	Mean        float64            `json:"mean"`
+	Median      float64            `json:"median"`
+	Percentiles map[string]float64 `json:"percentiles"`
+}
+
+// statsGroup holds the aggregates of the cars sharing the same values for
+// the group_by fields.
+// @Description aggregates for one group of cars
+type statsGroup struct {
+	Key     map[string]string     `json:"key"`
+	Count   int                   `json:"count"`
+	Metrics map[string]fieldStats `json:"metrics"`
+}
+
+// statsQuery describes how GET /cars/stats groups and aggregates cars.
+type statsQuery struct {
+	groupBy     []string
+	fields      []string
+	percentiles []float64
+}
+
+var groupableFields = map[string]func(Car) string{
+	"make":     func(c Car) string { return c.Make },
+	"model":    func(c Car) string { return c.Model },
+	"package":  func(c Car) string { return c.Package },
+	"color":    func(c Car) string { return c.Color },
+	"category": func(c Car) string { return c.Category },
+	"year":     func(c Car) string { return strconv.Itoa(c.Year) },
+}
+
+var numericFields = map[string]func(Car) float64{
+	"price":   func(c Car) float64 { return c.Price },
+	"mileage": func(c Car) float64 { return c.Mileage },
+	"year":    func(c Car) float64 { return float64(c.Year) },
+}
+
+func splitList(s string) []string {
+	out := []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func parseStatsQuery(groupBy, fields, percentiles string) (statsQuery, error) {
+	q := statsQuery{groupBy: splitList(groupBy), fields: splitList(fields)}
+
+	if len(q.fields) == 0 {
+		q.fields = []string{"price", "mileage"}
+	}
+
+	for _, p := range splitList(percentiles) {
+		n, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return statsQuery{}, fmt.Errorf("percentiles must be numbers")
+		}
+		q.percentiles = append(q.percentiles, n)
+	}
+	if len(q.percentiles) == 0 {
+		q.percentiles = defaultPercentiles
+	}
+
+	return q, nil
+}
+
+// computeStats groups cars in a single pass and aggregates each group.
+// Mileage is reported in units.
+func computeStats(cars []Car, q statsQuery, units string) []statsGroup {
+	groups := map[string]*statsGroup{}
+	values := map[string]map[string][]float64{}
+	keys := []string{}
+
+	for _, c := range cars {
+		key := map[string]string{}
+		parts := []string{}
+		for _, f := range q.groupBy {
+			key[f] = groupableFields[f](c)
+			parts = append(parts, key[f])
+		}
+		k := strings.Join(parts, "\x00")
+
+		if groups[k] == nil {
+			groups[k] = &statsGroup{Key: key, Metrics: map[string]fieldStats{}}
+			values[k] = map[string][]float64{}
+			keys = append(keys, k)
+		}
+		groups[k].Count++
+
+		for _, f := range q.fields {
+			v := numericFields[f](c)
+			if f == "mileage" {
+				v = fromMiles(v, units)
+			}
+			values[k][f] = append(values[k][f], v)
+		}
+	}
+
+	sort.Strings(keys)
+	out := []statsGroup{}
+	for _, k := range keys {
+		g := groups[k]
+		for _, f := range q.fields {
+			g.Metrics[f] = summarize(values[k][f], q.percentiles)
+		}
+		out = append(out, *g)
+	}
+	return out
+}
+
+func summarize(values []float64, percentiles []float64) fieldStats {
+	sort.Float64s(values)
+
+	s := fieldStats{Min: values[0], Max: values[len(values)-1], Percentiles: map[string]float64{}}
+	for _, v := range values {
+		s.Sum += v
+	}
+	s.Mean = s.Sum / float64(len(values))
+	s.Median = percentile(values, 50)
+	for _, p := range percentiles {
+		s.Percentiles["p"+strconv.FormatFloat(p, 'f', -1, 64)] = percentile(values, p)
+	}
+	return s
+}
+
+// percentile interpolates linearly between the closest ranks of sorted.
+func percentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeStats_WhenGroupedByMake(t *testing.T){
+	q, _ := parseStatsQuery("make", "price", "")
+	groups := computeStats(filterCars, q, "mi")
+
+	assert.Equal(t, len(groups), 2)
+	assert.Equal(t, groups[1].Key["make"], "Toyota")
+	assert.Equal(t, groups[1].Count, 2)
+	assert.Equal(t, groups[1].Metrics["price"].Sum, 5000.0)
+	assert.Equal(t, groups[1].Metrics["price"].Median, 2500.0)
+	assert.Equal(t, groups[1].Metrics["price"].Percentiles["p90"], 2900.0)
+}
+
+func TestComputeStats_WhenNotGrouped(t *testing.T){
+	q, _ := parseStatsQuery("", "mileage", "50")
+	groups := computeStats(filterCars, q, "mi")
+
+	assert.Equal(t, len(groups), 1)
+	assert.Equal(t, groups[0].Count, 3)
+	assert.Equal(t, groups[0].Metrics["mileage"].Min, 10.0)
+	assert.Equal(t, groups[0].Metrics["mileage"].Max, 100.0)
+	assert.Equal(t, groups[0].Metrics["mileage"].Median, 50.0)
+}
+
+func TestGetStats_WhenGroupByInvalid(t *testing.T){
+	var car Car
+	q, _ := parseStatsQuery("price", "", "")
+	_, err := car.getStats(carQuery{ units: "mi" }, q)
+
+	assert.Equal(t, err.Error(), "group_by must be one of make, model, package, color, category, year")
+}
+
+func TestGetStats_WhenPercentileOutOfRange(t *testing.T){
+	var car Car
+	q, _ := parseStatsQuery("", "", "101")
+	_, err := car.getStats(carQuery{ units: "mi" }, q)
+
+	assert.Equal(t, err.Error(), "percentiles must be between 0 and 100")
+}
+
+func TestGetStats_WhenPercentileIsNaN(t *testing.T){
+	var car Car
+	q, _ := parseStatsQuery("", "", "NaN")
+	_, err := car.getStats(carQuery{ units: "mi" }, q)
+
+	assert.Equal(t, err.Error(), "percentiles must be between 0 and 100")
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Lifecycle statuses of a car.
+const (
+	statusIncoming  = "incoming"
+	statusInTransit = "in_transit"
+	statusAvailable = "available"
+	statusReserved  = "reserved"
+	statusSold      = "sold"
+)
+
+var errTransition = errors.New("cannot transition")
+
+// statuses lists the lifecycle statuses in order.
+var statuses = []string{statusIncoming, statusInTransit, statusAvailable, statusReserved, statusSold}
+
+// entryStatuses are the statuses a car may be created with.
+var entryStatuses = []string{statusIncoming, statusInTransit, statusAvailable}
+
+// transitions maps each status to the statuses a car may move to from it.
+// Reserved and sold cars may go back to available when a deal falls
+// through. Only a hold makes a car reserved; validate_transition keeps
+// :transition from doing so.
+var transitions = map[string][]string{
+	statusIncoming:  {statusInTransit, statusAvailable},
+	statusInTransit: {statusIncoming, statusAvailable},
+	statusAvailable: {statusInTransit, statusReserved, statusSold},
+	statusReserved:  {statusAvailable, statusSold},
+	statusSold:      {statusAvailable},
+}
+
+// statusTransition asks for a car to move to Status, saying why.
+// @Description status transition of a car
+type statusTransition struct {
+	Status string `json:"status" enums:"incoming,in_transit,available,reserved,sold"`
+	Reason string `json:"reason"`
+}
+
+func validStatus(s string) bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+func validEntryStatus(s string) bool {
+	for _, v := range entryStatuses {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// canTransition returns an error naming the allowed statuses when a car
+// may not move from one status to the other.
+func canTransition(from, to string) error {
+	for _, s := range transitions[from] {
+		if s == to {
+			return nil
+		}
+	}
+	if from == to {
+		return fmt.Errorf("%w from %s to %s: car is already %s", errTransition, from, to, to)
+	}
+	return fmt.Errorf("%w from %s to %s: allowed are %s", errTransition, from, to, strings.Join(transitions[from], ", "))
+}
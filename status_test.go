@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func transitionPost(t *testing.T, id, body string) *httptest.ResponseRecorder {
+	h := &carHandler{}
+	r := httptest.NewRequest("POST", "/cars/"+id+":transition", strings.NewReader(body))
+	r.Header.Set("content-type", "application/json")
+	authAs(r, "sam")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestCreateCar_WhenStatusEmpty(t *testing.T){
+	car, err := persistTestCar("statuscar1").createCar()
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, car.Status, statusAvailable)
+}
+
+func TestCreateCar_WhenStatusGiven(t *testing.T){
+	for i, status := range []string{ statusIncoming, statusInTransit, statusAvailable } {
+		car := persistTestCar(fmt.Sprintf("statuscar2%d", i))
+		car.Status = status
+		created, err := car.createCar()
+
+		assert.Equal(t, err, nil)
+		assert.Equal(t, created.Status, status)
+	}
+}
+
+func TestCreateCar_WhenStatusNotAnEntryStatus(t *testing.T){
+	for i, status := range []string{ statusReserved, statusSold, "lost" } {
+		car := persistTestCar(fmt.Sprintf("statuscar6%d", i))
+		car.Status = status
+		_, err := car.createCar()
+
+		assert.Equal(t, err.Error(), "status field must be incoming, in_transit, available")
+	}
+}
+
+func TestTransition_WhenAllowed(t *testing.T){
+	persistTestCar("statuscar3").createCar()
+
+	w := transitionPost(t, "statuscar3", `{"status": "sold", "reason": "deposit paid"}`)
+
+	var car Car
+	json.Unmarshal(w.Body.Bytes(), &car)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, car.Status, statusSold)
+
+	versions, _ := db.getHistory("statuscar3")
+	last := versions[len(versions)-1]
+	assert.Equal(t, last.Action, actionTransitioned)
+	assert.Equal(t, last.Actor, "sam")
+	assert.Equal(t, last.Reason, "deposit paid")
+	assert.Equal(t, last.Changes["status"], fieldChange{From: statusAvailable, To: statusSold})
+}
+
+func TestTransition_WhenNotAllowed(t *testing.T){
+	persistTestCar("statuscar4").createCar()
+	transitionPost(t, "statuscar4", `{"status": "sold"}`)
+
+	w := transitionPost(t, "statuscar4", `{"status": "in_transit"}`)
+
+	assert.Equal(t, w.Code, http.StatusConflict)
+	assert.Equal(t, strings.Contains(w.Body.String(), "cannot transition from sold to in_transit: allowed are available"), true)
+}
+
+func TestTransition_WhenReserving(t *testing.T){
+	persistTestCar("statuscar9").createCar()
+
+	w := transitionPost(t, "statuscar9", `{"status": "reserved"}`)
+
+	assert.Equal(t, w.Code, http.StatusConflict)
+	assert.Equal(t, strings.Contains(w.Body.String(), "hold the car with a reservation instead"), true)
+	car, _ := db.getById("statuscar9")
+	assert.Equal(t, car.Status, statusAvailable)
+}
+
+func TestTransition_WhenStatusInvalid(t *testing.T){
+	persistTestCar("statuscar5").createCar()
+
+	w := transitionPost(t, "statuscar5", `{"status": "lost"}`)
+
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+}
+
+func TestTransition_WhenIdNotFound(t *testing.T){
+	w := transitionPost(t, "statusmissing", `{"status": "sold"}`)
+
+	assert.Equal(t, w.Code, http.StatusNotFound)
+}
+
+func TestUpdateCar_WhenStatusGiven(t *testing.T){
+	persistTestCar("statuscar6").createCar()
+	updated := persistTestCar("statuscar6")
+	updated.Status = statusSold
+
+	car, err := updated.updateCar()
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, car.Status, statusAvailable)
+}
+
+func TestGetAll_WhenFilteringByStatus(t *testing.T){
+	persistTestCar("statuscar7").createCar()
+	persistTestCar("statuscar8").createCar()
+	transitionPost(t, "statuscar8", `{"status": "in_transit"}`)
+
+	q, _ := parseCarQuery(map[string][]string{"status": {"incoming,in_transit"}})
+	cars, _ := db.getAll()
+	ids := []string{}
+	for _, c := range q.apply(cars) {
+		ids = append(ids, c.Id)
+	}
+
+	assert.Equal(t, strings.Contains(strings.Join(ids, ","), "statuscar8"), true)
+	assert.Equal(t, strings.Contains(strings.Join(ids, ","), "statuscar7"), false)
+}
+
+func TestOpen_WhenReplayingTransition(t *testing.T){
+	dir := t.TempDir()
+	var d Db
+	d.open(dir)
+	d.add(persistTestCar("a"))
+	d.transition("a", statusReserved, "sam", "deposit paid")
+	d.wal.f.Close()
+
+	var replayed Db
+	assert.Equal(t, replayed.open(dir), nil)
+	car, _ := replayed.getById("a")
+	assert.Equal(t, car.Status, statusReserved)
+	versions, _ := replayed.getHistory("a")
+	assert.Equal(t, versions[1].Reason, "deposit paid")
+}
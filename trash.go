@@ -0,0 +1,88 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// defaultTrashRetention is how long a deleted car can be restored before
+// it is purged for good.
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// trashedCar is a soft deleted car waiting to be restored or purged.
+// @Description a deleted car kept in the trash
+type trashedCar struct {
+	Car       Car       `json:"car"`
+	DeletedAt time.Time `json:"deleted_at"`
+	DeletedBy string    `json:"deleted_by"`
+}
+
+// carTrash holds soft deleted cars. Deleting an id that is already in the
+// trash replaces the older entry.
+type carTrash struct {
+	cars []trashedCar
+}
+
+func (t *carTrash) put(c Car, actor string) {
+	t.take(c.Id)
+	t.cars = append(t.cars, trashedCar{Car: c, DeletedAt: now(), DeletedBy: actor})
+}
+
+func (t *carTrash) take(id string) (trashedCar, error) {
+	for i, v := range t.cars {
+		if v.Car.Id == id {
+			t.cars = append(t.cars[:i], t.cars[i+1:]...)
+			return v, nil
+		}
+	}
+	return trashedCar{}, errIdNotFound
+}
+
+func (t *carTrash) contains(id string) bool {
+	for _, v := range t.cars {
+		if v.Car.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// expired counts the cars deleted before cutoff.
+func (t *carTrash) expired(cutoff time.Time) int {
+	n := 0
+	for _, v := range t.cars {
+		if v.DeletedAt.Before(cutoff) {
+			n++
+		}
+	}
+	return n
+}
+
+// purge permanently removes the cars deleted before cutoff and returns
+// how many were removed.
+func (t *carTrash) purge(cutoff time.Time) int {
+	kept := []trashedCar{}
+	for _, v := range t.cars {
+		if !v.DeletedAt.Before(cutoff) {
+			kept = append(kept, v)
+		}
+	}
+	n := len(t.cars) - len(kept)
+	t.cars = kept
+	return n
+}
+
+// purgeTrash runs the retention purge every interval until the process
+// exits.
+func (h *carHandler) purgeTrash(retention, interval time.Duration) {
+	for range time.Tick(interval) {
+		h.Lock()
+		dealerships.each(func(name string, d *Db) {
+			n, _ := d.purge(now().Add(-retention))
+			if n > 0 {
+				log.Printf("purged %d cars from the trash of %s", n, name)
+			}
+		})
+		h.Unlock()
+	}
+}
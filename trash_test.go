@@ -0,0 +1,121 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteCar_WhenMovedToTrash(t *testing.T){
+	car := Car{ Id: "trashme", Make: "Nissan", Model: "Leaf", Package: "XX", Color: "Gray", Year: 2013, Category: "Sedan", Mileage: 799, Price: 2499000 }
+	car.createCar()
+	_, err := car.deleteCar("alice")
+	assert.Equal(t, err, nil)
+
+	_, err = car.getCarById()
+	assert.Equal(t, err.Error(), "id not found")
+
+	trash, _ := car.getDeletedCars()
+	found := false
+	for _, v := range trash {
+		if v.Car.Id == "trashme" {
+			found = true
+			assert.Equal(t, v.DeletedBy, "alice")
+		}
+	}
+	assert.Equal(t, found, true)
+}
+
+func TestRestoreCar_WhenSuccessful(t *testing.T){
+	car := Car{ Id: "restoreme", Make: "Nissan", Model: "Leaf", Package: "XX", Color: "Gray", Year: 2013, Category: "Sedan", Mileage: 799, Price: 2499000 }
+	car.createCar()
+	car.deleteCar("alice")
+	_, err := car.restoreCar()
+	assert.Equal(t, err, nil)
+
+	q, err := car.getCarById()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, q.Model, "Leaf")
+}
+
+func TestRestoreCar_WhenIdInUse(t *testing.T){
+	car := Car{ Id: "reusedid", Make: "Nissan", Model: "Leaf", Package: "XX", Color: "Gray", Year: 2013, Category: "Sedan", Mileage: 799, Price: 2499000 }
+	car.createCar()
+	car.deleteCar("alice")
+	car.createCar()
+	_, err := car.restoreCar()
+
+	assert.Equal(t, err.Error(), "id already exists")
+}
+
+func TestRestoreCar_WhenIdNotInTrash(t *testing.T){
+	car := Car{ Id: "zzzzzzzzz" }
+	_, err := car.restoreCar()
+
+	assert.Equal(t, err.Error(), "id not found")
+}
+
+func TestPurge_WhenRetentionExpired(t *testing.T){
+	start := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	clock := start
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	var trash carTrash
+	trash.put(Car{ Id: "old" }, "alice")
+	clock = start.Add(48 * time.Hour)
+	trash.put(Car{ Id: "new" }, "alice")
+
+	n := trash.purge(clock.Add(-24 * time.Hour))
+
+	assert.Equal(t, n, 1)
+	assert.Equal(t, len(trash.cars), 1)
+	assert.Equal(t, trash.cars[0].Car.Id, "new")
+}
+
+func TestRestoreCar_WhenHeldBeforeDelete(t *testing.T){
+	var events []carEvent
+	bus.subscribe(func(e carEvent) {
+		if e.Car.Id == "restoreheld" {
+			events = append(events, e)
+		}
+	})
+	car := Car{ Id: "restoreheld", Make: "Nissan", Model: "Leaf", Package: "XX", Color: "Gray", Year: 2013, Category: "Sedan", Mileage: 799, Price: 2499000 }
+	car.createCar()
+	_, err := car.reserveCar(reservationRequest{Customer: "CUST-7"}, "rita")
+	assert.Equal(t, err, nil)
+	car.deleteCar("rita")
+
+	restored, err := car.restoreCar()
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, restored.Status, statusAvailable)
+	assert.Equal(t, events[len(events)-1].Type, eventRestored)
+	assert.Equal(t, events[len(events)-1].Car.Status, statusAvailable)
+}
+
+func TestPurge_WhenCarHasHistory(t *testing.T){
+	start := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	clock := start
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	db := &Db{}
+	db.add(&Car{ Id: "purged" })
+	db.delete("purged", "alice")
+	db.add(&Car{ Id: "reborn" })
+	db.delete("reborn", "alice")
+	db.add(&Car{ Id: "reborn" })
+	clock = start.Add(48 * time.Hour)
+
+	n, err := db.purge(clock.Add(-24 * time.Hour))
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, n, 2)
+	_, err = db.getHistory("purged")
+	assert.Equal(t, err, errIdNotFound)
+	versions, err := db.getHistory("reborn")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(versions), 3)
+}
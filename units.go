@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Odometer units accepted by the API. Mileage is always stored in miles,
+// whatever unit the client sent it in.
+const (
+	unitMiles      = "mi"
+	unitKilometres = "km"
+
+	kmPerMile = 1.609344
+)
+
+func validUnit(unit string) bool {
+	return unit == unitMiles || unit == unitKilometres
+}
+
+// parseUnits reads the units query parameter, defaulting to miles.
+func parseUnits(unit string) (string, error) {
+	if unit == "" {
+		return unitMiles, nil
+	}
+	if !validUnit(unit) {
+		return "", fmt.Errorf("units must be km or mi")
+	}
+	return unit, nil
+}
+
+// toMiles converts a distance expressed in unit to miles.
+func toMiles(v float64, unit string) float64 {
+	if unit == unitKilometres {
+		return v / kmPerMile
+	}
+	return v
+}
+
+// fromMiles converts a distance in miles to unit, rounded to two decimals.
+func fromMiles(v float64, unit string) float64 {
+	if unit == unitKilometres {
+		v = v * kmPerMile
+	}
+	return math.Round(v*100) / 100
+}
+
+// normalizeMileage converts the car's mileage to miles so the store only
+// ever holds one unit.
+func (c *Car) normalizeMileage() {
+	if c.MileageUnit == "" {
+		c.MileageUnit = unitMiles
+	}
+	c.Mileage = toMiles(c.Mileage, c.MileageUnit)
+	c.MileageUnit = unitMiles
+}
+
+// inUnits returns a copy of the car with its mileage expressed in unit.
+func (c Car) inUnits(unit string) Car {
+	c.Mileage = fromMiles(c.Mileage, unit)
+	c.MileageUnit = unit
+	return c
+}
+
+func carsInUnits(cars []Car, unit string) []Car {
+	out := make([]Car, 0, len(cars))
+	for _, v := range cars {
+		out = append(out, v.inUnits(unit))
+	}
+	return out
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+)
+
+// minComparables is how many comparable cars a valuation needs.
+const minComparables = 3
+
+var errNotEnoughComparables = errors.New("not enough comparable cars")
+
+// valuationZ scales the standard error of a prediction into a 95%
+// confidence range, using the normal approximation.
+const (
+	valuationConfidence = 0.95
+	valuationZ          = 1.96
+)
+
+// valuationQuery describes the car to value. Mileage is in miles.
+type valuationQuery struct {
+	id       string
+	make     string
+	model    string
+	category string
+	year     int
+	mileage  float64
+	units    string
+}
+
+// valuation is the suggested price of a car, fitted over comparable cars
+// in the store.
+// @Description suggested price of a car
+type valuation struct {
+	Make        string  `json:"make"`
+	Model       string  `json:"model"`
+	Category    string  `json:"category,omitempty"`
+	Year        int     `json:"year"`
+	Mileage     float64 `json:"mileage"`
+	Estimate    float64 `json:"estimate"`
+	Low         float64 `json:"low"`
+	High        float64 `json:"high"`
+	Confidence  float64 `json:"confidence"`
+	Basis       string  `json:"basis" enums:"make and model,make and category,category"`
+	Method      string  `json:"method"`
+	Comparables []Car   `json:"comparables"`
+}
+
+// valuationFeature is a car attribute the price is regressed on.
+type valuationFeature struct {
+	name string
+	of   func(Car) float64
+}
+
+var valuationFeatures = []valuationFeature{
+	{"year", func(c Car) float64 { return float64(c.Year) }},
+	{"mileage", func(c Car) float64 { return c.Mileage }},
+}
+
+func parseValuationQuery(v url.Values) (valuationQuery, error) {
+	var q valuationQuery
+	var err error
+
+	q.units, err = parseUnits(v.Get("units"))
+	if err != nil {
+		return valuationQuery{}, err
+	}
+	q.make = v.Get("make")
+	q.model = v.Get("model")
+	q.category = v.Get("category")
+	if q.year, err = intParam(v, "year"); err != nil {
+		return valuationQuery{}, err
+	}
+	if q.mileage, err = floatParam(v, "mileage"); err != nil {
+		return valuationQuery{}, err
+	}
+	q.mileage = toMiles(q.mileage, q.units)
+	return q, nil
+}
+
+// comparablesOf picks the priced cars to value q against: the same make and
+// model, else the same make and category, else the same category, whichever
+// first has minComparables cars. Short of that the largest set is returned.
+func comparablesOf(q valuationQuery, cars []Car) ([]Car, string) {
+	same := func(a, b string) bool { return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b)) }
+	tiers := []struct {
+		basis string
+		match func(Car) bool
+	}{
+		{"make and model", func(c Car) bool { return same(c.Make, q.make) && same(c.Model, q.model) }},
+		{"make and category", func(c Car) bool { return q.category != "" && same(c.Make, q.make) && same(c.Category, q.category) }},
+		{"category", func(c Car) bool { return q.category != "" && same(c.Category, q.category) }},
+	}
+
+	comps, basis := []Car{}, tiers[0].basis
+	for _, tier := range tiers {
+		found := []Car{}
+		for _, v := range cars {
+			if v.Id != q.id && v.Price > 0 && tier.match(v) {
+				found = append(found, v)
+			}
+		}
+		if len(found) > len(comps) {
+			comps, basis = found, tier.basis
+		}
+		if len(comps) >= minComparables {
+			break
+		}
+	}
+	return comps, basis
+}
+
+// estimatePrice fits price by least squares on the year and mileage of the
+// comparable cars and predicts it for q. Features that do not vary, or
+// that the comparables are too few to fit, are left out, down to the mean
+// price.
+func estimatePrice(q valuationQuery, cars []Car) (valuation, error) {
+	comps, basis := comparablesOf(q, cars)
+	if len(comps) < minComparables {
+		return valuation{}, fmt.Errorf("%w: found %d, need %d", errNotEnoughComparables, len(comps), minComparables)
+	}
+	subject := Car{Year: q.year, Mileage: q.mileage}
+
+	n := float64(len(comps))
+	var ym float64
+	for _, v := range comps {
+		ym += v.Price / n
+	}
+
+	features := []valuationFeature{}
+	for _, f := range valuationFeatures {
+		first := f.of(comps[0])
+		for _, v := range comps[1:] {
+			if f.of(v) != first {
+				features = append(features, f)
+				break
+			}
+		}
+	}
+
+	var xm, b []float64
+	var inv [][]float64
+	for {
+		// Keep one degree of freedom for the error.
+		for len(comps) <= len(features)+1 {
+			features = features[:len(features)-1]
+		}
+		var sxy []float64
+		xm, sxy, inv = normalEquations(features, comps, ym)
+		if inv != nil {
+			b = make([]float64, len(features))
+			for j := range features {
+				for l := range features {
+					b[j] += inv[j][l] * sxy[l]
+				}
+			}
+			break
+		}
+		features = features[:len(features)-1]
+	}
+
+	predict := func(c Car) float64 {
+		y := ym
+		for j, f := range features {
+			y += b[j] * (f.of(c) - xm[j])
+		}
+		return y
+	}
+	var sse float64
+	for _, v := range comps {
+		sse += math.Pow(v.Price-predict(v), 2)
+	}
+	s := math.Sqrt(sse / (n - 1 - float64(len(features))))
+
+	// The prediction error grows with the distance of the subject from the
+	// centre of the comparables.
+	leverage := 1 / n
+	for j, f := range features {
+		for l, g := range features {
+			leverage += (f.of(subject) - xm[j]) * inv[j][l] * (g.of(subject) - xm[l])
+		}
+	}
+	estimate := predict(subject)
+	spread := valuationZ * s * math.Sqrt(1+leverage)
+
+	method := "mean price"
+	if len(features) > 0 {
+		names := []string{}
+		for _, f := range features {
+			names = append(names, f.name)
+		}
+		method = "linear regression on " + strings.Join(names, " and ")
+	}
+	return valuation{
+		Make:        q.make,
+		Model:       q.model,
+		Category:    q.category,
+		Year:        q.year,
+		Mileage:     q.mileage,
+		Estimate:    roundPrice(math.Max(estimate, 0)),
+		Low:         roundPrice(math.Max(estimate-spread, 0)),
+		High:        roundPrice(math.Max(estimate+spread, 0)),
+		Confidence:  valuationConfidence,
+		Basis:       basis,
+		Method:      method,
+		Comparables: comps,
+	}, nil
+}
+
+// normalEquations centres the features of the comparables and returns
+// their means, their covariance with the price and the inverse of their
+// scatter matrix. inv is nil when the features are collinear.
+func normalEquations(features []valuationFeature, comps []Car, ym float64) (xm, sxy []float64, inv [][]float64) {
+	k := len(features)
+	xm = make([]float64, k)
+	for j, f := range features {
+		for _, v := range comps {
+			xm[j] += f.of(v) / float64(len(comps))
+		}
+	}
+
+	sxx := make([][]float64, k)
+	sxy = make([]float64, k)
+	for j, f := range features {
+		sxx[j] = make([]float64, k)
+		for l, g := range features {
+			for _, v := range comps {
+				sxx[j][l] += (f.of(v) - xm[j]) * (g.of(v) - xm[l])
+			}
+		}
+		for _, v := range comps {
+			sxy[j] += (f.of(v) - xm[j]) * (v.Price - ym)
+		}
+	}
+
+	switch k {
+	case 0:
+		return xm, sxy, [][]float64{}
+	case 1:
+		return xm, sxy, [][]float64{{1 / sxx[0][0]}}
+	default:
+		det := sxx[0][0]*sxx[1][1] - sxx[0][1]*sxx[1][0]
+		if math.Abs(det) <= 1e-9*sxx[0][0]*sxx[1][1] {
+			return xm, sxy, nil
+		}
+		return xm, sxy, [][]float64{
+			{sxx[1][1] / det, -sxx[0][1] / det},
+			{-sxx[1][0] / det, sxx[0][0] / det},
+		}
+	}
+}
+
+func (v valuation) inUnits(unit string) valuation {
+	v.Mileage = fromMiles(v.Mileage, unit)
+	v.Comparables = carsInUnits(v.Comparables, unit)
+	return v
+}
+
+func roundPrice(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func (db *Db) valuate(q valuationQuery) (valuation, error) {
+	return estimatePrice(q, db.cars)
+}
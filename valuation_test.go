@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func valuationTestCar(id string, year int, mileage float64) Car {
+	return Car{ Id: id, Make: "Wartburg", Model: "353", Package: "W", Color: "Blue", Year: year, Category: "Sedan", Mileage: mileage, Price: 100000 + 20000*float64(year-1980) - mileage, MileageUnit: "mi" }
+}
+
+func TestEstimatePrice_WhenPriceIsLinear(t *testing.T){
+	cars := []Car{
+		valuationTestCar("a", 1980, 90000),
+		valuationTestCar("b", 1984, 60000),
+		valuationTestCar("c", 1986, 70000),
+		valuationTestCar("d", 1988, 20000),
+	}
+
+	v, err := estimatePrice(valuationQuery{make: "wartburg", model: "353", year: 1985, mileage: 50000}, cars)
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, v.Estimate, 150000.0)
+	assert.Equal(t, []float64{v.Low, v.High}, []float64{150000, 150000})
+	assert.Equal(t, v.Basis, "make and model")
+	assert.Equal(t, v.Method, "linear regression on year and mileage")
+	assert.Equal(t, len(v.Comparables), 4)
+}
+
+func TestEstimatePrice_WhenFewComparables(t *testing.T){
+	cars := []Car{
+		valuationTestCar("a", 1980, 90000),
+		valuationTestCar("b", 1984, 60000),
+		valuationTestCar("c", 1986, 70000),
+	}
+	cars[2].Model = "1.3"
+
+	_, err := estimatePrice(valuationQuery{make: "Wartburg", model: "353", year: 1985}, cars)
+	assert.Equal(t, err.Error(), "not enough comparable cars: found 2, need 3")
+
+	v, err := estimatePrice(valuationQuery{make: "Wartburg", model: "353", category: "sedan", year: 1985}, cars)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, v.Basis, "make and category")
+	assert.Equal(t, v.Method, "linear regression on year")
+	assert.Equal(t, v.Low < v.Estimate && v.Estimate < v.High, true)
+}
+
+func TestValuation_WhenRequested(t *testing.T){
+	for _, v := range []Car{
+		valuationTestCar("valcar1", 1980, 90000),
+		valuationTestCar("valcar2", 1984, 60000),
+		valuationTestCar("valcar3", 1986, 70000),
+		valuationTestCar("valcar4", 1988, 20000),
+	} {
+		car := v
+		_, err := car.createCar()
+		assert.Equal(t, err, nil)
+	}
+	h := &carHandler{}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/cars/valuation?make=Wartburg&model=353&year=1985&mileage=80467.2&units=km", nil))
+	var out valuation
+	json.Unmarshal(w.Body.Bytes(), &out)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, out.Estimate, 150000.0)
+	assert.Equal(t, out.Mileage, 80467.2)
+	assert.Equal(t, out.Comparables[0].MileageUnit, "km")
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/cars/valcar2/valuation", nil))
+	json.Unmarshal(w.Body.Bytes(), &out)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, len(out.Comparables), 3)
+	assert.Equal(t, out.Method, "linear regression on year")
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/cars/valuation?make=Wartburg&model=311&year=1965", nil))
+	assert.Equal(t, w.Code, http.StatusUnprocessableEntity)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/cars/valuation?make=Wartburg&year=1985", nil))
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// vinInfo is what a VIN says about a car.
+// @Description decoded vehicle identification number
+type vinInfo struct {
+	Vin          string `json:"vin"`
+	Wmi          string `json:"wmi"`
+	Make         string `json:"make,omitempty"`
+	Country      string `json:"country,omitempty"`
+	Year         int    `json:"year"`
+	SerialNumber string `json:"serial_number"`
+}
+
+// wmiMaker is the make and country of a world manufacturer identifier.
+type wmiMaker struct {
+	make    string
+	country string
+}
+
+// wmis maps the first three characters of a VIN to the manufacturer.
+var wmis = map[string]wmiMaker{
+	"1C4": {"Chrysler", "United States"},
+	"1C6": {"Ram", "United States"},
+	"1FA": {"Ford", "United States"},
+	"1FM": {"Ford", "United States"},
+	"1FT": {"Ford", "United States"},
+	"1G1": {"Chevrolet", "United States"},
+	"1GC": {"Chevrolet", "United States"},
+	"1G6": {"Cadillac", "United States"},
+	"1GT": {"GMC", "United States"},
+	"1HG": {"Honda", "United States"},
+	"1J4": {"Jeep", "United States"},
+	"1N4": {"Nissan", "United States"},
+	"2FM": {"Ford", "Canada"},
+	"2HG": {"Honda", "Canada"},
+	"2T1": {"Toyota", "Canada"},
+	"2T3": {"Toyota", "Canada"},
+	"3FA": {"Ford", "Mexico"},
+	"3N1": {"Nissan", "Mexico"},
+	"3VW": {"Volkswagen", "Mexico"},
+	"4T1": {"Toyota", "United States"},
+	"4T3": {"Toyota", "United States"},
+	"5FN": {"Honda", "United States"},
+	"5TD": {"Toyota", "United States"},
+	"5TF": {"Toyota", "United States"},
+	"5UX": {"BMW", "United States"},
+	"5YJ": {"Tesla", "United States"},
+	"JF1": {"Subaru", "Japan"},
+	"JHM": {"Honda", "Japan"},
+	"JM1": {"Mazda", "Japan"},
+	"JN1": {"Nissan", "Japan"},
+	"JTD": {"Toyota", "Japan"},
+	"JTE": {"Toyota", "Japan"},
+	"JTM": {"Toyota", "Japan"},
+	"KMH": {"Hyundai", "South Korea"},
+	"KNA": {"Kia", "South Korea"},
+	"KND": {"Kia", "South Korea"},
+	"SAJ": {"Jaguar", "United Kingdom"},
+	"SAL": {"Land Rover", "United Kingdom"},
+	"VF1": {"Renault", "France"},
+	"WAU": {"Audi", "Germany"},
+	"WBA": {"BMW", "Germany"},
+	"WDD": {"Mercedes-Benz", "Germany"},
+	"WP0": {"Porsche", "Germany"},
+	"WVW": {"Volkswagen", "Germany"},
+	"YV1": {"Volvo", "Sweden"},
+	"ZFF": {"Ferrari", "Italy"},
+}
+
+// vinRegions maps the first character of a VIN to the country of
+// manufacture for WMIs missing from wmis. Characters shared by several
+// countries are left out.
+var vinRegions = map[byte]string{
+	'1': "United States", '4': "United States", '5': "United States",
+	'2': "Canada", '3': "Mexico", 'J': "Japan", 'K': "South Korea",
+	'L': "China", 'S': "United Kingdom", 'W': "Germany", 'Z': "Italy",
+}
+
+// vinYearCodes are the model year codes of the tenth character, starting
+// at 1980 and repeating every 30 years.
+const vinYearCodes = "ABCDEFGHJKLMNPRSTVWXY123456789"
+
+// vinWeights weigh every character of the VIN in its check digit.
+var vinWeights = [17]int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+
+// vinValue transliterates a VIN character for the check digit. It returns
+// -1 for characters a VIN cannot contain.
+func vinValue(ch byte) int {
+	switch {
+	case ch >= '0' && ch <= '9':
+		return int(ch - '0')
+	case ch == 'I' || ch == 'O' || ch == 'Q':
+		return -1
+	case ch >= 'A' && ch <= 'Z':
+		return [26]int{1, 2, 3, 4, 5, 6, 7, 8, 0, 1, 2, 3, 4, 5, 0, 7, 0, 9, 2, 3, 4, 5, 6, 7, 8, 9}[ch-'A']
+	}
+	return -1
+}
+
+// vinCheckDigit computes the ninth character of a 17 character VIN.
+func vinCheckDigit(vin string) byte {
+	sum := 0
+	for i := 0; i < 17; i++ {
+		sum += vinValue(vin[i]) * vinWeights[i]
+	}
+	if sum%11 == 10 {
+		return 'X'
+	}
+	return byte('0' + sum%11)
+}
+
+// decodeVin validates the VIN and reads the manufacturer and model year
+// from it. The make is empty when the WMI is unknown.
+func decodeVin(vin string) (vinInfo, error) {
+	vin = strings.ToUpper(strings.TrimSpace(vin))
+	if len(vin) != 17 {
+		return vinInfo{}, fmt.Errorf("vin must be 17 characters")
+	}
+	for i := 0; i < len(vin); i++ {
+		if vin[i] == 'I' || vin[i] == 'O' || vin[i] == 'Q' {
+			return vinInfo{}, fmt.Errorf("vin must not contain I, O or Q")
+		}
+		if vinValue(vin[i]) == -1 {
+			return vinInfo{}, fmt.Errorf("vin contains invalid character %q", vin[i])
+		}
+	}
+	if want := vinCheckDigit(vin); vin[8] != want {
+		return vinInfo{}, fmt.Errorf("vin check digit is %c, expected %c", vin[8], want)
+	}
+
+	code := strings.IndexByte(vinYearCodes, vin[9])
+	if code == -1 {
+		return vinInfo{}, fmt.Errorf("vin model year code %c invalid", vin[9])
+	}
+	// A letter in the seventh position marks the 2010 cycle.
+	year := 1980 + code
+	if vin[6] >= 'A' && vin[6] <= 'Z' {
+		year += 30
+	}
+
+	info := vinInfo{Vin: vin, Wmi: vin[:3], Year: year, SerialNumber: vin[11:]}
+	if maker, ok := wmis[info.Wmi]; ok {
+		info.Make, info.Country = maker.make, maker.country
+	} else {
+		info.Country = vinRegions[vin[0]]
+	}
+	return info, nil
+}
+
+// fillFromVin checks the VIN of the car and fills Make, Year and Country
+// from it when they are empty. Submitted values that disagree with the
+// VIN are kept and listed in VinWarnings.
+func (c *Car) fillFromVin() error {
+	c.VinWarnings = nil
+	if c.Vin == "" {
+		return nil
+	}
+	info, err := decodeVin(c.Vin)
+	if err != nil {
+		return err
+	}
+	c.Vin = info.Vin
+
+	if c.Make == "" {
+		c.Make = info.Make
+	} else if info.Make != "" && !strings.EqualFold(c.Make, info.Make) {
+		c.VinWarnings = append(c.VinWarnings, fmt.Sprintf("make %s does not match the VIN, which decodes to %s", c.Make, info.Make))
+	}
+	if c.Year == 0 {
+		c.Year = info.Year
+	} else if c.Year != info.Year {
+		c.VinWarnings = append(c.VinWarnings, fmt.Sprintf("year %d does not match the VIN, which decodes to %d", c.Year, info.Year))
+	}
+	if c.Country == "" {
+		c.Country = info.Country
+	} else if info.Country != "" && !strings.EqualFold(c.Country, info.Country) {
+		c.VinWarnings = append(c.VinWarnings, fmt.Sprintf("country %s does not match the VIN, which decodes to %s", c.Country, info.Country))
+	}
+	return nil
+}
+
+// vinHandler exposes the VIN decoder.
+type vinHandler struct{}
+
+// decodeVin godoc
+// @Summary		Decode a VIN
+// @Description	Validates the check digit of the VIN and decodes its manufacturer, country and model year. make is omitted when the manufacturer is unknown
+// @Tags		vin
+// @Accept		json
+// @Produce		json
+// @Param		vin			path			string			true			"Vehicle identification number"
+// @Success		200			{object}		vinInfo			"OK"
+// @Failure		400			{string}		string			"BadRequest"
+// @Router		/vin/{vin}	[get]
+func (h *vinHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		respondWithError(w, http.StatusMethodNotAllowed, "invalid method")
+		return
+	}
+
+	info, err := decodeVin(strings.TrimPrefix(r.URL.Path, "/vin/"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, info)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeVin_WhenValid(t *testing.T){
+	info, err := decodeVin("1ftfw1et9dfc10312")
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, info, vinInfo{Vin: "1FTFW1ET9DFC10312", Wmi: "1FT", Make: "Ford", Country: "United States", Year: 2013, SerialNumber: "C10312"})
+}
+
+func TestDecodeVin_WhenCheckDigitIsX(t *testing.T){
+	info, err := decodeVin("1M8GDM9AXKP042788")
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, info.Year, 1989)
+	assert.Equal(t, info.Make, "")
+	assert.Equal(t, info.Country, "United States")
+}
+
+func TestDecodeVin_WhenInvalid(t *testing.T){
+	_, err := decodeVin("1FTFW1ET8DFC10312")
+	assert.Equal(t, err.Error(), "vin check digit is 8, expected 9")
+
+	_, err = decodeVin("1FTFW1ET9DFC1031")
+	assert.Equal(t, err.Error(), "vin must be 17 characters")
+
+	_, err = decodeVin("1FTFW1ET9DFC1O312")
+	assert.Equal(t, err.Error(), "vin must not contain I, O or Q")
+}
+
+func TestCreateCar_WhenVinGiven(t *testing.T){
+	car := persistTestCar("vincar1")
+	car.Make, car.Year = "", 0
+	car.Vin = "5yj3e1ea2kf317000"
+
+	created, err := car.createCar()
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, created.Vin, "5YJ3E1EA2KF317000")
+	assert.Equal(t, created.Make, "Tesla")
+	assert.Equal(t, created.Year, 2019)
+	assert.Equal(t, created.Country, "United States")
+	assert.Equal(t, len(created.VinWarnings), 0)
+}
+
+func TestCreateCar_WhenVinDisagrees(t *testing.T){
+	car := persistTestCar("vincar2")
+	car.Year = 2015
+	car.Vin = "4T1BF1FK7EU123456"
+
+	created, err := car.createCar()
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, created.Make, "Nissan")
+	assert.Equal(t, created.VinWarnings, []string{
+		"make Nissan does not match the VIN, which decodes to Toyota",
+		"year 2015 does not match the VIN, which decodes to 2014",
+	})
+}
+
+func TestCreateCar_WhenVinInvalid(t *testing.T){
+	car := persistTestCar("vincar3")
+	car.Vin = "4T1BF1FK0EU123456"
+
+	_, err := car.createCar()
+
+	assert.Equal(t, err.Error(), "vin check digit is 0, expected 7")
+}
+
+func TestVinHandler_WhenDecoding(t *testing.T){
+	h := &vinHandler{}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/vin/JHMFA1F55AS000001", nil))
+	var info vinInfo
+	json.Unmarshal(w.Body.Bytes(), &info)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, info.Make, "Honda")
+	assert.Equal(t, info.Country, "Japan")
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/vin/JHMFA1F50AS000001", nil))
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+}
+
+func TestPutCar_WhenInvalid(t *testing.T){
+	persistTestCar("vincar4").createCar()
+	invalidVin := persistTestCar("vincar4")
+	invalidVin.Vin = "1FTFW1ET8DFC10312"
+	negativeMileage := persistTestCar("vincar4")
+	negativeMileage.Mileage = -1
+
+	for _, c := range []*Car{ invalidVin, negativeMileage } {
+		body, _ := json.Marshal(c)
+		r := httptest.NewRequest("PUT", "/cars", strings.NewReader(string(body)))
+		r.Header.Set("content-type", "application/json")
+		w := httptest.NewRecorder()
+		(&carHandler{}).ServeHTTP(w, r)
+
+		assert.Equal(t, w.Code, http.StatusBadRequest)
+	}
+	car, _ := db.getById("vincar4")
+	assert.Equal(t, car.Vin, "")
+	assert.Equal(t, car.Mileage, 799.0)
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"container/heap"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Delivery states. A delivery that exhausts its retries is dead and stays
+// in the subscription's dead letters until it is redelivered.
+const (
+	deliveryPending   = "pending"
+	deliverySucceeded = "succeeded"
+	deliveryDead      = "dead"
+)
+
+// maxDeliveryLog is how many deliveries are kept per subscription.
+const maxDeliveryLog = 100
+
+// defaultWebhookWorkers is how many deliveries are sent at once.
+const defaultWebhookWorkers = 4
+
+// webhookSubscription asks for car events of the given types to be POSTed
+// to URL, signed with Secret.
+// @Description webhook subscription
+type webhookSubscription struct {
+	Id         string    `json:"id"`
+	URL        string    `json:"url"`
+	Events     []string  `json:"events" enums:"car.created,car.updated,car.deleted,car.restored"`
+	Secret     string    `json:"secret,omitempty"`
+	Dealership string    `json:"dealership"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// deliveryAttempt is the outcome of one POST to the subscriber.
+type deliveryAttempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// webhookDelivery is one event sent to one subscription.
+// @Description webhook delivery and its attempts
+type webhookDelivery struct {
+	Id             string            `json:"id"`
+	SubscriptionId string            `json:"subscription_id"`
+	Event          carEvent          `json:"event"`
+	Status         string            `json:"status" enums:"pending,succeeded,dead"`
+	Attempts       []deliveryAttempt `json:"attempts"`
+}
+
+func (s webhookSubscription) wants(typ string) bool {
+	for _, v := range s.Events {
+		if v == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func newId() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// sign computes the X-Webhook-Signature header value for body sent at
+// timestamp: the hex HMAC-SHA256 of "timestamp.body" keyed with secret.
+func sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// send posts the event once and reports the attempt.
+func (h *webhookHandler) send(s webhookSubscription, d *webhookDelivery) deliveryAttempt {
+	attempt := deliveryAttempt{At: now()}
+
+	body, _ := json.Marshal(d.Event)
+	timestamp := strconv.FormatInt(attempt.At.Unix(), 10)
+
+	req, err := http.NewRequest("POST", s.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set("X-Webhook-Event", d.Event.Type)
+	req.Header.Set("X-Webhook-Delivery", d.Id)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", sign(s.Secret, timestamp, body))
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	res.Body.Close()
+
+	attempt.StatusCode = res.StatusCode
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("unexpected status %d", res.StatusCode)
+	}
+	return attempt
+}
+
+// deliveryJob is the next attempt of a delivery, due at due. attempt
+// counts the attempts of the current round, which redelivery restarts.
+type deliveryJob struct {
+	sub      webhookSubscription
+	delivery *webhookDelivery
+	attempt  int
+	due      time.Time
+}
+
+// deliveryQueue orders pending jobs by when they are due.
+type deliveryQueue []*deliveryJob
+
+func (q deliveryQueue) Len() int            { return len(q) }
+func (q deliveryQueue) Less(i, j int) bool  { return q[i].due.Before(q[j].due) }
+func (q deliveryQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *deliveryQueue) Push(x interface{}) { *q = append(*q, x.(*deliveryJob)) }
+func (q *deliveryQueue) Pop() interface{} {
+	old := *q
+	j := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return j
+}
+
+// enqueue schedules the job, starting the workers on first use. h must be
+// locked.
+func (h *webhookHandler) enqueue(j *deliveryJob) {
+	h.started.Do(h.start)
+	heap.Push(&h.pending, j)
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+// start runs the scheduler and a fixed pool of workers, so deliveries and
+// their retries never take more than h.workers goroutines.
+func (h *webhookHandler) start() {
+	h.wake = make(chan struct{}, 1)
+	h.ready = make(chan *deliveryJob)
+	go h.schedule()
+	for i := 0; i < h.workers; i++ {
+		go h.work()
+	}
+}
+
+// schedule hands the pending jobs to the workers as they fall due.
+func (h *webhookHandler) schedule() {
+	for {
+		h.Lock()
+		var next *deliveryJob
+		wait := time.Duration(-1)
+		if len(h.pending) > 0 {
+			if wait = time.Until(h.pending[0].due); wait <= 0 {
+				next = heap.Pop(&h.pending).(*deliveryJob)
+			}
+		}
+		h.Unlock()
+
+		switch {
+		case next != nil:
+			h.ready <- next
+		case wait < 0:
+			<-h.wake
+		default:
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-h.wake:
+				timer.Stop()
+			}
+		}
+	}
+}
+
+// work makes one attempt per job. A failed attempt is scheduled again
+// with exponential backoff until maxAttempts is reached, when the
+// delivery moves to the dead letters.
+func (h *webhookHandler) work() {
+	for j := range h.ready {
+		attempt := h.send(j.sub, j.delivery)
+
+		h.Lock()
+		d := j.delivery
+		d.Attempts = append(d.Attempts, attempt)
+		j.attempt++
+		switch {
+		case attempt.Error == "":
+			d.Status = deliverySucceeded
+		case j.attempt >= h.maxAttempts:
+			d.Status = deliveryDead
+			h.deadLetters[j.sub.Id] = append(h.deadLetters[j.sub.Id], d)
+		default:
+			j.due = time.Now().Add(h.backoff << (j.attempt - 1))
+			h.enqueue(j)
+		}
+		h.Unlock()
+	}
+}
+
+// dispatch queues a delivery of e to every subscription of the car's
+// dealership interested in it. Publishers hold the cars lock, which
+// guards the subscriptions in the store.
+func (h *webhookHandler) dispatch(e carEvent) {
+	store, err := dealerships.get(orDefault(e.Car.Dealership))
+	if err != nil {
+		return
+	}
+
+	defer h.Unlock()
+	h.Lock()
+
+	for _, s := range store.webhooks {
+		if s.wants(e.Type) {
+			d := &webhookDelivery{Id: newId(), SubscriptionId: s.Id, Event: e, Status: deliveryPending, Attempts: []deliveryAttempt{}}
+			h.logDelivery(d)
+			h.enqueue(&deliveryJob{sub: s, delivery: d, due: time.Now()})
+		}
+	}
+}
+
+func (h *webhookHandler) logDelivery(d *webhookDelivery) {
+	log := append(h.deliveries[d.SubscriptionId], d)
+	if len(log) > maxDeliveryLog {
+		log = log[len(log)-maxDeliveryLog:]
+	}
+	h.deliveries[d.SubscriptionId] = log
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598.
+var sharedAddressSpace = &net.IPNet{IP: net.IP{100, 64, 0, 0}, Mask: net.CIDRMask(10, 32)}
+
+// internalAddress reports whether ip is loopback, private, link-local or
+// otherwise not a public unicast address. Webhooks are never sent there,
+// so subscriptions cannot reach services behind the server.
+func internalAddress(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
+
+// internalHost reports whether the host of a subscription URL names this
+// machine or an internal address literal. Other names are checked when
+// connecting, as they may resolve anywhere.
+func internalHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && internalAddress(ip)
+}
+
+// newWebhookClient returns the client deliveries are sent with. It refuses
+// to connect to internal addresses whatever the subscriber's host resolves
+// to, including after redirects, and bypasses proxies so the check applies
+// to the subscriber itself.
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || internalAddress(ip) {
+				return fmt.Errorf("webhook url resolves to internal address %s", host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}
+}
+
+func (db *Db) subscribe(s webhookSubscription) error {
+	if err := db.wal.append(walRecord{Op: opSubscribe, Subscription: &s}); err != nil {
+		return err
+	}
+	db.webhooks = append(db.webhooks, s)
+	return nil
+}
+
+func (db *Db) unsubscribe(id string) error {
+	for i, s := range db.webhooks {
+		if s.Id == id {
+			if err := db.wal.append(walRecord{Op: opUnsubscribe, Id: id}); err != nil {
+				return err
+			}
+			db.webhooks = append(db.webhooks[:i], db.webhooks[i+1:]...)
+			return nil
+		}
+	}
+	return errIdNotFound
+}
+
+func (db *Db) getWebhook(id string) (webhookSubscription, error) {
+	for _, s := range db.webhooks {
+		if s.Id == id {
+			return s, nil
+		}
+	}
+	return webhookSubscription{}, errIdNotFound
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// getAll godoc
+// @Summary		List webhook subscriptions
+// @Description Lists the webhook subscriptions of the dealership. Secrets are not returned
+// @Tags		webhook
+// @Accept		json
+// @Produce		json
+// @Success		200 		{array} 		webhookSubscription		"OK"
+// @Router		/webhooks	[get]
+func (h *webhookHandler) getAll(w http.ResponseWriter, r *http.Request) {
+	defer h.cars.Unlock()
+	h.cars.Lock()
+
+	store, err := dealerships.get(dealershipOf(r.Context()))
+	if err != nil {
+		respondWithError(w, dealershipStatus(err), err.Error())
+		return
+	}
+	out := []webhookSubscription{}
+	for _, s := range store.webhooks {
+		s.Secret = ""
+		out = append(out, s)
+	}
+
+	respondWithJSON(w, http.StatusOK, out)
+}
+
+// getById godoc
+// @Summary		Get a webhook subscription
+// @Description Gets the webhook subscription corresponding to the id in the path. The secret is not returned
+// @Tags		webhook
+// @Accept		json
+// @Produce		json
+// @Param		id				path			string					true		"Subscription Id"
+// @Success		200				{object}		webhookSubscription		"OK"
+// @Failure		404				{string}		string					"NotFound"
+// @Router		/webhooks/{id}	[get]
+func (h *webhookHandler) getById(w http.ResponseWriter, r *http.Request) {
+	defer h.cars.Unlock()
+	h.cars.Lock()
+
+	s, err := h.find(r, idFromUrl(r))
+	if err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	s.Secret = ""
+	respondWithJSON(w, http.StatusOK, s)
+}
+
+// post godoc
+// @Summary		Create a webhook subscription
+// @Description	Subscribes a URL to car events. URLs on localhost or at private, loopback or link-local addresses are rejected, also when a host name resolves to one at delivery time. Every delivery is a JSON POST signed in the X-Webhook-Signature header with the hex HMAC-SHA256 of "<X-Webhook-Timestamp>.<body>" keyed with the secret. Failed deliveries are retried with exponential backoff before being moved to the dead letters
+// @Tags		webhook
+// @Accept		json
+// @Produce		json
+// @Param		subscription	body			webhookSubscription		true		"Subscription JSON Object"
+// @Success		201				{object}		webhookSubscription		"Created"
+// @Failure		400				{string}		string					"BadRequest"
+// @Param		Idempotency-Key	header	string	false	"Replays the stored response when the request is repeated with the same key"
+// @Failure		422			{string}		string			"Idempotency-Key reused with a different request"
+// @Router		/webhooks		[post]
+func (h *webhookHandler) post(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ct := r.Header.Get("content-type")
+	if ct != "application/json" {
+		respondWithError(w, http.StatusUnsupportedMediaType, "content type 'application/json' required")
+		return
+	}
+
+	var s webhookSubscription
+	err = json.Unmarshal(body, &s)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = m.validate_webhook(&s)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	defer h.cars.Unlock()
+	h.cars.Lock()
+
+	store, err := dealerships.get(dealershipOf(r.Context()))
+	if err != nil {
+		respondWithError(w, dealershipStatus(err), err.Error())
+		return
+	}
+
+	s.Id = newId()
+	s.Dealership = dealershipOf(r.Context())
+	s.CreatedAt = now()
+	if err := store.subscribe(s); err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, s)
+}
+
+// delete godoc
+// @Summary		Delete a webhook subscription
+// @Description Deletes the webhook subscription corresponding to the id in the path, with its delivery log and dead letters
+// @Tags		webhook
+// @Accept		json
+// @Produce		json
+// @Param		id				path			string			true		"Subscription Id"
+// @Success		204				{string}		string			"NoContent"
+// @Failure		404				{string}		string			"NotFound"
+// @Param		Idempotency-Key	header	string	false	"Replays the stored response when the request is repeated with the same key"
+// @Failure		422			{string}		string			"Idempotency-Key reused with a different request"
+// @Router		/webhooks/{id}	[delete]
+func (h *webhookHandler) delete(w http.ResponseWriter, r *http.Request) {
+	defer h.cars.Unlock()
+	h.cars.Lock()
+
+	store, err := dealerships.get(dealershipOf(r.Context()))
+	if err != nil {
+		respondWithError(w, dealershipStatus(err), err.Error())
+		return
+	}
+	id := idFromUrl(r)
+	if err := store.unsubscribe(id); err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	defer h.Unlock()
+	h.Lock()
+
+	delete(h.deliveries, id)
+	delete(h.deadLetters, id)
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// getDeliveries godoc
+// @Summary		List webhook deliveries
+// @Description Lists the most recent deliveries of the subscription with every attempt made
+// @Tags		webhook
+// @Accept		json
+// @Produce		json
+// @Param		id							path		string				true		"Subscription Id"
+// @Success		200							{array}		webhookDelivery		"OK"
+// @Failure		404							{string}	string				"NotFound"
+// @Router		/webhooks/{id}/deliveries	[get]
+func (h *webhookHandler) getDeliveries(w http.ResponseWriter, r *http.Request) {
+	defer h.cars.Unlock()
+	h.cars.Lock()
+
+	id := idFromUrl(r)
+	if _, err := h.find(r, id); err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	defer h.Unlock()
+	h.Lock()
+
+	respondWithJSON(w, http.StatusOK, deliveriesOrEmpty(h.deliveries[id]))
+}
+
+// getDeadLetters godoc
+// @Summary		List dead letters
+// @Description Lists the deliveries of the subscription that failed every retry
+// @Tags		webhook
+// @Accept		json
+// @Produce		json
+// @Param		id							path		string				true		"Subscription Id"
+// @Success		200							{array}		webhookDelivery		"OK"
+// @Failure		404							{string}	string				"NotFound"
+// @Router		/webhooks/{id}/dead-letters	[get]
+func (h *webhookHandler) getDeadLetters(w http.ResponseWriter, r *http.Request) {
+	defer h.cars.Unlock()
+	h.cars.Lock()
+
+	id := idFromUrl(r)
+	if _, err := h.find(r, id); err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	defer h.Unlock()
+	h.Lock()
+
+	respondWithJSON(w, http.StatusOK, deliveriesOrEmpty(h.deadLetters[id]))
+}
+
+// redeliver godoc
+// @Summary		Redeliver a dead letter
+// @Description Removes the delivery from the dead letters and sends it again, with a fresh round of retries
+// @Tags		webhook
+// @Accept		json
+// @Produce		json
+// @Param		id													path		string				true		"Subscription Id"
+// @Param		delivery											path		string				true		"Delivery Id"
+// @Success		202													{object}	webhookDelivery		"Accepted"
+// @Failure		404													{string}	string				"NotFound"
+// @Router		/webhooks/{id}/dead-letters/{delivery}:redeliver	[post]
+func (h *webhookHandler) redeliver(w http.ResponseWriter, r *http.Request) {
+	defer h.cars.Unlock()
+	h.cars.Lock()
+
+	id := idFromUrl(r)
+	sub, err := h.find(r, id)
+	if err != nil {
+		respondWithError(w, statusOf(err), err.Error())
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 5 || !strings.HasSuffix(parts[4], ":redeliver") {
+		respondWithError(w, http.StatusNotFound, "delivery not found")
+		return
+	}
+	deliveryId := strings.TrimSuffix(parts[4], ":redeliver")
+
+	defer h.Unlock()
+	h.Lock()
+
+	for j, d := range h.deadLetters[id] {
+		if d.Id == deliveryId {
+			h.deadLetters[id] = append(h.deadLetters[id][:j], h.deadLetters[id][j+1:]...)
+			d.Status = deliveryPending
+			h.enqueue(&deliveryJob{sub: sub, delivery: d, due: time.Now()})
+			respondWithJSON(w, http.StatusAccepted, d)
+			return
+		}
+	}
+
+	respondWithError(w, http.StatusNotFound, "delivery not found")
+}
+
+// find returns the subscription with the id in the dealership of the
+// request. h.cars must be locked.
+func (h *webhookHandler) find(r *http.Request, id string) (webhookSubscription, error) {
+	store, err := dealerships.get(dealershipOf(r.Context()))
+	if err != nil {
+		return webhookSubscription{}, err
+	}
+	return store.getWebhook(id)
+}
+
+func deliveriesOrEmpty(d []*webhookDelivery) []*webhookDelivery {
+	if d == nil {
+		return []*webhookDelivery{}
+	}
+	return d
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// webhookHandler serves the subscriptions, which are kept in the store of
+// each dealership under the lock of cars, and sends their deliveries. Its
+// own lock guards the delivery state and is taken after that of cars.
+type webhookHandler struct {
+	sync.Mutex
+	cars        *carHandler
+	deliveries  map[string][]*webhookDelivery
+	deadLetters map[string][]*webhookDelivery
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	workers     int
+
+	started sync.Once
+	pending deliveryQueue
+	wake    chan struct{}
+	ready   chan *deliveryJob
+}
+
+func (h *webhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	dealership, err := dealershipFromRequest(r)
+	if err != nil {
+		respondWithError(w, dealershipStatus(err), err.Error())
+		return
+	}
+	r = withDealership(r, dealership)
+
+	switch r.Method {
+	case "GET":
+		switch {
+		case idFromUrl(r) == "-1":
+			h.getAll(w, r)
+		case actionFromUrl(r) == "deliveries":
+			h.getDeliveries(w, r)
+		case actionFromUrl(r) == "dead-letters":
+			h.getDeadLetters(w, r)
+		default:
+			h.getById(w, r)
+		}
+	case "POST":
+		if actionFromUrl(r) == "dead-letters" {
+			h.redeliver(w, r)
+		} else {
+			h.post(w, r)
+		}
+	case "DELETE":
+		h.delete(w, r)
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "invalid method")
+	}
+}
+
+func newWebhookHandler(cars *carHandler) *webhookHandler {
+	h := &webhookHandler{
+		cars:        cars,
+		deliveries:  map[string][]*webhookDelivery{},
+		deadLetters: map[string][]*webhookDelivery{},
+		client:      newWebhookClient(),
+		maxAttempts: 6,
+		backoff:     time.Second,
+		workers:     defaultWebhookWorkers,
+	}
+	bus.subscribe(h.dispatch)
+	return h
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestWebhookHandler subscribes url to car.created in a new dealership
+// of its own, whose user is named after it. The test server is local, so deliveries skip the guard
+// against internal addresses.
+func newTestWebhookHandler(dealership, url string) *webhookHandler {
+	dealerships.Lock()
+	if dealerships.users == nil {
+		dealerships.users = map[string]dealershipUser{}
+	}
+	dealerships.users[dealership] = dealershipUser{ password: "secret", home: dealership }
+	dealerships.Unlock()
+	dealerships.add(dealership, &Db{ webhooks: []webhookSubscription{ { Id: "sub", URL: url, Events: []string{ eventCreated }, Secret: "s3cret", Dealership: dealership } } })
+	return &webhookHandler{
+		cars:        &carHandler{},
+		deliveries:  map[string][]*webhookDelivery{},
+		deadLetters: map[string][]*webhookDelivery{},
+		client:      http.DefaultClient,
+		maxAttempts: 3,
+		backoff:     time.Millisecond,
+		workers:     2,
+	}
+}
+
+func webhookDo(h *webhookHandler, method, path, dealership, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("content-type", "application/json")
+	r.SetBasicAuth(dealership, "secret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func waitForStatus(h *webhookHandler, d *webhookDelivery, status string) string {
+	for i := 0; i < 200; i++ {
+		h.Lock()
+		s := d.Status
+		h.Unlock()
+		if s == status {
+			return s
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return d.Status
+}
+
+func TestDispatch_WhenDeliverySigned(t *testing.T){
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer srv.Close()
+
+	h := newTestWebhookHandler("hooks-signed", srv.URL)
+	h.dispatch(carEvent{ Id: 1, Type: eventCreated, Car: Car{ Id: "abc", Dealership: "hooks-signed" } })
+	h.dispatch(carEvent{ Id: 2, Type: eventUpdated, Car: Car{ Id: "abc", Dealership: "hooks-signed" } })
+
+	r := <-received
+	body := <-bodies
+	assert.Equal(t, r.Header.Get("X-Webhook-Event"), eventCreated)
+	assert.Equal(t, r.Header.Get("X-Webhook-Signature"), sign("s3cret", r.Header.Get("X-Webhook-Timestamp"), body))
+	assert.Equal(t, waitForStatus(h, h.deliveries["sub"][0], deliverySucceeded), deliverySucceeded)
+	assert.Equal(t, len(h.deliveries["sub"]), 1)
+}
+
+func TestDispatch_WhenRetriesExhausted(t *testing.T){
+	fail := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	h := newTestWebhookHandler("hooks-retried", srv.URL)
+	h.dispatch(carEvent{ Id: 1, Type: eventCreated, Car: Car{ Dealership: "hooks-retried" } })
+	h.Lock()
+	d := h.deliveries["sub"][0]
+	h.Unlock()
+
+	assert.Equal(t, waitForStatus(h, d, deliveryDead), deliveryDead)
+	assert.Equal(t, len(d.Attempts), 3)
+	assert.Equal(t, d.Attempts[0].StatusCode, 500)
+	assert.Equal(t, len(h.deadLetters["sub"]), 1)
+
+	h.Lock()
+	fail = false
+	h.Unlock()
+
+	w := webhookDo(h, "POST", "/webhooks/sub/dead-letters/"+d.Id+":redeliver", "hooks-retried", "")
+
+	assert.Equal(t, w.Code, http.StatusAccepted)
+	assert.Equal(t, waitForStatus(h, d, deliverySucceeded), deliverySucceeded)
+	assert.Equal(t, len(h.deadLetters["sub"]), 0)
+}
+
+func TestDispatch_WhenManyDeliveriesFail(t *testing.T){
+	var mu sync.Mutex
+	inFlight, most := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > most {
+			most = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	h := newTestWebhookHandler("hooks-bounded", srv.URL)
+	for i := 0; i < 20; i++ {
+		h.dispatch(carEvent{ Id: uint64(i), Type: eventCreated, Car: Car{ Dealership: "hooks-bounded" } })
+	}
+	h.Lock()
+	last := h.deliveries["sub"][19]
+	h.Unlock()
+
+	assert.Equal(t, waitForStatus(h, last, deliveryDead), deliveryDead)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.LessOrEqual(t, most, h.workers)
+}
+
+func TestPostWebhook_WhenEventUnknown(t *testing.T){
+	h := newTestWebhookHandler("hooks-unknown", "https://hooks.example.com")
+	w := webhookDo(h, "POST", "/webhooks", "hooks-unknown", `{"url":"https://hooks.example.com/hook","events":["car.sold"],"secret":"x"}`)
+
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+	assert.Contains(t, w.Body.String(), "unknown event type car.sold")
+}
+
+func TestPostWebhook_WhenSuccessful(t *testing.T){
+	h := newTestWebhookHandler("hooks-created", "https://hooks.example.com")
+	w := webhookDo(h, "POST", "/webhooks", "hooks-created", `{"url":"https://hooks.example.com/hook","events":["car.deleted"],"secret":"x"}`)
+
+	assert.Equal(t, w.Code, http.StatusCreated)
+	store, _ := dealerships.get("hooks-created")
+	assert.Equal(t, len(store.webhooks), 2)
+
+	w = webhookDo(h, "GET", "/webhooks", "hooks-created", "")
+	assert.NotContains(t, w.Body.String(), "secret")
+}
+
+func TestPostWebhook_WhenUrlInternal(t *testing.T){
+	h := newTestWebhookHandler("hooks-internal", "https://hooks.example.com")
+	for _, url := range []string{ "http://localhost/hook", "http://api.localhost/hook", "http://127.0.0.1:8080/hook", "http://10.0.0.5/hook", "http://169.254.169.254/latest", "http://[::1]/hook", "http://100.64.1.1/hook" } {
+		w := webhookDo(h, "POST", "/webhooks", "hooks-internal", `{"url":"`+url+`","events":["car.created"],"secret":"x"}`)
+
+		assert.Equal(t, w.Code, http.StatusBadRequest, url)
+		assert.Contains(t, w.Body.String(), "url must not point to an internal address", url)
+	}
+}
+
+func TestWebhookClient_WhenAddressInternal(t *testing.T){
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	_, err := newWebhookClient().Post(srv.URL, "application/json", strings.NewReader("{}"))
+
+	assert.NotEqual(t, err, nil)
+	assert.Contains(t, err.Error(), "internal address")
+}
+
+func TestDeleteWebhook_WhenSuccessful(t *testing.T){
+	h := newTestWebhookHandler("hooks-deleted", "https://hooks.example.com")
+	w := webhookDo(h, "DELETE", "/webhooks/sub", "hooks-deleted", "")
+
+	assert.Equal(t, w.Code, http.StatusNoContent)
+	w = webhookDo(h, "GET", "/webhooks/sub", "hooks-deleted", "")
+	assert.Equal(t, w.Code, http.StatusNotFound)
+}
+
+func TestOpen_WhenReplayingSubscriptions(t *testing.T){
+	dir := t.TempDir()
+	var d Db
+	assert.Equal(t, d.open(dir), nil)
+	d.subscribe(webhookSubscription{ Id: "a", URL: "https://hooks.example.com/a", Events: []string{ eventCreated }, Secret: "x" })
+	d.subscribe(webhookSubscription{ Id: "b", URL: "https://hooks.example.com/b", Events: []string{ eventDeleted }, Secret: "y" })
+	d.unsubscribe("a")
+	d.wal.f.Close()
+
+	var replayed Db
+	assert.Equal(t, replayed.open(dir), nil)
+	assert.Equal(t, len(replayed.webhooks), 1)
+	s, err := replayed.getWebhook("b")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, s.Secret, "y")
+}